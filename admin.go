@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"golang_cms/app"
+	"golang_cms/audit"
+	"golang_cms/config"
+	"golang_cms/model"
+	"golang_cms/rbac"
+	"golang_cms/repository"
+	"time"
+)
+
+const grantAdminUsage = "usage: golang_cms grant-admin <email> [flags]"
+
+// runGrantAdmin implements the "grant-admin" command, which makes the
+// registered user with the given email an admin. It is how the first admin
+// is created: registering never grants more than the viewer role.
+func runGrantAdmin(args []string) error {
+	if len(args) == 0 || args[0] == "" || args[0][0] == '-' {
+		return errors.New(grantAdminUsage)
+	}
+	email, args := args[0], args[1:]
+
+	cfg, err := config.Load(args)
+	if err != nil {
+		return err
+	}
+
+	ctx := audit.WithActor(context.Background(), audit.System("grant-admin"))
+	a, err := app.Connect(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer a.Close(ctx)
+
+	repos := audit.Recorded(a.Repos, audit.NewLog(a.Repos.Audit))
+	user, err := grantAdmin(ctx, repos.User, email)
+	if errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("no user is registered with email %s", email)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s (%s) is an admin\n", email, user.User_id)
+	return nil
+}
+
+// grantAdmin gives the user with email the admin role.
+func grantAdmin(ctx context.Context, users repository.UserRepository, email string) (model.User, error) {
+	user, err := users.FindByEmail(ctx, email)
+	if err != nil {
+		return user, err
+	}
+	if user.Role == rbac.RoleAdmin {
+		return user, nil
+	}
+	userType := "ADMIN"
+	user.Role = rbac.RoleAdmin
+	user.User_type = &userType
+	user.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	return users.Update(ctx, user)
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"golang_cms/model"
+	"golang_cms/rbac"
+	"golang_cms/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestGrantAdmin(t *testing.T) {
+	ctx := context.Background()
+	repos := repository.NewMemoryRepositories()
+	email, userType := "ada@example.com", "USER"
+	if _, err := repos.User.Create(ctx, model.User{ID: primitive.NewObjectID(), User_id: "ada", Email: &email, Phone: &email, User_type: &userType, Role: rbac.RoleViewer}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		user, err := grantAdmin(ctx, repos.User, email)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if user.Role != rbac.RoleAdmin || *user.User_type != "ADMIN" {
+			t.Fatalf("granted user has role %q and type %q", user.Role, *user.User_type)
+		}
+	}
+	stored, _ := repos.User.FindByUserID(ctx, "ada")
+	if stored.Role != rbac.RoleAdmin {
+		t.Errorf("stored role = %q", stored.Role)
+	}
+
+	if _, err := grantAdmin(ctx, repos.User, "nobody@example.com"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("granting an unknown email = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,125 @@
+// Package apitoken issues and checks personal API tokens. An API token acts
+// for the user who created it, limited to the permissions it was given, so
+// programs can call the API without the user's password.
+package apitoken
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang_cms/helper"
+	"golang_cms/model"
+	"golang_cms/rbac"
+	"golang_cms/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Prefix starts every API token, which tells them apart from access tokens.
+const Prefix = "cms_"
+
+// prefixLength is how much of a token is kept in the clear to recognise it.
+const prefixLength = len(Prefix) + 8
+
+// lastUsedPrecision is how stale the last use of a token may be; a token
+// used more often is not written on every request.
+const lastUsedPrecision = time.Minute
+
+// ErrInvalidToken is returned for an API token that is unknown, expired or
+// whose user no longer exists.
+var ErrInvalidToken = errors.New("invalid or expired API token")
+
+// ErrNotGranted is returned when a token is given a permission its user's
+// role does not grant.
+var ErrNotGranted = errors.New("permission not granted by your role")
+
+// IsApiToken reports whether raw looks like an API token rather than an
+// access token.
+func IsApiToken(raw string) bool {
+	return strings.HasPrefix(raw, Prefix)
+}
+
+// Service creates API tokens and authenticates requests made with them.
+type Service struct {
+	tokens repository.ApiTokenRepository
+	users  repository.UserRepository
+	authz  *rbac.Authorizer
+}
+
+func NewService(tokens repository.ApiTokenRepository, users repository.UserRepository, authz *rbac.Authorizer) *Service {
+	return &Service{tokens: tokens, users: users, authz: authz}
+}
+
+// Create issues a token named name for user uid. Every permission must be
+// granted in full by the user's role, wildcards included. The token itself is only returned here; it is
+// stored as a hash.
+func (s *Service) Create(ctx context.Context, uid string, name string, permissions []string, expiresAt *time.Time) (model.ApiToken, string, error) {
+	granted, err := s.authz.Permissions(ctx, uid)
+	if err != nil {
+		return model.ApiToken{}, "", err
+	}
+	for _, permission := range permissions {
+		if err := rbac.Validate(permission); err != nil {
+			return model.ApiToken{}, "", err
+		}
+		if !rbac.Covers(granted, []string{permission}) {
+			return model.ApiToken{}, "", fmt.Errorf("%w: %q", ErrNotGranted, permission)
+		}
+	}
+
+	random, err := helper.RandomToken(32)
+	if err != nil {
+		return model.ApiToken{}, "", err
+	}
+	raw := Prefix + random
+
+	token, err := s.tokens.Create(ctx, model.ApiToken{
+		Id:          primitive.NewObjectID(),
+		UserId:      uid,
+		Name:        name,
+		Prefix:      raw[:prefixLength],
+		TokenHash:   helper.HashToken(raw),
+		Permissions: permissions,
+		ExpiresAt:   expiresAt,
+		CreatedAt:   time.Now().UTC().Truncate(time.Millisecond),
+	})
+	return token, raw, err
+}
+
+// Authenticate returns the token raw and the user it acts for, and records
+// that it was used.
+func (s *Service) Authenticate(ctx context.Context, raw string) (model.ApiToken, model.User, error) {
+	token, err := s.tokens.FindByHash(ctx, helper.HashToken(raw))
+	if errors.Is(err, repository.ErrNotFound) {
+		return token, model.User{}, ErrInvalidToken
+	}
+	if err != nil {
+		return token, model.User{}, err
+	}
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	if token.ExpiresAt != nil && !now.Before(*token.ExpiresAt) {
+		return token, model.User{}, ErrInvalidToken
+	}
+	user, err := s.users.FindByUserID(ctx, token.UserId)
+	if errors.Is(err, repository.ErrNotFound) {
+		return token, user, ErrInvalidToken
+	}
+	if err != nil {
+		return token, user, err
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= lastUsedPrecision {
+		// the token may have been deleted since it was read
+		if err := s.tokens.MarkUsed(ctx, token, now); errors.Is(err, repository.ErrNotFound) {
+			return token, user, ErrInvalidToken
+		} else if err != nil {
+			return token, user, err
+		}
+		token.LastUsedAt = &now
+	}
+	return token, user, nil
+}
@@ -0,0 +1,121 @@
+package apitoken
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"golang_cms/helper"
+	"golang_cms/model"
+	"golang_cms/rbac"
+	"golang_cms/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func newService(t *testing.T) (*Service, repository.Repositories) {
+	t.Helper()
+	repos := repository.NewMemoryRepositories()
+	email := "ada@example.com"
+	_, err := repos.User.Create(context.Background(), model.User{ID: primitive.NewObjectID(), User_id: "ada", Email: &email, Phone: &email, Role: rbac.RoleAuthor})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewService(repos.ApiToken, repos.User, rbac.NewAuthorizer(repos.User, repos.Role)), repos
+}
+
+func TestCreate(t *testing.T) {
+	ctx := context.Background()
+	s, repos := newService(t)
+
+	token, raw, err := s.Create(ctx, "ada", "ci", []string{"banner:read", "banner:write"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsApiToken(raw) || !strings.HasPrefix(raw, token.Prefix) || len(token.Prefix) != prefixLength {
+		t.Errorf("raw %q, prefix %q", raw, token.Prefix)
+	}
+	stored, err := repos.ApiToken.FindByID(ctx, token.Id)
+	if err != nil || stored.TokenHash != helper.HashToken(raw) || strings.Contains(stored.TokenHash, raw) {
+		t.Errorf("stored = %+v, %v", stored, err)
+	}
+	if stored.UserId != "ada" || stored.Name != "ci" || len(stored.Permissions) != 2 {
+		t.Errorf("stored = %+v", stored)
+	}
+
+	if _, _, err := s.Create(ctx, "ada", "ci", []string{"banner:publish"}, nil); !errors.Is(err, ErrNotGranted) {
+		t.Errorf("permission the role lacks = %v", err)
+	}
+	// an author may read and write banners but not publish them
+	if _, _, err := s.Create(ctx, "ada", "ci", []string{"banner:*"}, nil); !errors.Is(err, ErrNotGranted) {
+		t.Errorf("wildcard wider than the role = %v", err)
+	}
+	if _, _, err := s.Create(ctx, "ada", "ci", []string{"*"}, nil); !errors.Is(err, ErrNotGranted) {
+		t.Errorf("wildcard wider than the role = %v", err)
+	}
+	if _, _, err := s.Create(ctx, "ada", "ci", []string{"user:read"}, nil); !errors.Is(err, ErrNotGranted) {
+		t.Errorf("permission the role lacks = %v", err)
+	}
+	if _, _, err := s.Create(ctx, "ada", "ci", []string{"banner:delete"}, nil); !errors.Is(err, rbac.ErrUnknownPermission) {
+		t.Errorf("unknown permission = %v", err)
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	ctx := context.Background()
+	s, repos := newService(t)
+	token, raw, err := s.Create(ctx, "ada", "ci", []string{"banner:read"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, user, err := s.Authenticate(ctx, raw)
+	if err != nil || got.Id != token.Id || user.User_id != "ada" || got.LastUsedAt == nil {
+		t.Fatalf("Authenticate = %+v, %+v, %v", got, user, err)
+	}
+	stored, _ := repos.ApiToken.FindByID(ctx, token.Id)
+	if stored.LastUsedAt == nil {
+		t.Error("last use not recorded")
+	}
+
+	if _, _, err := s.Authenticate(ctx, Prefix+"made-up"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("unknown token = %v", err)
+	}
+	if _, _, err := s.Authenticate(ctx, raw+"x"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("altered token = %v", err)
+	}
+
+	if err := repos.ApiToken.Delete(ctx, token.Id); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.Authenticate(ctx, raw); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("deleted token = %v", err)
+	}
+}
+
+func TestAuthenticateExpired(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newService(t)
+	past := time.Now().Add(-time.Minute)
+	_, raw, err := s.Create(ctx, "ada", "ci", []string{"banner:read"}, &past)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.Authenticate(ctx, raw); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expired token = %v", err)
+	}
+
+	future := time.Now().Add(time.Hour)
+	_, raw, _ = s.Create(ctx, "ada", "ci", []string{"banner:read"}, &future)
+	if _, _, err := s.Authenticate(ctx, raw); err != nil {
+		t.Errorf("token before its expiry = %v", err)
+	}
+}
+
+func TestIsApiToken(t *testing.T) {
+	if !IsApiToken("cms_abc") || IsApiToken("eyJhbGciOiJIUzI1NiJ9.x.y") {
+		t.Error("IsApiToken mixes up API and access tokens")
+	}
+}
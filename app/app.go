@@ -0,0 +1,250 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"golang_cms/audit"
+	"golang_cms/config"
+	"golang_cms/helper"
+	"golang_cms/mailer"
+	"golang_cms/media"
+	"golang_cms/migration"
+	"golang_cms/passwordreset"
+	"golang_cms/repository"
+	"golang_cms/routes"
+	"golang_cms/scheduler"
+	"golang_cms/search"
+	"golang_cms/session"
+	"golang_cms/trash"
+	"golang_cms/workflow"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+	"gorm.io/gorm"
+)
+
+// App owns the database connection and everything built on top of it.
+// Nothing connects until New is called, and Close releases the connection.
+type App struct {
+	Config *config.Config
+	Mongo  *mongo.Client
+	SQL    *gorm.DB
+	Repos  repository.Repositories
+	Search search.Index
+	Media  *media.Library
+	Router *gin.Engine
+
+	// Scheduler runs the background jobs while Run serves.
+	Scheduler *scheduler.Scheduler
+
+	resets       *passwordreset.Service
+	shuttingDown int32
+}
+
+// New connects to the configured database, retrying with backoff, and wires
+// the repositories, audit log, search index, media library, mailer, router and
+// background jobs.
+func New(ctx context.Context, cfg *config.Config) (*App, error) {
+	gin.SetMode(cfg.Server.Mode)
+	helper.SetSecretKey(cfg.Auth.SecretKey)
+	helper.SetAccessTokenTTL(time.Duration(cfg.Auth.AccessTokenTTL))
+
+	a, err := Connect(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Search.Driver == "mongo" || (cfg.Search.Driver == "" && a.Mongo != nil) {
+		a.Search = search.NewMongoIndex(a.Mongo.Database(cfg.Database.Name))
+	} else {
+		index := search.NewMemoryIndex()
+		if err := search.Rebuild(ctx, a.Repos, index); err != nil {
+			a.Close(ctx)
+			return nil, err
+		}
+		a.Search = index
+		a.Repos = search.Indexed(a.Repos, index)
+	}
+	a.Repos = audit.Recorded(a.Repos, audit.NewLog(a.Repos.Audit))
+
+	var storage media.Storage
+	if cfg.Media.Driver == "gridfs" {
+		storage = media.NewGridFSStorage(a.Mongo.Database(cfg.Database.Name))
+	} else {
+		local, err := media.NewLocalStorage(cfg.Media.Dir)
+		if err != nil {
+			a.Close(ctx)
+			return nil, err
+		}
+		storage = local
+	}
+	cache, err := media.NewDiskCache(cfg.Media.CacheDir, cfg.Media.CacheMaxSize)
+	if err != nil {
+		a.Close(ctx)
+		return nil, err
+	}
+	variants := make([]media.Variant, len(cfg.Media.Variants))
+	for i, v := range cfg.Media.Variants {
+		variants[i] = media.Variant{Name: v.Name, Transform: media.Transform{Width: v.Width, Height: v.Height, Fit: media.Fit(v.Fit), Format: v.Format}}
+	}
+	a.Media = media.NewLibrary(storage, cache,
+		media.Limits{MaxSize: cfg.Media.MaxSize, AllowedTypes: cfg.Media.AllowedTypes},
+		media.ImageOptions{Variants: variants, MaxDimension: cfg.Media.MaxDimension, MaxPixels: cfg.Media.MaxPixels, ResizeStep: cfg.Media.ResizeStep, Quality: cfg.Media.JPEGQuality})
+
+	var mail mailer.Mailer
+	switch cfg.Mail.Driver {
+	case "smtp":
+		mail = mailer.NewSMTPMailer(cfg.Mail.Host, cfg.Mail.Port, cfg.Mail.Username, cfg.Mail.Password, cfg.Mail.From, cfg.Mail.Insecure)
+	case "file":
+		files, err := mailer.NewFileMailer(cfg.Mail.Dir, cfg.Mail.From)
+		if err != nil {
+			a.Close(ctx)
+			return nil, err
+		}
+		mail = files
+	default:
+		mail = mailer.LogMailer{}
+	}
+
+	sessions := session.NewManager(a.Repos.Refresh, a.Repos.Revoked, a.Repos.User,
+		time.Duration(cfg.Auth.RefreshTokenTTL), time.Duration(cfg.Auth.RevocationCacheTTL))
+	a.resets = passwordreset.NewService(a.Repos, sessions, mail,
+		time.Duration(cfg.Auth.PasswordResetTTL), cfg.Auth.PasswordResetURL)
+	a.Router = routes.NewRouter(a.Repos, a.Search, a.Media, sessions, a.resets)
+	routes.HealthRoutes(a.Router, a.Ready)
+	jobs := []scheduler.Job{
+		workflow.Job(a.Repos, time.Duration(cfg.Scheduler.Interval)),
+		session.Job(a.Repos, time.Duration(cfg.Scheduler.Interval)),
+		passwordreset.Job(a.Repos, time.Duration(cfg.Scheduler.Interval)),
+	}
+	if cfg.Trash.RetentionDays > 0 {
+		retention := time.Duration(cfg.Trash.RetentionDays) * 24 * time.Hour
+		jobs = append(jobs, trash.Job(a.Repos, retention, time.Duration(cfg.Trash.PurgeInterval)))
+	}
+	a.Scheduler = scheduler.New(jobs...)
+	return a, nil
+}
+
+// Connect connects to the configured database, retrying with backoff, and
+// wires the repositories but nothing on top of them, for the commands that
+// work on the data without serving it.
+func Connect(ctx context.Context, cfg *config.Config) (*App, error) {
+	a := &App{Config: cfg}
+	switch cfg.Database.Driver {
+	case "mongo":
+		client, err := ConnectMongo(ctx, cfg.Database)
+		if err != nil {
+			return nil, err
+		}
+		a.Mongo = client
+		db := client.Database(cfg.Database.Name)
+		if pending, err := migration.New(db).Pending(ctx); err == nil && pending > 0 {
+			log.Printf("%d database migrations are pending, run \"migrate up\"", pending)
+		}
+		a.Repos = repository.NewMongoRepositories(db)
+	default:
+		db, err := connectSQL(ctx, cfg.Database)
+		if err != nil {
+			return nil, err
+		}
+		a.SQL = db
+		if a.Repos, err = repository.NewSQLRepositories(db); err != nil {
+			a.Close(ctx)
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+// Ready reports whether the app can serve traffic: it is not shutting down
+// and the database answers a ping.
+func (a *App) Ready(ctx context.Context) error {
+	if atomic.LoadInt32(&a.shuttingDown) == 1 {
+		return errors.New("shutting down")
+	}
+	if a.Mongo != nil {
+		return a.Mongo.Ping(ctx, nil)
+	}
+	if a.SQL != nil {
+		sqlDB, err := a.SQL.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	}
+	return nil
+}
+
+// Run serves HTTP, or HTTPS when a certificate is configured, and runs the
+// background jobs until ctx is cancelled. In-flight requests are then given
+// ShutdownTimeout to finish.
+func (a *App) Run(ctx context.Context) error {
+	jobsCtx, stopJobs := context.WithCancel(ctx)
+	defer a.Scheduler.Wait()
+	defer stopJobs()
+	a.Scheduler.Start(jobsCtx)
+
+	server := &http.Server{
+		Addr:              ":" + a.Config.Server.Port,
+		Handler:           a.Router,
+		ReadTimeout:       time.Duration(a.Config.Server.ReadTimeout),
+		ReadHeaderTimeout: time.Duration(a.Config.Server.ReadHeaderTimeout),
+		WriteTimeout:      time.Duration(a.Config.Server.WriteTimeout),
+		IdleTimeout:       time.Duration(a.Config.Server.IdleTimeout),
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		log.Printf("Listening on %s", server.Addr)
+		if a.Config.Server.TLSCertFile != "" {
+			serveErr <- server.ListenAndServeTLS(a.Config.Server.TLSCertFile, a.Config.Server.TLSKeyFile)
+		} else {
+			serveErr <- server.ListenAndServe()
+		}
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Println("Shutting down, draining in-flight requests")
+	atomic.StoreInt32(&a.shuttingDown, 1)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(a.Config.Server.ShutdownTimeout))
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Close waits for the password reset emails still being sent and
+// disconnects from the database.
+func (a *App) Close(ctx context.Context) error {
+	if a.resets != nil {
+		if err := a.resets.Wait(ctx); err != nil {
+			log.Printf("password resets: stopped waiting for emails being sent: %v", err)
+		}
+	}
+	if a.Mongo != nil {
+		return a.Mongo.Disconnect(ctx)
+	}
+	if a.SQL != nil {
+		sqlDB, err := a.SQL.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.Close()
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"golang_cms/config"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+const maxRetryBackoff = 30 * time.Second
+
+// retry runs connect until it succeeds, doubling the wait between attempts.
+func retry(ctx context.Context, cfg config.DatabaseConfig, name string, connect func() error) error {
+	backoff := time.Duration(cfg.RetryBackoff)
+	for attempt := 0; ; attempt++ {
+		err := connect()
+		if err == nil {
+			return nil
+		}
+		if attempt >= cfg.ConnectRetries {
+			return fmt.Errorf("connect to %s: %w", name, err)
+		}
+
+		log.Printf("Connecting to %s failed (%v), retrying in %s", name, err, backoff)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > maxRetryBackoff {
+			backoff = maxRetryBackoff
+		}
+	}
+}
+
+// ConnectMongo connects and pings MongoDB, retrying with backoff as configured.
+func ConnectMongo(ctx context.Context, cfg config.DatabaseConfig) (*mongo.Client, error) {
+	opts := options.Client().
+		ApplyURI(cfg.MongoURI).
+		SetMaxPoolSize(cfg.MaxPoolSize).
+		SetMinPoolSize(cfg.MinPoolSize).
+		SetMaxConnIdleTime(time.Duration(cfg.MaxIdleTime)).
+		SetConnectTimeout(time.Duration(cfg.ConnectTimeout))
+
+	var client *mongo.Client
+	err := retry(ctx, cfg, "MongoDB", func() error {
+		attemptCtx, cancel := context.WithTimeout(ctx, time.Duration(cfg.ConnectTimeout))
+		defer cancel()
+
+		c, err := mongo.Connect(attemptCtx, opts)
+		if err != nil {
+			return err
+		}
+
+		//ping the database
+		if err := c.Ping(attemptCtx, nil); err != nil {
+			c.Disconnect(context.Background())
+			return err
+		}
+		client = c
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Println("Connected to MongoDB Successfully!")
+	return client, nil
+}
+
+func connectSQL(ctx context.Context, cfg config.DatabaseConfig) (*gorm.DB, error) {
+	var dialector gorm.Dialector
+	switch cfg.Driver {
+	case "sqlite":
+		dialector = sqlite.Open(cfg.DSN)
+	case "postgres":
+		dialector = postgres.Open(cfg.DSN)
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", cfg.Driver)
+	}
+
+	var db *gorm.DB
+	err := retry(ctx, cfg, cfg.Driver, func() error {
+		d, err := gorm.Open(dialector, &gorm.Config{})
+		if err != nil {
+			return err
+		}
+
+		sqlDB, err := d.DB()
+		if err != nil {
+			return err
+		}
+		sqlDB.SetMaxOpenConns(int(cfg.MaxPoolSize))
+		sqlDB.SetMaxIdleConns(int(cfg.MinPoolSize))
+		sqlDB.SetConnMaxIdleTime(time.Duration(cfg.MaxIdleTime))
+
+		attemptCtx, cancel := context.WithTimeout(ctx, time.Duration(cfg.ConnectTimeout))
+		defer cancel()
+		if err := sqlDB.PingContext(attemptCtx); err != nil {
+			sqlDB.Close()
+			return err
+		}
+		db = d
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("Connected to %s Successfully!\n", cfg.Driver)
+	return db, nil
+}
@@ -0,0 +1,47 @@
+// Package audit keeps an append-only log of every create, update and delete
+// of users and content: who made it, from where, and what changed.
+package audit
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Actor is whoever a change is attributed to.
+type Actor struct {
+	Uid       string
+	Email     string
+	Ip        string
+	UserAgent string
+}
+
+type actorKey struct{}
+
+// WithActor attributes the changes made with ctx to actor.
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// System is the actor of the changes a background job makes on its own.
+func System(job string) Actor {
+	return Actor{Uid: "system:" + job}
+}
+
+// ActorOf returns the actor of ctx: the one set with WithActor or, for a
+// context derived from a request, the authenticated user and the client.
+// It is empty for anything else.
+func ActorOf(ctx context.Context) Actor {
+	if actor, ok := ctx.Value(actorKey{}).(Actor); ok {
+		return actor
+	}
+	if c, ok := ctx.Value(gin.ContextKey).(*gin.Context); ok && c.Request != nil {
+		return Actor{
+			Uid:       c.GetString("uid"),
+			Email:     c.GetString("email"),
+			Ip:        c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+		}
+	}
+	return Actor{}
+}
@@ -0,0 +1,139 @@
+package audit
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"golang_cms/helper"
+	"golang_cms/model"
+	"golang_cms/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// redacted are the fields whose values are never written to the log; a
+// change to them is recorded without the old and new value.
+var redacted = map[string]bool{
+	"password":      true,
+	"token":         true,
+	"refresh_token": true,
+}
+
+const redactedValue = "[redacted]"
+
+// Log writes audit entries to a repository.
+type Log struct {
+	repo  repository.AuditRepository
+	locks keyedMutex
+}
+
+func NewLog(repo repository.AuditRepository) *Log {
+	return &Log{repo: repo}
+}
+
+// Record logs that the actor of ctx applied action to resource id of
+// resourceType, which went from before to after. before is nil for a
+// create and after is nil for a delete. An update that changed nothing is
+// not logged.
+func (l *Log) Record(ctx context.Context, action string, resourceType string, resourceId string, before interface{}, after interface{}) error {
+	from, err := helper.Snapshot(before)
+	if err != nil {
+		return err
+	}
+	to, err := helper.Snapshot(after)
+	if err != nil {
+		return err
+	}
+
+	changes := helper.Diff(from, to)
+	if action == ActionUpdate && len(changes) == 0 {
+		return nil
+	}
+	for i, change := range changes {
+		if !redacted[change.Field] {
+			continue
+		}
+		if change.From != nil {
+			changes[i].From = redactedValue
+		}
+		if change.To != nil {
+			changes[i].To = redactedValue
+		}
+	}
+
+	actor := ActorOf(ctx)
+	_, err = l.repo.Create(ctx, model.AuditEntry{
+		Id:           primitive.NewObjectID(),
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceId:   resourceId,
+		ActorUid:     actor.Uid,
+		ActorEmail:   actor.Email,
+		Ip:           actor.Ip,
+		UserAgent:    actor.UserAgent,
+		Changes:      changes,
+		CreatedAt:    time.Now().UTC().Truncate(time.Millisecond),
+	})
+	return err
+}
+
+// recordWritten records a change that is already stored. Failing the
+// request would report a stored change as failed and invite a retry that
+// repeats it, so an entry that cannot be written is logged instead.
+func (l *Log) recordWritten(ctx context.Context, action string, resourceType string, resourceId string, before interface{}, after interface{}) {
+	if err := l.Record(ctx, action, resourceType, resourceId, before, after); err != nil {
+		log.Printf("audit: recording %s of %s %s failed: %v", action, resourceType, resourceId, err)
+	}
+}
+
+// lock serializes the audited writes of key within this instance, so that
+// the state read before a write is the one the write replaces. Writes of
+// other instances can still come in between.
+func (l *Log) lock(key string) (unlock func()) {
+	return l.locks.lock(key)
+}
+
+// keyedMutex hands out a mutex per key, dropping it once nobody holds or
+// waits for it.
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*keyedLock
+}
+
+type keyedLock struct {
+	sync.Mutex
+	refs int
+}
+
+func (m *keyedMutex) lock(key string) (unlock func()) {
+	m.mu.Lock()
+	if m.locks == nil {
+		m.locks = map[string]*keyedLock{}
+	}
+	l := m.locks[key]
+	if l == nil {
+		l = &keyedLock{}
+		m.locks[key] = l
+	}
+	l.refs++
+	m.mu.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+		m.mu.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(m.locks, key)
+		}
+		m.mu.Unlock()
+	}
+}
@@ -0,0 +1,298 @@
+package audit
+
+import (
+	"context"
+
+	"golang_cms/model"
+	"golang_cms/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Recorded wraps the repositories of repos so that every create, update
+// and delete that succeeds is also written to log, attributed to the actor
+// of its context. A change is stored whether or not its entry can be
+// written; entries that fail are reported in the application log.
+func Recorded(repos repository.Repositories, log *Log) repository.Repositories {
+	repos.Banner = &auditedRepository[model.Banner]{repos.Banner, log, "banner", false}
+	repos.Meta = &auditedRepository[model.Meta]{repos.Meta, log, "meta", false}
+	repos.Desc = &auditedRepository[model.Desc]{repos.Desc, log, "desc", false}
+	repos.Category = &auditedRepository[model.MainCategory]{repos.Category, log, "kategori", false}
+	repos.Child = &auditedChildRepository{
+		ChildCategoryRepository: repos.Child,
+		audited:                 &auditedRepository[model.ChildCategory]{repos.Child, log, "child_kategori", false},
+	}
+	repos.Tree = &auditedTreeRepository{
+		CategoryTreeRepository: repos.Tree,
+		audited:                &auditedRepository[model.Category]{repos.Tree, log, "category", true},
+	}
+	repos.Product = &auditedProductRepository{
+		ProductRepository: repos.Product,
+		audited:           &auditedRepository[model.Product]{repos.Product, log, "product", false},
+	}
+	repos.Media = &auditedMediaRepository{
+		MediaRepository: repos.Media,
+		audited:         &auditedRepository[model.Media]{repos.Media, log, "media", false},
+	}
+	repos.Role = &auditedRoleRepository{
+		RoleRepository: repos.Role,
+		audited:        &auditedRepository[model.Role]{repos.Role, log, "role", false},
+	}
+	repos.ApiToken = &auditedApiTokenRepository{
+		ApiTokenRepository: repos.ApiToken,
+		audited:            &auditedRepository[model.ApiToken]{repos.ApiToken, log, "api_token", false},
+	}
+	repos.User = &auditedUserRepository{repos.User, log}
+	return repos
+}
+
+func idOf(doc interface{}) (primitive.ObjectID, error) {
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	var key struct {
+		Id primitive.ObjectID `bson:"_id"`
+	}
+	err = bson.Unmarshal(raw, &key)
+	return key.Id, err
+}
+
+// auditedRepository logs the writes of a CRUD repository. Writes of one
+// document are serialized, or of every document of the resource when
+// writes of one can change others, as moves in the category tree do.
+type auditedRepository[T any] struct {
+	repository.CrudRepository[T]
+	log           *Log
+	resourceType  string
+	wholeResource bool
+}
+
+func (r *auditedRepository[T]) lock(id primitive.ObjectID) (unlock func()) {
+	if r.wholeResource {
+		return r.log.lock(r.resourceType)
+	}
+	return r.log.lock(r.resourceType + ":" + id.Hex())
+}
+
+func (r *auditedRepository[T]) Create(ctx context.Context, doc T) (T, error) {
+	created, err := r.CrudRepository.Create(ctx, doc)
+	if err != nil {
+		return created, err
+	}
+	id, _ := idOf(created)
+	r.log.recordWritten(ctx, ActionCreate, r.resourceType, id.Hex(), nil, created)
+	return created, nil
+}
+
+func (r *auditedRepository[T]) Update(ctx context.Context, doc T) (T, error) {
+	id, err := idOf(doc)
+	if err != nil {
+		return doc, err
+	}
+	defer r.lock(id)()
+	before, err := r.CrudRepository.FindByID(ctx, id)
+	if err != nil {
+		return before, err
+	}
+
+	updated, err := r.CrudRepository.Update(ctx, doc)
+	if err != nil {
+		return updated, err
+	}
+	r.log.recordWritten(ctx, ActionUpdate, r.resourceType, id.Hex(), before, updated)
+	return updated, nil
+}
+
+func (r *auditedRepository[T]) Delete(ctx context.Context, id primitive.ObjectID) error {
+	defer r.lock(id)()
+	before, err := r.CrudRepository.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := r.CrudRepository.Delete(ctx, id); err != nil {
+		return err
+	}
+	r.log.recordWritten(ctx, ActionDelete, r.resourceType, id.Hex(), before, nil)
+	return nil
+}
+
+// auditedChildRepository, auditedTreeRepository, auditedProductRepository,
+// auditedMediaRepository, auditedRoleRepository and auditedApiTokenRepository
+// route the writes of the shared CRUD methods through auditedRepository and
+// leave the reads as they are.
+type auditedChildRepository struct {
+	repository.ChildCategoryRepository
+	audited *auditedRepository[model.ChildCategory]
+}
+
+func (r *auditedChildRepository) Create(ctx context.Context, doc model.ChildCategory) (model.ChildCategory, error) {
+	return r.audited.Create(ctx, doc)
+}
+
+func (r *auditedChildRepository) Update(ctx context.Context, doc model.ChildCategory) (model.ChildCategory, error) {
+	return r.audited.Update(ctx, doc)
+}
+
+func (r *auditedChildRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return r.audited.Delete(ctx, id)
+}
+
+type auditedTreeRepository struct {
+	repository.CategoryTreeRepository
+	audited *auditedRepository[model.Category]
+}
+
+func (r *auditedTreeRepository) Create(ctx context.Context, doc model.Category) (model.Category, error) {
+	return r.audited.Create(ctx, doc)
+}
+
+func (r *auditedTreeRepository) Update(ctx context.Context, doc model.Category) (model.Category, error) {
+	return r.audited.Update(ctx, doc)
+}
+
+func (r *auditedTreeRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return r.audited.Delete(ctx, id)
+}
+
+// Move is logged as an update of the moved category. The path and depth of
+// its descendants follow from it and are not logged one by one.
+func (r *auditedTreeRepository) Move(ctx context.Context, id primitive.ObjectID, parentId *primitive.ObjectID, position int) (model.Category, error) {
+	defer r.audited.lock(id)()
+	before, err := r.CategoryTreeRepository.FindByID(ctx, id)
+	if err != nil {
+		return before, err
+	}
+
+	moved, err := r.CategoryTreeRepository.Move(ctx, id, parentId, position)
+	if err != nil {
+		return moved, err
+	}
+	r.audited.log.recordWritten(ctx, ActionUpdate, r.audited.resourceType, id.Hex(), before, moved)
+	return moved, nil
+}
+
+// Reorder is logged as an update of every sibling whose position changed.
+func (r *auditedTreeRepository) Reorder(ctx context.Context, parentId *primitive.ObjectID, ids []primitive.ObjectID) ([]model.Category, error) {
+	defer r.audited.lock(primitive.NilObjectID)()
+	siblings, err := r.CategoryTreeRepository.FindChildren(ctx, parentId)
+	if err != nil {
+		return nil, err
+	}
+	before := make(map[primitive.ObjectID]model.Category, len(siblings))
+	for _, sibling := range siblings {
+		before[sibling.Id] = sibling
+	}
+
+	reordered, err := r.CategoryTreeRepository.Reorder(ctx, parentId, ids)
+	if err != nil {
+		return reordered, err
+	}
+	for _, category := range reordered {
+		r.audited.log.recordWritten(ctx, ActionUpdate, r.audited.resourceType, category.Id.Hex(), before[category.Id], category)
+	}
+	return reordered, nil
+}
+
+type auditedProductRepository struct {
+	repository.ProductRepository
+	audited *auditedRepository[model.Product]
+}
+
+func (r *auditedProductRepository) Create(ctx context.Context, doc model.Product) (model.Product, error) {
+	return r.audited.Create(ctx, doc)
+}
+
+func (r *auditedProductRepository) Update(ctx context.Context, doc model.Product) (model.Product, error) {
+	return r.audited.Update(ctx, doc)
+}
+
+func (r *auditedProductRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return r.audited.Delete(ctx, id)
+}
+
+type auditedMediaRepository struct {
+	repository.MediaRepository
+	audited *auditedRepository[model.Media]
+}
+
+func (r *auditedMediaRepository) Create(ctx context.Context, doc model.Media) (model.Media, error) {
+	return r.audited.Create(ctx, doc)
+}
+
+func (r *auditedMediaRepository) Update(ctx context.Context, doc model.Media) (model.Media, error) {
+	return r.audited.Update(ctx, doc)
+}
+
+func (r *auditedMediaRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return r.audited.Delete(ctx, id)
+}
+
+type auditedRoleRepository struct {
+	repository.RoleRepository
+	audited *auditedRepository[model.Role]
+}
+
+func (r *auditedRoleRepository) Create(ctx context.Context, doc model.Role) (model.Role, error) {
+	return r.audited.Create(ctx, doc)
+}
+
+func (r *auditedRoleRepository) Update(ctx context.Context, doc model.Role) (model.Role, error) {
+	return r.audited.Update(ctx, doc)
+}
+
+func (r *auditedRoleRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return r.audited.Delete(ctx, id)
+}
+
+// auditedApiTokenRepository leaves MarkUsed out of the log, which would
+// otherwise get an entry every time a token is used.
+type auditedApiTokenRepository struct {
+	repository.ApiTokenRepository
+	audited *auditedRepository[model.ApiToken]
+}
+
+func (r *auditedApiTokenRepository) Create(ctx context.Context, doc model.ApiToken) (model.ApiToken, error) {
+	return r.audited.Create(ctx, doc)
+}
+
+func (r *auditedApiTokenRepository) Update(ctx context.Context, doc model.ApiToken) (model.ApiToken, error) {
+	return r.audited.Update(ctx, doc)
+}
+
+func (r *auditedApiTokenRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return r.audited.Delete(ctx, id)
+}
+
+// auditedUserRepository logs the writes of users, which are keyed by their
+// user_id rather than an ObjectID. Passwords and tokens are redacted.
+type auditedUserRepository struct {
+	repository.UserRepository
+	log *Log
+}
+
+func (r *auditedUserRepository) Create(ctx context.Context, user model.User) (model.User, error) {
+	created, err := r.UserRepository.Create(ctx, user)
+	if err != nil {
+		return created, err
+	}
+	r.log.recordWritten(ctx, ActionCreate, "user", created.User_id, nil, created)
+	return created, nil
+}
+
+func (r *auditedUserRepository) Update(ctx context.Context, user model.User) (model.User, error) {
+	defer r.log.lock("user:" + user.User_id)()
+	before, err := r.UserRepository.FindByUserID(ctx, user.User_id)
+	if err != nil {
+		return before, err
+	}
+
+	updated, err := r.UserRepository.Update(ctx, user)
+	if err != nil {
+		return updated, err
+	}
+	r.log.recordWritten(ctx, ActionUpdate, "user", user.User_id, before, updated)
+	return updated, nil
+}
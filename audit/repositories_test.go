@@ -0,0 +1,105 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"golang_cms/model"
+	"golang_cms/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// failingAudit is an audit repository that cannot write.
+type failingAudit struct {
+	repository.AuditRepository
+}
+
+func (failingAudit) Create(ctx context.Context, entry model.AuditEntry) (model.AuditEntry, error) {
+	return entry, errors.New("audit store is down")
+}
+
+func TestAuditFailureDoesNotFailWrite(t *testing.T) {
+	ctx := context.Background()
+	repos := repository.NewMemoryRepositories()
+	recorded := Recorded(repos, NewLog(failingAudit{repos.Audit}))
+
+	banner, err := recorded.Banner.Create(ctx, model.Banner{Id: primitive.NewObjectID(), Banner: "a.png", Alt: "a", Link: "/a"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	banner.Alt = "b"
+	if _, err := recorded.Banner.Update(ctx, banner); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	stored, err := repos.Banner.FindByID(ctx, banner.Id)
+	if err != nil || stored.Alt != "b" {
+		t.Fatalf("stored banner = %+v, %v; want alt b", stored, err)
+	}
+	if err := recorded.Banner.Delete(ctx, banner.Id); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+}
+
+func TestConcurrentUpdatesAreRecordedInOrder(t *testing.T) {
+	ctx := context.Background()
+	repos := repository.NewMemoryRepositories()
+	recorded := Recorded(repos, NewLog(repos.Audit))
+
+	banner, err := recorded.Banner.Create(ctx, model.Banner{Id: primitive.NewObjectID(), Banner: "a.png", Alt: "start", Link: "/a"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	const writers = 20
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			update := banner
+			update.Alt = fmt.Sprintf("alt %d", i)
+			if _, err := recorded.Banner.Update(ctx, update); err != nil {
+				t.Errorf("update %d: %v", i, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	entries, _, err := repos.Audit.List(ctx, repository.Query{Filter: repository.Filter{"resource_type": "banner", "action": ActionUpdate}})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(entries) != writers {
+		t.Fatalf("got %d update entries, want %d", len(entries), writers)
+	}
+	// every update replaced a different value, so no two entries share the
+	// value they changed from
+	from := map[interface{}]bool{}
+	for _, entry := range entries {
+		for _, change := range entry.Changes {
+			if change.Field != "alt" {
+				continue
+			}
+			if from[change.From] {
+				t.Fatalf("two updates recorded as changing alt from %v", change.From)
+			}
+			from[change.From] = true
+		}
+	}
+	if !from["start"] {
+		t.Fatal("no update recorded as changing the initial alt")
+	}
+}
+
+func TestKeyedMutexReleasesKeys(t *testing.T) {
+	var m keyedMutex
+	unlock := m.lock("a")
+	unlock()
+	if len(m.locks) != 0 {
+		t.Fatalf("%d locks left after unlocking", len(m.locks))
+	}
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+// valid returns the defaults of env with the settings that have none.
+func valid(env string) Config {
+	cfg := Defaults(env)
+	cfg.Database.MongoURI = "mongodb://localhost:27017"
+	cfg.Auth.SecretKey = strings.Repeat("s", 32)
+	cfg.Mail.Host = "smtp.example.com"
+	return cfg
+}
+
+func TestDefaultsAreValid(t *testing.T) {
+	for _, env := range []string{EnvDev, EnvStaging, EnvProd} {
+		if err := valid(env).Validate(); err != nil {
+			t.Errorf("%s: %v", env, err)
+		}
+	}
+}
+
+func TestValidateProd(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(*Config)
+		problem string
+	}{
+		{"sqlite", func(cfg *Config) { cfg.Database.Driver, cfg.Database.DSN = "sqlite", "cms.db" }, "sqlite driver is not allowed in prod"},
+		{"short secret", func(cfg *Config) { cfg.Auth.SecretKey = "short" }, "at least 32 characters"},
+		{"log mail", func(cfg *Config) { cfg.Mail.Driver = "log" }, "log mail driver is not allowed in prod"},
+		{"file mail", func(cfg *Config) { cfg.Mail.Driver = "file" }, "file mail driver is not allowed in prod"},
+		{"smtp without host", func(cfg *Config) { cfg.Mail.Host = "" }, "mail host is required"},
+	}
+	for _, tt := range tests {
+		cfg := valid(EnvProd)
+		tt.change(&cfg)
+		err := cfg.Validate()
+		if err == nil || !strings.Contains(err.Error(), tt.problem) {
+			t.Errorf("%s: Validate() = %v, want %q", tt.name, err, tt.problem)
+		}
+
+		// the same settings are fine outside prod
+		cfg.Env = EnvDev
+		if tt.name != "smtp without host" {
+			if err := cfg.Validate(); err != nil {
+				t.Errorf("%s in dev: %v", tt.name, err)
+			}
+		}
+	}
+}
+
+func TestMailDriverDefaults(t *testing.T) {
+	if driver := Defaults(EnvDev).Mail.Driver; driver != "log" {
+		t.Errorf("dev mail driver = %q, want log", driver)
+	}
+	if driver := Defaults(EnvProd).Mail.Driver; driver != "smtp" {
+		t.Errorf("prod mail driver = %q, want smtp", driver)
+	}
+}
@@ -1,17 +1,148 @@
 package config
 
 import (
-	"log"
+	"errors"
+	"fmt"
+	"io/fs"
 	"os"
+	"strconv"
 
 	"github.com/joho/godotenv"
 )
 
-func EnvMongoURI() string {
+// loadDotEnv copies the variables of a .env file into the process
+// environment. Variables that are already set are left untouched and a
+// missing file is not an error.
+func loadDotEnv() error {
 	err := godotenv.Load()
-	if err != nil {
-		log.Fatal("Error load the .env file, try again later")
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
 	}
+	return err
+}
 
-	return os.Getenv("MONGOURI")
-}
\ No newline at end of file
+func lookupEnv(key string) string {
+	return os.Getenv(key)
+}
+
+// applyEnv overrides cfg with the environment variables that are set.
+func applyEnv(cfg *Config) error {
+	env := &envReader{}
+	env.string(&cfg.Server.Port, "PORT")
+	env.string(&cfg.Server.Mode, "GIN_MODE")
+	env.duration(&cfg.Server.ReadTimeout, "SERVER_READ_TIMEOUT")
+	env.duration(&cfg.Server.ReadHeaderTimeout, "SERVER_READ_HEADER_TIMEOUT")
+	env.duration(&cfg.Server.WriteTimeout, "SERVER_WRITE_TIMEOUT")
+	env.duration(&cfg.Server.IdleTimeout, "SERVER_IDLE_TIMEOUT")
+	env.duration(&cfg.Server.ShutdownTimeout, "SERVER_SHUTDOWN_TIMEOUT")
+	env.string(&cfg.Server.TLSCertFile, "TLS_CERT_FILE")
+	env.string(&cfg.Server.TLSKeyFile, "TLS_KEY_FILE")
+	env.string(&cfg.Database.Driver, "DB_DRIVER")
+	env.string(&cfg.Database.MongoURI, "MONGOURI")
+	env.string(&cfg.Database.Name, "DB_NAME")
+	env.string(&cfg.Database.DSN, "DB_DSN")
+	env.uint(&cfg.Database.MaxPoolSize, "DB_MAX_POOL_SIZE")
+	env.uint(&cfg.Database.MinPoolSize, "DB_MIN_POOL_SIZE")
+	env.duration(&cfg.Database.MaxIdleTime, "DB_MAX_IDLE_TIME")
+	env.duration(&cfg.Database.ConnectTimeout, "DB_CONNECT_TIMEOUT")
+	env.int(&cfg.Database.ConnectRetries, "DB_CONNECT_RETRIES")
+	env.duration(&cfg.Database.RetryBackoff, "DB_RETRY_BACKOFF")
+	env.string(&cfg.Auth.SecretKey, "SECRET_KEY")
+	env.duration(&cfg.Auth.AccessTokenTTL, "ACCESS_TOKEN_TTL")
+	env.duration(&cfg.Auth.RefreshTokenTTL, "REFRESH_TOKEN_TTL")
+	env.duration(&cfg.Auth.RevocationCacheTTL, "REVOCATION_CACHE_TTL")
+	env.duration(&cfg.Auth.PasswordResetTTL, "PASSWORD_RESET_TTL")
+	env.string(&cfg.Auth.PasswordResetURL, "PASSWORD_RESET_URL")
+	env.string(&cfg.Search.Driver, "SEARCH_DRIVER")
+	env.string(&cfg.Media.Driver, "MEDIA_DRIVER")
+	env.string(&cfg.Media.Dir, "MEDIA_DIR")
+	env.int64(&cfg.Media.MaxSize, "MEDIA_MAX_SIZE")
+	env.string(&cfg.Media.CacheDir, "MEDIA_CACHE_DIR")
+	env.int64(&cfg.Media.CacheMaxSize, "MEDIA_CACHE_MAX_SIZE")
+	env.string(&cfg.Mail.Driver, "MAIL_DRIVER")
+	env.string(&cfg.Mail.From, "MAIL_FROM")
+	env.string(&cfg.Mail.Host, "SMTP_HOST")
+	env.int(&cfg.Mail.Port, "SMTP_PORT")
+	env.string(&cfg.Mail.Username, "SMTP_USERNAME")
+	env.string(&cfg.Mail.Password, "SMTP_PASSWORD")
+	env.bool(&cfg.Mail.Insecure, "SMTP_INSECURE")
+	env.string(&cfg.Mail.Dir, "MAIL_DIR")
+	env.duration(&cfg.Scheduler.Interval, "SCHEDULER_INTERVAL")
+	env.int(&cfg.Trash.RetentionDays, "TRASH_RETENTION_DAYS")
+	return env.err
+}
+
+// envReader copies environment variables into config fields, keeping the
+// first parse error.
+type envReader struct {
+	err error
+}
+
+func (r *envReader) lookup(key string) (string, bool) {
+	value, ok := os.LookupEnv(key)
+	return value, ok && value != ""
+}
+
+func (r *envReader) fail(key string, err error) {
+	if r.err == nil {
+		r.err = fmt.Errorf("invalid %s: %w", key, err)
+	}
+}
+
+func (r *envReader) string(dst *string, key string) {
+	if value, ok := r.lookup(key); ok {
+		*dst = value
+	}
+}
+
+func (r *envReader) int(dst *int, key string) {
+	if value, ok := r.lookup(key); ok {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			r.fail(key, err)
+			return
+		}
+		*dst = parsed
+	}
+}
+
+func (r *envReader) int64(dst *int64, key string) {
+	if value, ok := r.lookup(key); ok {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			r.fail(key, err)
+			return
+		}
+		*dst = parsed
+	}
+}
+
+func (r *envReader) uint(dst *uint64, key string) {
+	if value, ok := r.lookup(key); ok {
+		parsed, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			r.fail(key, err)
+			return
+		}
+		*dst = parsed
+	}
+}
+
+func (r *envReader) bool(dst *bool, key string) {
+	if value, ok := r.lookup(key); ok {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			r.fail(key, err)
+			return
+		}
+		*dst = parsed
+	}
+}
+
+func (r *envReader) duration(dst *Duration, key string) {
+	if value, ok := r.lookup(key); ok {
+		if err := dst.UnmarshalText([]byte(value)); err != nil {
+			r.fail(key, err)
+		}
+	}
+}
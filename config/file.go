@@ -0,0 +1,62 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+var defaultConfigFiles = []string{"config.yaml", "config.yml", "config.toml"}
+
+// loadFiles applies the config file at path, or the first default file found
+// in the working directory, followed by its profile variant, e.g.
+// config.prod.yaml next to config.yaml.
+func loadFiles(cfg *Config, path string, env string) error {
+	if path == "" {
+		for _, candidate := range defaultConfigFiles {
+			if _, err := os.Stat(candidate); err == nil {
+				path = candidate
+				break
+			}
+		}
+		if path == "" {
+			return nil
+		}
+	}
+
+	if err := loadFile(cfg, path); err != nil {
+		return err
+	}
+
+	ext := filepath.Ext(path)
+	profilePath := strings.TrimSuffix(path, ext) + "." + env + ext
+	if _, err := os.Stat(profilePath); err == nil {
+		return loadFile(cfg, profilePath)
+	}
+	return nil
+}
+
+func loadFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalStrict(data, cfg)
+	case ".toml":
+		_, err = toml.Decode(string(data), cfg)
+	default:
+		return fmt.Errorf("unsupported config file %q (want .yaml, .yml or .toml)", path)
+	}
+
+	if err != nil {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	return nil
+}
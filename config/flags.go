@@ -0,0 +1,64 @@
+package config
+
+import (
+	"flag"
+)
+
+type cliFlags struct {
+	env        string
+	configFile string
+	values     map[string]*string
+}
+
+// parseFlags reads the command line. Only flags that were given explicitly
+// override the other configuration sources.
+func parseFlags(args []string) (*cliFlags, error) {
+	set := flag.NewFlagSet("golang_cms", flag.ContinueOnError)
+
+	f := &cliFlags{values: map[string]*string{}}
+	set.StringVar(&f.env, "env", "", "configuration profile: dev, staging or prod")
+	set.StringVar(&f.configFile, "config", "", "path to a YAML or TOML config file")
+	f.values["port"] = set.String("port", "", "HTTP port")
+	f.values["mode"] = set.String("mode", "", "gin mode: debug, release or test")
+	f.values["tls-cert"] = set.String("tls-cert", "", "TLS certificate file")
+	f.values["tls-key"] = set.String("tls-key", "", "TLS private key file")
+	f.values["db-driver"] = set.String("db-driver", "", "storage backend: mongo, sqlite or postgres")
+	f.values["mongo-uri"] = set.String("mongo-uri", "", "MongoDB connection URI")
+	f.values["db-name"] = set.String("db-name", "", "MongoDB database name")
+	f.values["db-dsn"] = set.String("db-dsn", "", "SQL connection string")
+	f.values["secret-key"] = set.String("secret-key", "", "JWT signing secret")
+	f.values["search-driver"] = set.String("search-driver", "", "search index: mongo or memory")
+
+	if err := set.Parse(args); err != nil {
+		return nil, err
+	}
+
+	given := map[string]bool{}
+	set.Visit(func(fl *flag.Flag) {
+		given[fl.Name] = true
+	})
+	for name := range f.values {
+		if !given[name] {
+			delete(f.values, name)
+		}
+	}
+	return f, nil
+}
+
+func (f *cliFlags) apply(cfg *Config) {
+	targets := map[string]*string{
+		"port":          &cfg.Server.Port,
+		"mode":          &cfg.Server.Mode,
+		"tls-cert":      &cfg.Server.TLSCertFile,
+		"tls-key":       &cfg.Server.TLSKeyFile,
+		"db-driver":     &cfg.Database.Driver,
+		"mongo-uri":     &cfg.Database.MongoURI,
+		"db-name":       &cfg.Database.Name,
+		"db-dsn":        &cfg.Database.DSN,
+		"secret-key":    &cfg.Auth.SecretKey,
+		"search-driver": &cfg.Search.Driver,
+	}
+	for name, value := range f.values {
+		*targets[name] = *value
+	}
+}
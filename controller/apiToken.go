@@ -0,0 +1,162 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"golang_cms/apitoken"
+	"golang_cms/model"
+	"golang_cms/repository"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var validasiApiToken = validator.New()
+
+// ErrApiTokenRequest is returned when an API token is used to manage API
+// tokens, to change an account or to log out; a leaked token must not be
+// able to mint new ones or take the account over.
+var ErrApiTokenRequest = errors.New("this requires a login session, not an API token")
+
+// apiTokenFields are the fields the API tokens of a user can be filtered
+// and sorted by.
+var apiTokenFields = ListFields{
+	"name" : {Field: "name", Kind: StringField},
+	"prefix" : {Field: "prefix", Kind: StringField},
+	"expires_at" : {Field: "expires_at", Kind: TimeField},
+	"last_used_at" : {Field: "last_used_at", Kind: TimeField},
+	"created_at" : {Field: "created_at", Kind: TimeField},
+}
+
+// ApiTokenInput is the body of a new API token. Without ExpiresAt the token
+// lasts until it is deleted.
+type ApiTokenInput struct {
+	Name        string     `json:"name" validate:"required,max=64"`
+	Permissions []string   `json:"permissions" validate:"required,min=1"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+}
+
+// CreatedApiToken is a new API token together with the token itself, which
+// is only ever shown once.
+type CreatedApiToken struct {
+	model.ApiToken
+	Token string `json:"token"`
+}
+
+// ApiTokenController lets users manage their own API tokens.
+type ApiTokenController struct {
+	tokens  repository.ApiTokenRepository
+	service *apitoken.Service
+}
+
+func NewApiTokenController(tokens repository.ApiTokenRepository, service *apitoken.Service) *ApiTokenController {
+	return &ApiTokenController{tokens: tokens, service: service}
+}
+
+// session rejects requests made with an API token.
+func (tc *ApiTokenController) session(c *gin.Context) bool {
+	if c.GetString("api_token") != "" {
+		respondError(c, statusOf(ErrApiTokenRequest), ErrApiTokenRequest)
+		return false
+	}
+	return true
+}
+
+// GetApiTokens lists the API tokens of the user, newest first unless sorted
+// otherwise.
+func (tc *ApiTokenController) GetApiTokens(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	if !tc.session(c) {
+		return
+	}
+	lq, err := parseListQuery(c, apiTokenFields, []repository.Sort{{Field: "created_at", Desc: true}})
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	lq.Filter["user_id"] = c.GetString("uid")
+
+	listDocs(ctx, c, lq, tc.tokens.List)
+}
+
+// CreateApiToken issues an API token acting for the user with some of the
+// permissions of their role.
+func (tc *ApiTokenController) CreateApiToken(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	if !tc.session(c) {
+		return
+	}
+	var input ApiTokenInput
+	if err := c.ShouldBindJSON(&input)
+	err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if validationErr := validasiApiToken.Struct(&input)
+	validationErr != nil {
+		respondError(c, http.StatusBadRequest, validationErr)
+		return
+	}
+	if input.ExpiresAt != nil {
+		if !input.ExpiresAt.After(time.Now()) {
+			respondError(c, http.StatusBadRequest, fmt.Errorf("expires_at must be in the future"))
+			return
+		}
+		expiresAt := input.ExpiresAt.UTC().Truncate(time.Millisecond)
+		input.ExpiresAt = &expiresAt
+	}
+
+	token, raw, err := tc.service.Create(ctx, c.GetString("uid"), input.Name, input.Permissions, input.ExpiresAt)
+	if err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"Status" : 201,
+		"Message" : "Data created successfully! Store the token now, it is not shown again.",
+		"Data" : CreatedApiToken{ApiToken: token, Token: raw},
+	})
+}
+
+// DeleteApiToken revokes an API token of the user for good.
+func (tc *ApiTokenController) DeleteApiToken(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	if !tc.session(c) {
+		return
+	}
+	id, err := primitive.ObjectIDFromHex(c.Param("tokenid"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	token, err := tc.tokens.FindByID(ctx, id)
+	if err == nil && token.UserId != c.GetString("uid") {
+		err = repository.ErrNotFound
+	}
+	if err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+
+	if err := tc.tokens.Delete(ctx, id)
+	err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"Status" : 200,
+		"Message" : "Data deleted successfully!",
+	})
+}
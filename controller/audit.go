@@ -0,0 +1,45 @@
+package controller
+
+import (
+	"context"
+	"golang_cms/repository"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// auditFields are the fields the audit log can be filtered and sorted by;
+// created_at[gte] and created_at[lte] select a time range.
+var auditFields = ListFields{
+	"actor" : {Field: "actor_uid", Kind: StringField},
+	"actor_email" : {Field: "actor_email", Kind: StringField},
+	"resource_type" : {Field: "resource_type", Kind: StringField},
+	"resource_id" : {Field: "resource_id", Kind: StringField},
+	"action" : {Field: "action", Kind: StringField},
+	"ip" : {Field: "ip", Kind: StringField},
+	"created_at" : {Field: "created_at", Kind: TimeField},
+}
+
+// AuditController serves the audit log, which is only ever read here.
+type AuditController struct {
+	entries repository.AuditRepository
+}
+
+func NewAuditController(entries repository.AuditRepository) *AuditController {
+	return &AuditController{entries: entries}
+}
+
+// GetAuditLog lists audit entries, newest first unless sorted otherwise.
+func (ac *AuditController) GetAuditLog(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	lq, err := parseListQuery(c, auditFields, []repository.Sort{{Field: "created_at", Desc: true}})
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	listDocs(ctx, c, lq, ac.entries.List)
+}
@@ -0,0 +1,226 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"golang_cms/helper"
+	"golang_cms/model"
+	"golang_cms/passwordreset"
+	"golang_cms/repository"
+	"golang_cms/session"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RefreshInput is the body of a token refresh.
+type RefreshInput struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// LogoutInput is the optional body of a logout. Everywhere ends every
+// session of the user instead of only the current one.
+type LogoutInput struct {
+	Everywhere bool `json:"everywhere"`
+}
+
+// ForgotPasswordInput is the body of a password reset request.
+type ForgotPasswordInput struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// ResetPasswordInput is the body of a password reset, with the token from
+// the reset email.
+type ResetPasswordInput struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=8"`
+}
+
+// AuthController logs users in and out, keeps their sessions going and
+// resets forgotten passwords.
+type AuthController struct {
+	users    repository.UserRepository
+	sessions *session.Manager
+	resets   *passwordreset.Service
+}
+
+func NewAuthController(users repository.UserRepository, sessions *session.Manager, resets *passwordreset.Service) *AuthController {
+	return &AuthController{users: users, sessions: sessions, resets: resets}
+}
+
+// Login checks the email and password of a user and starts a session. The
+// user is returned with the access and refresh token of the session, which
+// are not stored with the user.
+func (ac *AuthController) Login(c *gin.Context) {
+	var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+	var user model.User
+	defer cancel()
+
+	if err := c.BindJSON(&user)
+	err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error" : err.Error()})
+		return
+	}
+
+	if user.Email == nil || user.Password == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error" : "Incorrect email or password!"})
+		return
+	}
+
+	foundUser, err := ac.users.FindByEmail(ctx, *user.Email)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error" : "Incorrect email or password!"})
+		return
+	}
+
+	passwordIsValid, message := VerifyPassword(*user.Password, *foundUser.Password)
+	if passwordIsValid != true {
+		c.JSON(http.StatusBadRequest, gin.H{"error" : message})
+		return
+	}
+
+	if foundUser.Email == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error" : "User not found!"})
+		return
+	}
+
+	tokens, err := ac.sessions.Start(ctx, foundUser)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error" : err.Error()})
+		return
+	}
+
+	foundUser.Token = &tokens.AccessToken
+	foundUser.Refresh_token = &tokens.RefreshToken
+	c.JSON(http.StatusOK, foundUser)
+}
+
+// Refresh exchanges a refresh token for a new access and refresh token.
+// Every refresh token works once; using one again ends its session.
+func (ac *AuthController) Refresh(c *gin.Context) {
+	var ctx, cancel = context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	var input RefreshInput
+	if err := c.ShouldBindJSON(&input)
+	err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error" : err.Error()})
+		return
+	}
+	if validationErr := validasiUser.Struct(&input)
+	validationErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error" : validationErr.Error()})
+		return
+	}
+
+	tokens, err := ac.sessions.Refresh(ctx, input.RefreshToken)
+	if errors.Is(err, session.ErrInvalidRefreshToken) || errors.Is(err, session.ErrRefreshTokenReused) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error" : err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error" : err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Logout revokes the access token of the request and ends its session, or
+// every session of the user with {"everywhere": true}. Refresh tokens of
+// the ended sessions stop working too.
+func (ac *AuthController) Logout(c *gin.Context) {
+	var ctx, cancel = context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	var input LogoutInput
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&input)
+		err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error" : err.Error()})
+			return
+		}
+	}
+
+	value, _ := c.Get("claims")
+	claims, ok := value.(*helper.SignedDetails)
+	if !ok {
+		//token API tidak punya sesi, token API dicabut dengan menghapusnya
+		c.JSON(http.StatusForbidden, gin.H{"error" : ErrApiTokenRequest.Error()})
+		return
+	}
+
+	if err := ac.sessions.Logout(ctx, claims, input.Everywhere)
+	err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error" : err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"Status" : 200,
+		"Message" : "Logged out successfully!",
+	})
+}
+
+// ForgotPassword emails a password reset token to the user with the given
+// email. The answer is the same whether or not the email is registered.
+func (ac *AuthController) ForgotPassword(c *gin.Context) {
+	var input ForgotPasswordInput
+	if err := c.ShouldBindJSON(&input)
+	err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error" : err.Error()})
+		return
+	}
+	if validationErr := validasiUser.Struct(&input)
+	validationErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error" : validationErr.Error()})
+		return
+	}
+
+	//email dikirim di belakang, supaya email terdaftar dan tidak terdaftar dijawab sama cepat
+	if err := ac.resets.RequestAsync(input.Email)
+	errors.Is(err, passwordreset.ErrTooManyRequests) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error" : err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"Status" : 200,
+		"Message" : "If the email is registered, a password reset link has been sent to it.",
+	})
+}
+
+// ResetPassword sets a new password with the token of a reset email. The
+// token works once, and every session of the user ends.
+func (ac *AuthController) ResetPassword(c *gin.Context) {
+	var ctx, cancel = context.WithTimeout(c, 30*time.Second)
+	defer cancel()
+
+	var input ResetPasswordInput
+	if err := c.ShouldBindJSON(&input)
+	err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error" : err.Error()})
+		return
+	}
+	if validationErr := validasiUser.Struct(&input)
+	validationErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error" : validationErr.Error()})
+		return
+	}
+
+	err := ac.resets.Reset(ctx, input.Token, HashPassword(input.Password))
+	if errors.Is(err, passwordreset.ErrInvalidToken) {
+		c.JSON(http.StatusBadRequest, gin.H{"error" : err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error" : err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"Status" : 200,
+		"Message" : "Password has been reset, please log in again.",
+	})
+}
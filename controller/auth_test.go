@@ -0,0 +1,45 @@
+package controller
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"golang_cms/mailer"
+	"golang_cms/middleware"
+	"golang_cms/passwordreset"
+	"golang_cms/ratelimit"
+	"golang_cms/repository"
+	"golang_cms/session"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestForgotPassword(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repos := repository.NewMemoryRepositories()
+	sessions := session.NewManager(repos.Refresh, repos.Revoked, repos.User, time.Hour, time.Minute)
+	resets := passwordreset.NewService(repos, sessions, mailer.LogMailer{}, time.Hour, "")
+	auth := NewAuthController(repos.User, sessions, resets)
+	router := gin.New()
+	router.POST("/auth/forgot-password", middleware.RateLimitByIP(ratelimit.New(5, time.Minute)), auth.ForgotPassword)
+
+	if code, _ := serve(t, router, "POST", "/auth/forgot-password", `{"email":"not an email"}`); code != http.StatusBadRequest {
+		t.Errorf("invalid email = %d", code)
+	}
+	for i := 0; i < passwordreset.RequestsPerEmail; i++ {
+		if code, out := serve(t, router, "POST", "/auth/forgot-password", `{"email":"nobody@example.com"}`); code != http.StatusOK {
+			t.Fatalf("request %d = %d %v", i, code, out)
+		}
+	}
+	if code, _ := serve(t, router, "POST", "/auth/forgot-password", `{"email":"nobody@example.com"}`); code != http.StatusTooManyRequests {
+		t.Errorf("request over the email limit = %d", code)
+	}
+
+	// five requests from the client so far, so the next is over its limit
+	req := newJSONRequest("POST", "/auth/forgot-password", `{"email":"other@example.com"}`)
+	w := serveRequest(router, req)
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Errorf("request over the IP limit = %d, Retry-After %q", w.Code, w.Header().Get("Retry-After"))
+	}
+}
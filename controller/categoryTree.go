@@ -0,0 +1,489 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"golang_cms/model"
+	"golang_cms/repository"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var validasiCategory = validator.New()
+
+// categoryFields are the fields the flat category list can be filtered and
+// sorted by. It is sorted in tree order unless asked otherwise.
+var categoryFields = ListFields{
+	"id" : {Field: "_id", Kind: ObjectIDField},
+	"name" : {Field: "name", Kind: StringField},
+	"slug" : {Field: "slug", Kind: StringField},
+	"parent_id" : {Field: "parent_id", Kind: ObjectIDField},
+	"depth" : {Field: "depth", Kind: IntField},
+	"order" : {Field: "order", Kind: IntField},
+	"status" : workflowFields["status"],
+	"publish_at" : workflowFields["publish_at"],
+	"unpublish_at" : workflowFields["unpublish_at"],
+}
+
+var categoryTreeOrder = []repository.Sort{{Field: "depth"}, {Field: "order"}, {Field: "name"}}
+
+// CategoryTreeController serves categories nested to any depth. Parent,
+// path and order are only changed through Create, Move and Reorder so the
+// tree stays consistent.
+type CategoryTreeController struct {
+	repo     repository.CategoryTreeRepository
+	products repository.ProductRepository
+}
+
+func NewCategoryTreeController(repo repository.CategoryTreeRepository, products repository.ProductRepository) *CategoryTreeController {
+	return &CategoryTreeController{repo: repo, products: products}
+}
+
+type moveCategoryInput struct {
+	ParentId *primitive.ObjectID `json:"parent_id"`
+	Position *int                `json:"position"`
+}
+
+type reorderCategoryInput struct {
+	ParentId *primitive.ObjectID  `json:"parent_id"`
+	Ids      []primitive.ObjectID `json:"ids" validate:"required"`
+}
+
+// category loads the category named by :categoryid.
+func (tc *CategoryTreeController) category(ctx context.Context, c *gin.Context) (model.Category, bool) {
+	objId, err := primitive.ObjectIDFromHex(c.Param("categoryid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"Status" : 400,
+			"Message" : "Invalid category id!",
+		})
+		return model.Category{}, false
+	}
+
+	category, err := tc.repo.FindByID(ctx, objId)
+	if err != nil {
+		respondError(c, statusOf(err), err)
+		return category, false
+	}
+	return category, true
+}
+
+func (tc *CategoryTreeController) bind(c *gin.Context, input interface{}) bool {
+	if err := c.ShouldBindJSON(input)
+	err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return false
+	}
+
+	if validationErr := validasiCategory.Struct(input)
+	validationErr != nil {
+		respondError(c, http.StatusBadRequest, validationErr)
+		return false
+	}
+	return true
+}
+
+func (tc *CategoryTreeController) CreateCategory(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	var input model.Category
+	if !tc.bind(c, &input) {
+		return
+	}
+	if err := settleWorkflow(&input.Workflow, nil, time.Now())
+	err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+	if err := mayPublish(c, "category", input.Workflow, nil)
+	err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+
+	newCategory, err := tc.repo.Create(ctx, model.Category{
+		Id: primitive.NewObjectID(),
+		Name: input.Name,
+		Slug: input.Slug,
+		ParentId: input.ParentId,
+		Workflow: input.Workflow,
+	})
+	if err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"Status" : 201,
+		"Message" : "Data created successfully!",
+		"Data" : newCategory,
+	})
+}
+
+func (tc *CategoryTreeController) GetCategory(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	category, ok := tc.category(ctx, c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"Status" : 200,
+		"Message" : "Data fetched successfully!",
+		"Data" : category,
+	})
+}
+
+// GetCategories returns categories as a flat, paginated list.
+func (tc *CategoryTreeController) GetCategories(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	lq, err := parseListQuery(c, categoryFields, categoryTreeOrder)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	listDocs(ctx, c, lq, tc.repo.List)
+}
+
+// GetTree returns every root category with its descendants nested.
+func (tc *CategoryTreeController) GetTree(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	categories, err := tc.repo.FindAll(ctx)
+	if err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"Status" : 200,
+		"Message" : "Data fetched successfully!",
+		"Data" : buildCategoryTree(categories, nil),
+	})
+}
+
+// GetSubtree returns the category in the URL with its descendants nested.
+func (tc *CategoryTreeController) GetSubtree(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	category, ok := tc.category(ctx, c)
+	if !ok {
+		return
+	}
+
+	descendants, err := tc.repo.FindDescendants(ctx, category)
+	if err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"Status" : 200,
+		"Message" : "Data fetched successfully!",
+		"Data" : &model.CategoryNode{Category: category, Children: buildCategoryTree(descendants, &category.Id)},
+	})
+}
+
+// GetBreadcrumb returns the chain of categories from the root down to and
+// including the category in the URL.
+func (tc *CategoryTreeController) GetBreadcrumb(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	category, ok := tc.category(ctx, c)
+	if !ok {
+		return
+	}
+
+	ancestors, err := tc.repo.FindAncestors(ctx, category)
+	if err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"Status" : 200,
+		"Message" : "Data fetched successfully!",
+		"Data" : append(ancestors, category),
+	})
+}
+
+// EditCategory changes the name, slug and publishing state; use
+// MoveCategory to re-parent.
+func (tc *CategoryTreeController) EditCategory(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	category, ok := tc.category(ctx, c)
+	if !ok {
+		return
+	}
+
+	var input model.Category
+	if !tc.bind(c, &input) {
+		return
+	}
+
+	if err := settleWorkflow(&input.Workflow, &category.Workflow, time.Now())
+	err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+	if err := mayPublish(c, "category", input.Workflow, &category.Workflow)
+	err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+
+	category.Name = input.Name
+	category.Slug = input.Slug
+	category.Workflow = input.Workflow
+
+	updatedCategory, err := tc.repo.Update(ctx, category)
+	if err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"Status" : 200,
+		"Message" : "Data updated successfully!",
+		"Data" : updatedCategory,
+	})
+}
+
+// MoveCategory puts the category under parent_id, or at the root when it
+// is null, at position among its new siblings (the end when omitted).
+func (tc *CategoryTreeController) MoveCategory(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	category, ok := tc.category(ctx, c)
+	if !ok {
+		return
+	}
+
+	var input moveCategoryInput
+	if !tc.bind(c, &input) {
+		return
+	}
+
+	position := -1
+	if input.Position != nil {
+		position = *input.Position
+	}
+
+	movedCategory, err := tc.repo.Move(ctx, category.Id, input.ParentId, position)
+	if err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"Status" : 200,
+		"Message" : "Data updated successfully!",
+		"Data" : movedCategory,
+	})
+}
+
+// ReorderCategories sets the order of the children of parent_id, or of the
+// root categories when it is null, to the order of ids.
+func (tc *CategoryTreeController) ReorderCategories(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	var input reorderCategoryInput
+	if !tc.bind(c, &input) {
+		return
+	}
+
+	siblings, err := tc.repo.Reorder(ctx, input.ParentId, input.Ids)
+	if err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"Status" : 200,
+		"Message" : "Data updated successfully!",
+		"Data" : siblings,
+	})
+}
+
+// DeleteCategory refuses to delete a category that still has children or
+// products filed under it.
+func (tc *CategoryTreeController) DeleteCategory(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	category, ok := tc.category(ctx, c)
+	if !ok {
+		return
+	}
+
+	count, err := tc.repo.CountChildren(ctx, category.Id)
+	if err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+	if count > 0 {
+		err := fmt.Errorf("%w: category still has %d child categories", ErrInUse, count)
+		respondError(c, statusOf(err), err)
+		return
+	}
+
+	count, err = tc.products.CountByCategory(ctx, category.Id)
+	if err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+	if count > 0 {
+		err := fmt.Errorf("%w: category still has %d products", ErrInUse, count)
+		respondError(c, statusOf(err), err)
+		return
+	}
+
+	if err := tc.repo.Delete(ctx, category.Id)
+	err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"Status" : 200,
+		"Message" : "Data deleted successfully!",
+	})
+}
+
+// SetCategoryStatus changes only the publishing state of the category in
+// the URL.
+func (tc *CategoryTreeController) SetCategoryStatus(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	category, ok := tc.category(ctx, c)
+	if !ok {
+		return
+	}
+
+	var input WorkflowInput
+	if !tc.bind(c, &input) {
+		return
+	}
+
+	current := category.Workflow
+	category.Workflow = model.Workflow{Status: input.Status, PublishAt: input.PublishAt, UnpublishAt: input.UnpublishAt}
+	if err := settleWorkflow(&category.Workflow, nil, time.Now())
+	err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+	if err := mayPublish(c, "category", category.Workflow, &current)
+	err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+
+	updatedCategory, err := tc.repo.Update(ctx, category)
+	if err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"Status" : 200,
+		"Message" : "Data updated successfully!",
+		"Data" : updatedCategory,
+	})
+}
+
+// GetPublicTree returns the tree of published categories. A category that
+// is not published hides its whole subtree, published or not.
+func (tc *CategoryTreeController) GetPublicTree(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	categories, _, err := tc.repo.List(ctx, repository.Query{Filter: repository.Filter{"status": model.StatusPublished}})
+	if err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"Status" : 200,
+		"Message" : "Data fetched successfully!",
+		"Data" : buildCategoryTree(categories, nil),
+	})
+}
+
+// GetPublicCategory returns the category in the URL when it and all of its
+// ancestors are published.
+func (tc *CategoryTreeController) GetPublicCategory(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	category, ok := tc.category(ctx, c)
+	if !ok {
+		return
+	}
+
+	ancestors, err := tc.repo.FindAncestors(ctx, category)
+	if err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+	for _, chain := range append(ancestors, category) {
+		if !isLive(chain.Workflow) {
+			respondError(c, http.StatusNotFound, repository.ErrNotFound)
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"Status" : 200,
+		"Message" : "Data fetched successfully!",
+		"Data" : category,
+	})
+}
+
+// buildCategoryTree nests categories under their parents, starting from
+// the children of root (the root categories when nil).
+func buildCategoryTree(categories []model.Category, root *primitive.ObjectID) []*model.CategoryNode {
+	repository.SortCategories(categories)
+
+	children := map[primitive.ObjectID][]*model.CategoryNode{}
+	var roots []*model.CategoryNode
+	nodes := make([]*model.CategoryNode, len(categories))
+	for i := range categories {
+		nodes[i] = &model.CategoryNode{Category: categories[i], Children: []*model.CategoryNode{}}
+	}
+	for _, node := range nodes {
+		if node.ParentId == nil || (root != nil && *node.ParentId == *root) {
+			roots = append(roots, node)
+			continue
+		}
+		children[*node.ParentId] = append(children[*node.ParentId], node)
+	}
+	for _, node := range nodes {
+		if nested, ok := children[node.Id]; ok {
+			node.Children = nested
+		}
+	}
+
+	if roots == nil {
+		roots = []*model.CategoryNode{}
+	}
+	return roots
+}
@@ -0,0 +1,117 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"golang_cms/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+func categoryRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	repos := repository.NewMemoryRepositories()
+	tree := NewCategoryTreeController(repos.Tree, repos.Product)
+	product := NewProductController(repos.Product, repos.Tree)
+	router := gin.New()
+	router.POST("/categories", tree.CreateCategory)
+	router.PUT("/categories/reorder", tree.ReorderCategories)
+	router.GET("/categories/:categoryid/tree", tree.GetSubtree)
+	router.GET("/categories/:categoryid/breadcrumb", tree.GetBreadcrumb)
+	router.PUT("/categories/:categoryid/move", tree.MoveCategory)
+	router.DELETE("/categories/:categoryid", tree.DeleteCategory)
+	router.POST("/product", product.CreateProduct)
+	router.PUT("/product/:productid", product.EditProduct)
+	router.GET("/products", product.GetProducts)
+	return router
+}
+
+// createCategory creates a category under parent ("" for the root) and
+// returns its id.
+func createCategory(t *testing.T, router http.Handler, name, parent string) string {
+	t.Helper()
+	body := fmt.Sprintf(`{"name":%q}`, name)
+	if parent != "" {
+		body = fmt.Sprintf(`{"name":%q,"parent_id":%q}`, name, parent)
+	}
+	code, out := serve(t, router, "POST", "/categories", body)
+	if code != http.StatusCreated {
+		t.Fatalf("create %s = %d %v", name, code, out)
+	}
+	return out["Data"].(map[string]interface{})["id"].(string)
+}
+
+// dataNames returns the names of the categories listed in the Data of out.
+func dataNames(out map[string]interface{}) []string {
+	var names []string
+	items, _ := out["Data"].([]interface{})
+	for _, item := range items {
+		names = append(names, item.(map[string]interface{})["name"].(string))
+	}
+	return names
+}
+
+func TestMoveCategory(t *testing.T) {
+	router := categoryRouter()
+	a := createCategory(t, router, "a", "")
+	b := createCategory(t, router, "b", "")
+	a1 := createCategory(t, router, "a1", a)
+
+	code, out := serve(t, router, "PUT", "/categories/"+a+"/move", `{"parent_id":"`+a1+`"}`)
+	if code != http.StatusBadRequest {
+		t.Errorf("move under a descendant = %d %v", code, out)
+	}
+	code, out = serve(t, router, "PUT", "/categories/"+a1+"/move", `{"parent_id":"`+b+`","position":0}`)
+	if code != http.StatusOK {
+		t.Fatalf("move a1 under b = %d %v", code, out)
+	}
+	if depth := out["Data"].(map[string]interface{})["depth"]; depth != 1.0 {
+		t.Errorf("moved depth = %v, want 1", depth)
+	}
+
+	_, out = serve(t, router, "GET", "/categories/"+a1+"/breadcrumb", "")
+	if got := fmt.Sprint(dataNames(out)); got != "[b a1]" {
+		t.Errorf("breadcrumb after move = %s, want [b a1]", got)
+	}
+	_, out = serve(t, router, "GET", "/categories/"+b+"/tree", "")
+	children := out["Data"].(map[string]interface{})["children"].([]interface{})
+	if len(children) != 1 || children[0].(map[string]interface{})["name"] != "a1" {
+		t.Errorf("subtree of b = %v", out["Data"])
+	}
+}
+
+func TestReorderCategories(t *testing.T) {
+	router := categoryRouter()
+	a := createCategory(t, router, "a", "")
+	b := createCategory(t, router, "b", "")
+
+	code, out := serve(t, router, "PUT", "/categories/reorder", `{"parent_id":null,"ids":["`+b+`","`+a+`"]}`)
+	if code != http.StatusOK || fmt.Sprint(dataNames(out)) != "[b a]" {
+		t.Errorf("reorder = %d %v", code, out)
+	}
+	code, out = serve(t, router, "PUT", "/categories/reorder", `{"parent_id":null,"ids":["`+b+`"]}`)
+	if code != http.StatusBadRequest {
+		t.Errorf("reorder missing a sibling = %d %v", code, out)
+	}
+}
+
+func TestDeleteCategoryWithChildren(t *testing.T) {
+	router := categoryRouter()
+	a := createCategory(t, router, "a", "")
+	a1 := createCategory(t, router, "a1", a)
+
+	if code, out := serve(t, router, "DELETE", "/categories/"+a, ""); code != http.StatusConflict {
+		t.Errorf("delete with children = %d %v", code, out)
+	}
+	if code, out := serve(t, router, "DELETE", "/categories/"+a1, ""); code != http.StatusOK {
+		t.Errorf("delete leaf = %d %v", code, out)
+	}
+	if code, out := serve(t, router, "DELETE", "/categories/"+a, ""); code != http.StatusOK {
+		t.Errorf("delete once empty = %d %v", code, out)
+	}
+	if code, _ := serve(t, router, "DELETE", "/categories/nope", ""); code != http.StatusBadRequest {
+		t.Errorf("delete with a bad id = %d", code)
+	}
+}
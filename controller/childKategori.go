@@ -0,0 +1,366 @@
+package controller
+
+import (
+	"context"
+	"golang_cms/model"
+	"golang_cms/repository"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var validasiChildKategori = validator.New()
+
+// childKategoriFields are the fields a list of children can be filtered and
+// sorted by; the parent always comes from the URL.
+var childKategoriFields = ListFields{
+	"id" : {Field: "_id", Kind: ObjectIDField},
+	"nama_produk" : {Field: "nama_produk", Kind: StringField},
+	"image" : {Field: "image", Kind: StringField},
+	"media_id" : {Field: "media_id", Kind: ObjectIDField},
+	"status" : workflowFields["status"],
+	"publish_at" : workflowFields["publish_at"],
+	"unpublish_at" : workflowFields["unpublish_at"],
+}
+
+// ChildKategoriController serves child categories nested under the main
+// category in the URL; a child is only reachable through its own parent.
+type ChildKategoriController struct {
+	parents  repository.CategoryRepository
+	children repository.ChildCategoryRepository
+	media    repository.MediaRepository
+}
+
+func NewChildKategoriController(parents repository.CategoryRepository, children repository.ChildCategoryRepository, media repository.MediaRepository) *ChildKategoriController {
+	return &ChildKategoriController{parents: parents, children: children, media: media}
+}
+
+// parent loads the main category named by :kategoriid, which must not be
+// in the trash.
+func (cc *ChildKategoriController) parent(ctx context.Context, c *gin.Context) (model.MainCategory, bool) {
+	parentId, err := primitive.ObjectIDFromHex(c.Param("kategoriid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"Status" : 400,
+			"Message" : "Invalid category id!",
+		})
+		return model.MainCategory{}, false
+	}
+
+	parent, err := cc.parents.FindByID(ctx, parentId)
+	if err == nil && isTrashed(&parent) {
+		err = repository.ErrNotFound
+	}
+	if err != nil {
+		respondError(c, statusOf(err), err)
+		return parent, false
+	}
+	return parent, true
+}
+
+// child loads the child named by :childid, which must belong to parent.
+func (cc *ChildKategoriController) child(ctx context.Context, c *gin.Context, parent model.MainCategory) (model.ChildCategory, bool) {
+	childId, err := primitive.ObjectIDFromHex(c.Param("childid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"Status" : 400,
+			"Message" : "Invalid child category id!",
+		})
+		return model.ChildCategory{}, false
+	}
+
+	child, err := cc.children.FindByID(ctx, childId)
+	if err == nil && child.IdMainCategory != parent.Id {
+		err = repository.ErrNotFound
+	}
+	if err != nil {
+		respondError(c, statusOf(err), err)
+		return child, false
+	}
+	return child, true
+}
+
+// bind reads and validates the request body. When it names a media, Image
+// is set to the url of that media.
+func (cc *ChildKategoriController) bind(ctx context.Context, c *gin.Context) (model.ChildCategory, bool) {
+	var input model.ChildCategory
+	if err := c.ShouldBind(&input)
+	err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return input, false
+	}
+
+	if validationErr := validasiChildKategori.Struct(&input)
+	validationErr != nil {
+		respondError(c, http.StatusBadRequest, validationErr)
+		return input, false
+	}
+
+	if input.MediaId != nil {
+		url, err := mediaURL(ctx, cc.media, *input.MediaId)
+		if err != nil {
+			respondError(c, statusOf(err), err)
+			return input, false
+		}
+		input.Image = url
+	}
+	return input, true
+}
+
+func (cc *ChildKategoriController) CreateChild(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	parent, ok := cc.parent(ctx, c)
+	if !ok {
+		return
+	}
+
+	input, ok := cc.bind(ctx, c)
+	if !ok {
+		return
+	}
+	if err := settleWorkflow(&input.Workflow, nil, time.Now())
+	err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+	if err := mayPublish(c, "kategori", input.Workflow, nil)
+	err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+
+	newChild := model.ChildCategory{
+		Id: primitive.NewObjectID(),
+		IdMainCategory: parent.Id,
+		Nama_produk: input.Nama_produk,
+		Image: input.Image,
+		MediaId: input.MediaId,
+		Workflow: input.Workflow,
+	}
+
+	if _, err := cc.children.Create(ctx, newChild)
+	err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"Status" : 201,
+		"Message" : "Data created successfully!",
+		"Data" : gin.H{"InsertedID" : newChild.Id},
+	})
+}
+
+func (cc *ChildKategoriController) GetChildren(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	parent, ok := cc.parent(ctx, c)
+	if !ok {
+		return
+	}
+
+	lq, err := parseListQuery(c, childKategoriFields, nil)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	lq.Filter["idmaincategory"] = parent.Id
+
+	listDocs(ctx, c, lq, cc.children.List)
+}
+
+func (cc *ChildKategoriController) GetChild(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	parent, ok := cc.parent(ctx, c)
+	if !ok {
+		return
+	}
+
+	child, ok := cc.child(ctx, c, parent)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"Status" : 200,
+		"Message" : "Data fetched successfully!",
+		"Data" : child,
+	})
+}
+
+func (cc *ChildKategoriController) EditChild(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	parent, ok := cc.parent(ctx, c)
+	if !ok {
+		return
+	}
+
+	child, ok := cc.child(ctx, c, parent)
+	if !ok {
+		return
+	}
+
+	input, ok := cc.bind(ctx, c)
+	if !ok {
+		return
+	}
+	if err := settleWorkflow(&input.Workflow, &child.Workflow, time.Now())
+	err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+	if err := mayPublish(c, "kategori", input.Workflow, &child.Workflow)
+	err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+
+	child.Nama_produk = input.Nama_produk
+	child.Image = input.Image
+	child.MediaId = input.MediaId
+	child.Workflow = input.Workflow
+
+	updatedChild, err := cc.children.Update(ctx, child)
+	if err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"Status" : 200,
+		"Message" : "Data updated successfully!",
+		"Data" : updatedChild,
+	})
+}
+
+func (cc *ChildKategoriController) DeleteChild(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	parent, ok := cc.parent(ctx, c)
+	if !ok {
+		return
+	}
+
+	child, ok := cc.child(ctx, c, parent)
+	if !ok {
+		return
+	}
+
+	if err := cc.children.Delete(ctx, child.Id)
+	err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"Status" : 200,
+		"Message" : "Data deleted successfully!",
+	})
+}
+
+// SetChildStatus changes only the publishing state of the child in the URL.
+func (cc *ChildKategoriController) SetChildStatus(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	parent, ok := cc.parent(ctx, c)
+	if !ok {
+		return
+	}
+
+	child, ok := cc.child(ctx, c, parent)
+	if !ok {
+		return
+	}
+
+	var input WorkflowInput
+	if err := c.ShouldBindJSON(&input)
+	err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if validationErr := validasiChildKategori.Struct(&input)
+	validationErr != nil {
+		respondError(c, http.StatusBadRequest, validationErr)
+		return
+	}
+
+	current := child.Workflow
+	child.Workflow = model.Workflow{Status: input.Status, PublishAt: input.PublishAt, UnpublishAt: input.UnpublishAt}
+	if err := settleWorkflow(&child.Workflow, nil, time.Now())
+	err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+	if err := mayPublish(c, "kategori", child.Workflow, &current)
+	err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+
+	updatedChild, err := cc.children.Update(ctx, child)
+	if err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"Status" : 200,
+		"Message" : "Data updated successfully!",
+		"Data" : updatedChild,
+	})
+}
+
+// GetKategoriWithChildren returns a main category with its children embedded.
+func (cc *ChildKategoriController) GetKategoriWithChildren(c *gin.Context) {
+	cc.kategoriWithChildren(c, false)
+}
+
+// GetPublicKategoriWithChildren is GetKategoriWithChildren for published
+// main categories, with their published children only.
+func (cc *ChildKategoriController) GetPublicKategoriWithChildren(c *gin.Context) {
+	cc.kategoriWithChildren(c, true)
+}
+
+func (cc *ChildKategoriController) kategoriWithChildren(c *gin.Context, public bool) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	parent, ok := cc.parent(ctx, c)
+	if !ok {
+		return
+	}
+	if public && !isLive(parent.Workflow) {
+		respondError(c, http.StatusNotFound, repository.ErrNotFound)
+		return
+	}
+
+	children, err := cc.children.FindByParent(ctx, parent.Id)
+	if err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+	shown := []model.ChildCategory{}
+	for _, child := range children {
+		if !public || isLive(child.Workflow) {
+			shown = append(shown, child)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"Status" : 200,
+		"Message" : "Data fetched successfully!",
+		"Data" : model.MainCategoryWithChildren{MainCategory: parent, Children: shown},
+	})
+}
@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"net/http"
+	"testing"
+
+	"golang_cms/rbac"
+	"golang_cms/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+func childRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	repos := repository.NewMemoryRepositories()
+	kategori := NewKategoriResource(repos.Category, repos.Child)
+	child := NewChildKategoriController(repos.Category, repos.Child, repos.Media)
+	router := gin.New()
+	kategori.Register(router, "/kategori", "/kategori", builtinGuard)
+	router.POST("/kategori/:kategoriid/children", builtinGuard("kategori:write"), child.CreateChild)
+	router.PUT("/kategori/:kategoriid/children/:childid", builtinGuard("kategori:write"), child.EditChild)
+	router.PUT("/kategori/:kategoriid/children/:childid/status", builtinGuard("kategori:write"), child.SetChildStatus)
+	router.GET("/kategori/:kategoriid/with-children", builtinGuard("kategori:read"), child.GetKategoriWithChildren)
+	router.GET("/public/kategori/:kategoriid/with-children", child.GetPublicKategoriWithChildren)
+	return router
+}
+
+// childNames returns the nama_produk of the children in the Data of out.
+func childNames(out map[string]interface{}) []string {
+	names := []string{}
+	data, _ := out["Data"].(map[string]interface{})
+	children, _ := data["children"].([]interface{})
+	for _, child := range children {
+		names = append(names, child.(map[string]interface{})["nama_produk"].(string))
+	}
+	return names
+}
+
+func TestChildWorkflow(t *testing.T) {
+	router := childRouter(t)
+	code, out := serveRole(t, router, rbac.RoleAdmin, "POST", "/kategori", `{"kategori_produk":"a","nama_produk":"a","status":"published"}`)
+	if code != http.StatusCreated {
+		t.Fatalf("create kategori = %d %v", code, out)
+	}
+	parent := "/kategori/" + out["Data"].(map[string]interface{})["InsertedID"].(string)
+
+	create := func(role, body string) (int, string) {
+		code, out := serveRole(t, router, role, "POST", parent+"/children", body)
+		if code != http.StatusCreated {
+			return code, ""
+		}
+		return code, out["Data"].(map[string]interface{})["InsertedID"].(string)
+	}
+	if code, _ := create(rbac.RoleAdmin, `{"nama_produk":"live","image":"/a.png","status":"published"}`); code != http.StatusCreated {
+		t.Fatalf("create published child = %d", code)
+	}
+	_, draft := create(rbac.RoleAuthor, `{"nama_produk":"draft","image":"/b.png"}`)
+	if code, _ := create(rbac.RoleAuthor, `{"nama_produk":"x","image":"/c.png","status":"published"}`); code != http.StatusForbidden {
+		t.Errorf("author publishing a child = %d", code)
+	}
+
+	_, out = serveRole(t, router, rbac.RoleAuthor, "GET", parent+"/with-children", "")
+	if got := childNames(out); len(got) != 2 {
+		t.Errorf("admin children = %v, want both", got)
+	}
+	_, out = serveRole(t, router, "", "GET", "/public"+parent+"/with-children", "")
+	if got := childNames(out); len(got) != 1 || got[0] != "live" {
+		t.Errorf("public children = %v, want [live]", got)
+	}
+
+	if code, out := serveRole(t, router, rbac.RoleAuthor, "PUT", parent+"/children/"+draft+"/status", `{"status":"published"}`); code != http.StatusForbidden {
+		t.Errorf("author publishing through status = %d %v", code, out)
+	}
+	if code, out := serveRole(t, router, rbac.RoleAuthor, "PUT", parent+"/children/"+draft, `{"nama_produk":"draft","image":"/b.png","status":"published"}`); code != http.StatusForbidden {
+		t.Errorf("author publishing through an edit = %d %v", code, out)
+	}
+	if code, out := serveRole(t, router, rbac.RoleEditor, "PUT", parent+"/children/"+draft+"/status", `{"status":"published"}`); code != http.StatusOK {
+		t.Fatalf("editor publishing = %d %v", code, out)
+	}
+	_, out = serveRole(t, router, "", "GET", "/public"+parent+"/with-children", "")
+	if got := childNames(out); len(got) != 2 {
+		t.Errorf("public children after publishing = %v, want both", got)
+	}
+}
@@ -0,0 +1,53 @@
+package controller
+
+import (
+	"errors"
+	"golang_cms/apitoken"
+	"golang_cms/rbac"
+	"golang_cms/repository"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrInUse is returned when a document cannot be deleted because other
+// documents still refer to it.
+var ErrInUse = errors.New("data is still in use")
+
+// ErrUnknownMedia is returned when a document refers to a media that does
+// not exist.
+var ErrUnknownMedia = errors.New("media does not exist")
+
+// statusOf maps repository errors onto the HTTP status returned to clients.
+func statusOf(err error) int {
+	if errors.Is(err, repository.ErrNotFound) {
+		return http.StatusNotFound
+	}
+	if errors.Is(err, ErrPublishDenied) || errors.Is(err, ErrApiTokenRequest) || errors.Is(err, apitoken.ErrNotGranted) || errors.Is(err, ErrNotHeld) || errors.Is(err, ErrAdminOnly) {
+		return http.StatusForbidden
+	}
+	if errors.Is(err, repository.ErrDuplicate) || errors.Is(err, ErrInUse) || errors.Is(err, ErrNotTrashed) || errors.Is(err, rbac.ErrBuiltinRole) || errors.Is(err, ErrLastAdmin) {
+		return http.StatusConflict
+	}
+	if errors.Is(err, repository.ErrInvalidMove) || errors.Is(err, repository.ErrInvalidOrder) || errors.Is(err, ErrUnknownMedia) || errors.Is(err, ErrInvalidSchedule) || errors.Is(err, rbac.ErrUnknownRole) || errors.Is(err, rbac.ErrUnknownPermission) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// allowed reports whether the user of the request was granted permission.
+// The permissions are loaded by middleware.RequirePermission; a request
+// that did not go through it has none.
+func allowed(c *gin.Context, permission string) bool {
+	granted, _ := c.Get("permissions")
+	permissions, _ := granted.([]string)
+	return rbac.Allows(permissions, permission)
+}
+
+// respondError writes the standard error body used by the content endpoints.
+func respondError(c *gin.Context, status int, err error) {
+	c.JSON(status, gin.H{
+		"Status" : status,
+		"Message" : err.Error(),
+	})
+}
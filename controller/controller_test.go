@@ -0,0 +1,40 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// allowAll is a guard that grants every permission.
+func allowAll(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("uid", "editor")
+		c.Set("permissions", []string{"*"})
+		c.Next()
+	}
+}
+
+func newJSONRequest(method, path, body string) *http.Request {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+func serveRequest(router http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func serve(t *testing.T, router http.Handler, method, path, body string) (int, map[string]interface{}) {
+	t.Helper()
+	w := serveRequest(router, newJSONRequest(method, path, body))
+	var out map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &out)
+	return w.Code, out
+}
@@ -0,0 +1,97 @@
+package controller
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"golang_cms/helper"
+)
+
+// titles returns the titles of the descs of a list response.
+func titles(out map[string]interface{}) []string {
+	var titles []string
+	for _, doc := range out["Data"].([]interface{}) {
+		titles = append(titles, doc.(map[string]interface{})["title"].(string))
+	}
+	return titles
+}
+
+func cursorOf(out map[string]interface{}, name string) string {
+	cursor, _ := out["Meta"].(map[string]interface{})[name].(string)
+	return cursor
+}
+
+func TestListCursors(t *testing.T) {
+	helper.SetSecretKey("cursor-test-key")
+	router := descRouter(t, 5)
+
+	var seen []string
+	code, out := serve(t, router, "GET", "/descs?limit=2&sort=id", "")
+	for pages := 0; ; pages++ {
+		if code != http.StatusOK || pages > 5 {
+			t.Fatalf("page %d = %d %v", pages, code, out)
+		}
+		seen = append(seen, titles(out)...)
+		next := cursorOf(out, "next_cursor")
+		if next == "" {
+			break
+		}
+		code, out = serve(t, router, "GET", "/descs?limit=2&sort=id&cursor="+url.QueryEscape(next), "")
+	}
+	want := []string{"title 00", "title 01", "title 02", "title 03", "title 04"}
+	if len(seen) != len(want) {
+		t.Fatalf("paged through %v, want %v", seen, want)
+	}
+	for i := range want {
+		if seen[i] != want[i] {
+			t.Fatalf("paged through %v, want %v", seen, want)
+		}
+	}
+
+	// back from the last page
+	prev := cursorOf(out, "prev_cursor")
+	code, out = serve(t, router, "GET", "/descs?limit=2&sort=id&cursor="+url.QueryEscape(prev), "")
+	if got := titles(out); code != http.StatusOK || len(got) != 2 || got[0] != "title 02" || got[1] != "title 03" {
+		t.Errorf("previous page = %d %v", code, got)
+	}
+	if cursorOf(out, "next_cursor") == "" || cursorOf(out, "prev_cursor") == "" {
+		t.Errorf("middle page misses a cursor: %v", out["Meta"])
+	}
+}
+
+func TestListCursorRejected(t *testing.T) {
+	helper.SetSecretKey("cursor-test-key")
+	router := descRouter(t, 3)
+	_, out := serve(t, router, "GET", "/descs?limit=1&sort=id", "")
+	next := url.QueryEscape(cursorOf(out, "next_cursor"))
+	if next == "" {
+		t.Fatal("no next cursor")
+	}
+
+	tests := map[string]string{
+		"tampered":     "/descs?limit=1&sort=id&cursor=x" + next,
+		"other filter": "/descs?limit=1&sort=id&title=x&cursor=" + next,
+		"other order":  "/descs?limit=1&sort=-id&cursor=" + next,
+		"with a page":  "/descs?limit=1&sort=id&page=2&cursor=" + next,
+	}
+	for name, path := range tests {
+		if code, out := serve(t, router, "GET", path, ""); code != http.StatusBadRequest {
+			t.Errorf("%s: %d %v", name, code, out)
+		}
+	}
+
+	// a different limit keeps the cursor valid
+	if code, out := serve(t, router, "GET", "/descs?limit=2&sort=id&cursor="+next, ""); code != http.StatusOK || len(titles(out)) != 2 {
+		t.Errorf("cursor with a new limit = %d %v", code, out)
+	}
+}
+
+func TestListWithoutCursorOrder(t *testing.T) {
+	helper.SetSecretKey("cursor-test-key")
+	router := descRouter(t, 3)
+	code, out := serve(t, router, "GET", "/descs?limit=1&sort=title", "")
+	if code != http.StatusOK || cursorOf(out, "next_cursor") != "" {
+		t.Errorf("title order = %d, next cursor %q", code, cursorOf(out, "next_cursor"))
+	}
+}
@@ -0,0 +1,36 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthController struct {
+	ready func(ctx context.Context) error
+}
+
+// NewHealthController returns the liveness and readiness handlers. ready
+// reports why the service cannot take traffic, or nil when it can.
+func NewHealthController(ready func(ctx context.Context) error) *HealthController {
+	return &HealthController{ready: ready}
+}
+
+func (hc *HealthController) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status" : "ok"})
+}
+
+func (hc *HealthController) Readiness(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+	defer cancel()
+
+	if err := hc.ready(ctx)
+	err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status" : "unavailable", "error" : err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status" : "ok"})
+}
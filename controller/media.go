@@ -0,0 +1,403 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"golang_cms/media"
+	"golang_cms/model"
+	"golang_cms/repository"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// multipartOverhead is how much a multipart body may exceed the file size
+// limit to make room for boundaries and headers.
+const multipartOverhead = 1 << 20
+
+// mediaFields are the fields the media list can be filtered and sorted by.
+var mediaFields = ListFields{
+	"id" : {Field: "_id", Kind: ObjectIDField},
+	"filename" : {Field: "filename", Kind: StringField},
+	"content_type" : {Field: "content_type", Kind: StringField},
+	"size" : {Field: "size", Kind: IntField},
+	"width" : {Field: "width", Kind: IntField},
+	"height" : {Field: "height", Kind: IntField},
+	"checksum" : {Field: "checksum", Kind: StringField},
+	"uploaded_by" : {Field: "uploaded_by", Kind: StringField},
+	"created_at" : {Field: "created_at", Kind: TimeField},
+}
+
+// MediaController serves the media library: uploads, their metadata and
+// their content. Banners and child categories refer to media by id.
+type MediaController struct {
+	media    repository.MediaRepository
+	banners  repository.BannerRepository
+	children repository.ChildCategoryRepository
+	library  *media.Library
+}
+
+func NewMediaController(mediaRepo repository.MediaRepository, banners repository.BannerRepository, children repository.ChildCategoryRepository, library *media.Library) *MediaController {
+	return &MediaController{media: mediaRepo, banners: banners, children: children, library: library}
+}
+
+// mediaURL checks that the media exists and returns the url its content is
+// served at.
+func mediaURL(ctx context.Context, repo repository.MediaRepository, id primitive.ObjectID) (string, error) {
+	if _, err := repo.FindByID(ctx, id)
+	err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return "", fmt.Errorf("%w: %s", ErrUnknownMedia, id.Hex())
+		}
+		return "", err
+	}
+	return "/media/" + id.Hex(), nil
+}
+
+func (mc *MediaController) find(ctx context.Context, c *gin.Context) (model.Media, bool) {
+	objId, err := primitive.ObjectIDFromHex(c.Param("mediaid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"Status" : 400,
+			"Message" : "Invalid media id!",
+		})
+		return model.Media{}, false
+	}
+
+	doc, err := mc.media.FindByID(ctx, objId)
+	if err != nil {
+		respondError(c, statusOf(err), err)
+		return doc, false
+	}
+	return doc, true
+}
+
+// Upload stores the "file" field of a multipart form. The content type is
+// sniffed from the content; uploading a file that is already in the library
+// returns the existing media instead of storing it twice.
+func (mc *MediaController) Upload(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 30*time.Second)
+	defer cancel()
+
+	maxSize := mc.library.Limits.MaxSize
+	if c.Request.ContentLength > maxSize+multipartOverhead {
+		respondError(c, http.StatusRequestEntityTooLarge, media.ErrTooLarge)
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+multipartOverhead)
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxSize+1))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	data, info, err := mc.library.Prepare(data)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, media.ErrTooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		} else if errors.Is(err, media.ErrUnsupportedType) {
+			status = http.StatusUnsupportedMediaType
+		}
+		respondError(c, status, err)
+		return
+	}
+
+	existing, err := mc.media.FindByChecksum(ctx, info.Checksum)
+	if err == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"Status" : 200,
+			"Message" : "File already uploaded",
+			"Data" : existing,
+		})
+		return
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		respondError(c, statusOf(err), err)
+		return
+	}
+
+	id := primitive.NewObjectID()
+	doc := model.Media{
+		Id: id,
+		Filename: filepath.Base(header.Filename),
+		ContentType: info.ContentType,
+		Size: int64(len(data)),
+		Width: info.Width,
+		Height: info.Height,
+		Checksum: info.Checksum,
+		StorageKey: id.Hex(),
+		UploadedBy: c.GetString("uid"),
+		CreatedAt: time.Now().UTC().Truncate(time.Millisecond),
+	}
+
+	if err := mc.library.Storage.Save(ctx, doc.StorageKey, bytes.NewReader(data))
+	err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	if media.IsImage(doc.ContentType) {
+		variants, err := mc.library.SaveVariants(ctx, doc.StorageKey, data)
+		if err != nil {
+			mc.library.Storage.Delete(ctx, doc.StorageKey)
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+		for _, variant := range variants {
+			doc.Variants = append(doc.Variants, model.MediaVariant{
+				Name: variant.Name,
+				Url: "/media/" + id.Hex() + "?variant=" + url.QueryEscape(variant.Name),
+				ContentType: variant.ContentType,
+				Width: variant.Width,
+				Height: variant.Height,
+				Size: variant.Size,
+			})
+		}
+	}
+
+	created, err := mc.media.Create(ctx, doc)
+	if err != nil {
+		mc.removeFiles(ctx, doc)
+		respondError(c, statusOf(err), err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"Status" : 201,
+		"Message" : "Data created successfully!",
+		"Data" : created,
+	})
+}
+
+// Serve writes the content of a media, of one of its variants with
+// ?variant=name, or of the image resized with ?w=&h=&fit=&format=. Content
+// never changes under an id, so it may be cached forever and revalidated
+// by its checksum.
+func (mc *MediaController) Serve(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 30*time.Second)
+	defer cancel()
+
+	doc, ok := mc.find(ctx, c)
+	if !ok {
+		return
+	}
+
+	if name := c.Query("variant")
+	name != "" {
+		mc.serveVariant(ctx, c, doc, name)
+		return
+	}
+	if c.Query("w") != "" || c.Query("h") != "" || c.Query("fit") != "" || c.Query("format") != "" {
+		mc.serveResized(c, doc)
+		return
+	}
+
+	if notModified(c, doc.Checksum) {
+		return
+	}
+	mc.serveStored(ctx, c, doc, doc.StorageKey, doc.Size, doc.ContentType)
+}
+
+func (mc *MediaController) serveVariant(ctx context.Context, c *gin.Context, doc model.Media, name string) {
+	for _, variant := range doc.Variants {
+		if variant.Name == name {
+			if notModified(c, doc.Checksum+"-"+name) {
+				return
+			}
+			mc.serveStored(ctx, c, doc, doc.StorageKey+"_"+name, variant.Size, variant.ContentType)
+			return
+		}
+	}
+	respondError(c, http.StatusNotFound, fmt.Errorf("media has no variant %q", name))
+}
+
+func (mc *MediaController) serveResized(c *gin.Context, doc model.Media) {
+	t := media.Transform{Fit: media.FitContain, Format: c.Query("format")}
+	if fit := c.Query("fit")
+	fit != "" {
+		t.Fit = media.Fit(fit)
+	}
+	for param, dst := range map[string]*int{"w" : &t.Width, "h" : &t.Height} {
+		if value := c.Query(param)
+		value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				respondError(c, http.StatusBadRequest, fmt.Errorf("invalid %s %q", param, value))
+				return
+			}
+			*dst = n
+		}
+	}
+	if err := t.Validate(mc.library.Images.MaxDimension)
+	err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if !media.IsImage(doc.ContentType) {
+		respondError(c, http.StatusBadRequest, media.ErrNotImage)
+		return
+	}
+	t = mc.library.Round(t)
+
+	if notModified(c, fmt.Sprintf("%s-%dx%d-%s-%s", doc.Checksum, t.Width, t.Height, t.Fit, t.Format)) {
+		return
+	}
+
+	rendered, err := mc.library.Resize(doc.StorageKey, t, func() ([]byte, error) {
+		ctx, cancel := context.WithTimeout(c, 30*time.Second)
+		defer cancel()
+
+		content, err := mc.library.Storage.Open(ctx, doc.StorageKey)
+		if err != nil {
+			return nil, err
+		}
+		defer content.Close()
+		return io.ReadAll(content)
+	})
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Data(http.StatusOK, rendered.ContentType, rendered.Data)
+}
+
+// notModified sets the caching headers for content identified by tag and
+// answers 304 when the client already has it.
+func notModified(c *gin.Context, tag string) bool {
+	etag := `"` + tag + `"`
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return true
+	}
+	return false
+}
+
+func (mc *MediaController) serveStored(ctx context.Context, c *gin.Context, doc model.Media, key string, size int64, contentType string) {
+	content, err := mc.library.Storage.Open(ctx, key)
+	if err != nil {
+		if errors.Is(err, media.ErrNotExist) {
+			respondError(c, http.StatusNotFound, err)
+			return
+		}
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	defer content.Close()
+
+	c.DataFromReader(http.StatusOK, size, contentType, content, map[string]string{
+		"X-Content-Type-Options" : "nosniff",
+		"Content-Disposition" : "inline; filename=" + strconv.Quote(doc.Filename),
+	})
+}
+
+// removeFiles deletes the stored files of a media: the upload, its variants
+// and its cached renderings.
+func (mc *MediaController) removeFiles(ctx context.Context, doc model.Media) error {
+	for _, variant := range doc.Variants {
+		if err := mc.library.Storage.Delete(ctx, doc.StorageKey+"_"+variant.Name)
+		err != nil && !errors.Is(err, media.ErrNotExist) {
+			return err
+		}
+	}
+	if err := mc.library.Forget(doc.StorageKey)
+	err != nil {
+		return err
+	}
+	if err := mc.library.Storage.Delete(ctx, doc.StorageKey)
+	err != nil && !errors.Is(err, media.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (mc *MediaController) GetMedia(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	doc, ok := mc.find(ctx, c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"Status" : 200,
+		"Message" : "Data fetched successfully!",
+		"Data" : doc,
+	})
+}
+
+func (mc *MediaController) GetMedias(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	lq, err := parseListQuery(c, mediaFields, nil)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	listDocs(ctx, c, lq, mc.media.List)
+}
+
+// DeleteMedia removes a media that no banner or child category uses.
+func (mc *MediaController) DeleteMedia(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	doc, ok := mc.find(ctx, c)
+	if !ok {
+		return
+	}
+
+	used := repository.Query{Filter: repository.Filter{"media_id": doc.Id}, Limit: 1}
+	_, banners, err := mc.banners.List(ctx, used)
+	if err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+	_, children, err := mc.children.List(ctx, used)
+	if err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+	if banners > 0 || children > 0 {
+		respondError(c, http.StatusConflict, fmt.Errorf("%w: media is used by %d banners and %d child categories", ErrInUse, banners, children))
+		return
+	}
+
+	if err := mc.media.Delete(ctx, doc.Id)
+	err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+	if err := mc.removeFiles(ctx, doc)
+	err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"Status" : 200,
+		"Message" : "Data deleted successfully!",
+	})
+}
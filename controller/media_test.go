@@ -0,0 +1,180 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	_ "image/jpeg"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang_cms/media"
+	"golang_cms/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+func mediaRouter(t *testing.T, maxSize int64) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	storage, err := media.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache, err := media.NewDiskCache(t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	library := media.NewLibrary(storage, cache, media.Limits{MaxSize: maxSize}, media.ImageOptions{
+		MaxDimension: 64,
+		ResizeStep:   4,
+		Variants:     []media.Variant{{Name: "thumb", Transform: media.Transform{Width: 2, Height: 2, Fit: media.FitCover}}},
+	})
+	repos := repository.NewMemoryRepositories()
+	mc := NewMediaController(repos.Media, repos.Banner, repos.Child, library)
+	router := gin.New()
+	router.POST("/media", mc.Upload)
+	router.GET("/media/:mediaid", mc.Serve)
+	router.DELETE("/media/:mediaid", mc.DeleteMedia)
+	NewBannerResource(repos.Banner, repos.Media).Register(router, "/banner", "/banners", allowAll)
+	return router
+}
+
+func pngOf(t *testing.T, w, h int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, w, h))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// upload posts data as the "file" field of a multipart form.
+func upload(t *testing.T, router http.Handler, filename string, data []byte) (int, map[string]interface{}) {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, _ := form.CreateFormFile("file", filename)
+	part.Write(data)
+	form.Close()
+
+	req := httptest.NewRequest("POST", "/media", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	w := serveRequest(router, req)
+	var out map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &out)
+	return w.Code, out
+}
+
+func TestUploadMedia(t *testing.T) {
+	router := mediaRouter(t, 1<<16)
+	data := pngOf(t, 8, 4)
+
+	code, out := upload(t, router, "../../logo.png", data)
+	if code != http.StatusCreated {
+		t.Fatalf("upload = %d %v", code, out)
+	}
+	doc := out["Data"].(map[string]interface{})
+	if doc["filename"] != "logo.png" || doc["content_type"] != "image/png" || doc["width"] != 8.0 || doc["height"] != 4.0 {
+		t.Errorf("uploaded media = %v", doc)
+	}
+
+	code, out = upload(t, router, "again.png", data)
+	if code != http.StatusOK || out["Data"].(map[string]interface{})["id"] != doc["id"] {
+		t.Errorf("same content again = %d %v, want the existing media", code, out)
+	}
+
+	if code, out := upload(t, router, "page.png", []byte("<html></html>")); code != http.StatusUnsupportedMediaType {
+		t.Errorf("html named .png = %d %v", code, out)
+	}
+	if code, out := upload(t, mediaRouter(t, 16), "logo.png", data); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("over the size limit = %d %v", code, out)
+	}
+}
+
+func TestServeMedia(t *testing.T) {
+	router := mediaRouter(t, 1<<16)
+	data := pngOf(t, 2, 2)
+	_, out := upload(t, router, "dot.png", data)
+	id := out["Data"].(map[string]interface{})["id"].(string)
+
+	w := serveRequest(router, httptest.NewRequest("GET", "/media/"+id, nil))
+	if w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), data) {
+		t.Fatalf("serve = %d, %d bytes", w.Code, w.Body.Len())
+	}
+	if w.Header().Get("Content-Type") != "image/png" || w.Header().Get("X-Content-Type-Options") != "nosniff" {
+		t.Errorf("headers = %v", w.Header())
+	}
+
+	req := httptest.NewRequest("GET", "/media/"+id, nil)
+	req.Header.Set("If-None-Match", w.Header().Get("ETag"))
+	if w := serveRequest(router, req); w.Code != http.StatusNotModified {
+		t.Errorf("revalidation = %d", w.Code)
+	}
+}
+
+func TestDeleteMediaInUse(t *testing.T) {
+	router := mediaRouter(t, 1<<16)
+	_, out := upload(t, router, "dot.png", pngOf(t, 2, 2))
+	id := out["Data"].(map[string]interface{})["id"].(string)
+
+	code, out := serve(t, router, "POST", "/banner", `{"media_id":"`+id+`","alt":"a","link":"/a"}`)
+	if code != http.StatusCreated {
+		t.Fatalf("banner with media = %d %v", code, out)
+	}
+	if code, out := serve(t, router, "POST", "/banner", `{"media_id":"000000000000000000000001","alt":"a","link":"/a"}`); code == http.StatusCreated {
+		t.Errorf("banner with unknown media = %d %v", code, out)
+	}
+
+	if code, out := serve(t, router, "DELETE", "/media/"+id, ""); code != http.StatusConflict {
+		t.Errorf("delete media used by a banner = %d %v", code, out)
+	}
+	if code, _ := serve(t, router, "GET", "/media/"+id, ""); code != http.StatusOK {
+		t.Errorf("media gone after a refused delete: %d", code)
+	}
+}
+
+func TestServeMediaVariants(t *testing.T) {
+	router := mediaRouter(t, 1<<16)
+	_, out := upload(t, router, "wide.png", pngOf(t, 8, 4))
+	doc := out["Data"].(map[string]interface{})
+	id := doc["id"].(string)
+	variants, _ := doc["variants"].([]interface{})
+	if len(variants) != 1 || variants[0].(map[string]interface{})["url"] != "/media/"+id+"?variant=thumb" {
+		t.Fatalf("variants = %v", doc["variants"])
+	}
+
+	tests := []struct {
+		query string
+		code  int
+		w, h  int
+	}{
+		{"?variant=thumb", http.StatusOK, 2, 2},
+		{"?w=4", http.StatusOK, 4, 2},
+		{"?w=3", http.StatusOK, 4, 2},
+		{"?h=1&format=jpeg", http.StatusOK, 8, 4},
+		{"?w=6&h=6&fit=fill", http.StatusOK, 8, 8},
+		{"?w=63", http.StatusOK, 64, 32},
+		{"?variant=huge", http.StatusNotFound, 0, 0},
+		{"?w=abc", http.StatusBadRequest, 0, 0},
+		{"?w=65", http.StatusBadRequest, 0, 0},
+		{"?fit=stretch", http.StatusBadRequest, 0, 0},
+	}
+	for _, tt := range tests {
+		w := serveRequest(router, httptest.NewRequest("GET", "/media/"+id+tt.query, nil))
+		if w.Code != tt.code {
+			t.Errorf("%s = %d %s", tt.query, w.Code, w.Body)
+			continue
+		}
+		if tt.code != http.StatusOK {
+			continue
+		}
+		img, _, err := image.Decode(w.Body)
+		if err != nil || img.Bounds().Dx() != tt.w || img.Bounds().Dy() != tt.h {
+			t.Errorf("%s decoded to %v, %v; want %dx%d", tt.query, img.Bounds(), err, tt.w, tt.h)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"golang_cms/model"
+	"golang_cms/repository"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var validasiProduct = validator.New()
+
+// productFields are the fields the product list can be filtered and sorted by.
+var productFields = ListFields{
+	"id" : {Field: "_id", Kind: ObjectIDField},
+	"sku" : {Field: "sku", Kind: StringField},
+	"name" : {Field: "name", Kind: StringField},
+	"description" : {Field: "description", Kind: StringField},
+	"price" : {Field: "price", Kind: IntField},
+	"currency" : {Field: "currency", Kind: StringField},
+	"stock" : {Field: "stock", Kind: IntField},
+	"created_at" : {Field: "created_at", Kind: TimeField},
+	"updated_at" : {Field: "updated_at", Kind: TimeField},
+}
+
+// ProductController serves the product catalog. Every product is filed
+// under one or more categories of the category tree.
+type ProductController struct {
+	products   repository.ProductRepository
+	categories repository.CategoryTreeRepository
+}
+
+func NewProductController(products repository.ProductRepository, categories repository.CategoryTreeRepository) *ProductController {
+	return &ProductController{products: products, categories: categories}
+}
+
+func (pc *ProductController) id(c *gin.Context) (primitive.ObjectID, bool) {
+	objId, err := primitive.ObjectIDFromHex(c.Param("productid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"Status" : 400,
+			"Message" : "Invalid product id!",
+		})
+		return objId, false
+	}
+	return objId, true
+}
+
+// bind reads and validates the request body, including that every category
+// it names exists.
+func (pc *ProductController) bind(ctx context.Context, c *gin.Context) (model.Product, bool) {
+	var input model.Product
+	if err := c.ShouldBindJSON(&input)
+	err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return input, false
+	}
+
+	input.Currency = strings.ToUpper(input.Currency)
+	if validationErr := validasiProduct.Struct(&input)
+	validationErr != nil {
+		respondError(c, http.StatusBadRequest, validationErr)
+		return input, false
+	}
+
+	for _, categoryId := range input.CategoryIds {
+		if _, err := pc.categories.FindByID(ctx, categoryId)
+		err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				respondError(c, http.StatusBadRequest, fmt.Errorf("category %s does not exist", categoryId.Hex()))
+				return input, false
+			}
+			respondError(c, statusOf(err), err)
+			return input, false
+		}
+	}
+
+	return input, true
+}
+
+func (pc *ProductController) CreateProduct(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	input, ok := pc.bind(ctx, c)
+	if !ok {
+		return
+	}
+
+	//bson menyimpan waktu dalam milidetik, dipotong di sini supaya semua
+	//backend menyimpan nilai yang sama dan cursor created_at tetap tepat
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	newProduct := model.Product{
+		Id: primitive.NewObjectID(),
+		Sku: input.Sku,
+		Name: input.Name,
+		Description: input.Description,
+		Price: input.Price,
+		Currency: input.Currency,
+		Stock: input.Stock,
+		Images: input.Images,
+		CategoryIds: input.CategoryIds,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if _, err := pc.products.Create(ctx, newProduct)
+	err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"Status" : 201,
+		"Message" : "Data created successfully!",
+		"Data" : gin.H{"InsertedID" : newProduct.Id},
+	})
+}
+
+func (pc *ProductController) GetProduct(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	objId, ok := pc.id(c)
+	if !ok {
+		return
+	}
+
+	product, err := pc.products.FindByID(ctx, objId)
+	if err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"Status" : 200,
+		"Message" : "Data fetched successfully!",
+		"Data" : product,
+	})
+}
+
+// GetProducts lists products, accepting the list query of parseListQuery.
+// With ?category=<id> only the products filed under that category or any
+// of its descendants are returned; add &descendants=false to leave the
+// descendants out.
+func (pc *ProductController) GetProducts(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	lq, err := parseListQuery(c, productFields, nil, "category", "descendants")
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	hex := c.Query("category")
+	if hex == "" {
+		listDocs(ctx, c, lq, pc.products.List)
+		return
+	}
+
+	categoryIds, ok := pc.categoryFilter(ctx, c, hex)
+	if !ok {
+		return
+	}
+	listDocs(ctx, c, lq, func(ctx context.Context, q repository.Query) ([]model.Product, int64, error) {
+		return pc.products.ListByCategories(ctx, categoryIds, q)
+	})
+}
+
+// categoryFilter resolves ?category to the ids a product may be filed under.
+func (pc *ProductController) categoryFilter(ctx context.Context, c *gin.Context, hex string) ([]primitive.ObjectID, bool) {
+	categoryId, err := primitive.ObjectIDFromHex(hex)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"Status" : 400,
+			"Message" : "Invalid category id!",
+		})
+		return nil, false
+	}
+
+	category, err := pc.categories.FindByID(ctx, categoryId)
+	if err != nil {
+		respondError(c, statusOf(err), err)
+		return nil, false
+	}
+
+	categoryIds := []primitive.ObjectID{category.Id}
+	if c.Query("descendants") == "false" {
+		return categoryIds, true
+	}
+
+	descendants, err := pc.categories.FindDescendants(ctx, category)
+	if err != nil {
+		respondError(c, statusOf(err), err)
+		return nil, false
+	}
+	for _, descendant := range descendants {
+		categoryIds = append(categoryIds, descendant.Id)
+	}
+	return categoryIds, true
+}
+
+func (pc *ProductController) EditProduct(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	objId, ok := pc.id(c)
+	if !ok {
+		return
+	}
+
+	product, err := pc.products.FindByID(ctx, objId)
+	if err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+
+	input, ok := pc.bind(ctx, c)
+	if !ok {
+		return
+	}
+
+	product.Sku = input.Sku
+	product.Name = input.Name
+	product.Description = input.Description
+	product.Price = input.Price
+	product.Currency = input.Currency
+	product.Stock = input.Stock
+	product.Images = input.Images
+	product.CategoryIds = input.CategoryIds
+	product.UpdatedAt = time.Now().UTC().Truncate(time.Millisecond)
+
+	updatedProduct, err := pc.products.Update(ctx, product)
+	if err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"Status" : 200,
+		"Message" : "Data updated successfully!",
+		"Data" : updatedProduct,
+	})
+}
+
+func (pc *ProductController) DeleteProduct(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	objId, ok := pc.id(c)
+	if !ok {
+		return
+	}
+
+	if err := pc.products.Delete(ctx, objId)
+	err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"Status" : 200,
+		"Message" : "Data deleted successfully!",
+	})
+}
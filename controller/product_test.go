@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"testing"
+)
+
+// createProduct files a product under the given categories and returns its id.
+func createProduct(t *testing.T, router http.Handler, sku string, categories ...string) string {
+	t.Helper()
+	ids := ""
+	for i, category := range categories {
+		if i > 0 {
+			ids += ","
+		}
+		ids += fmt.Sprintf("%q", category)
+	}
+	body := fmt.Sprintf(`{"sku":%q,"name":%q,"price":100,"currency":"eur","category_ids":[%s]}`, sku, sku, ids)
+	code, out := serve(t, router, "POST", "/product", body)
+	if code != http.StatusCreated {
+		t.Fatalf("create %s = %d %v", sku, code, out)
+	}
+	return out["Data"].(map[string]interface{})["InsertedID"].(string)
+}
+
+// productSkus returns the sorted skus of the products listed in out.
+func productSkus(out map[string]interface{}) string {
+	var skus []string
+	items, _ := out["Data"].([]interface{})
+	for _, item := range items {
+		skus = append(skus, item.(map[string]interface{})["sku"].(string))
+	}
+	sort.Strings(skus)
+	return fmt.Sprint(skus)
+}
+
+func TestProductCategories(t *testing.T) {
+	router := categoryRouter()
+	shoes := createCategory(t, router, "shoes", "")
+	boots := createCategory(t, router, "boots", shoes)
+	hats := createCategory(t, router, "hats", "")
+	createProduct(t, router, "sneaker", shoes)
+	createProduct(t, router, "chelsea", boots)
+	createProduct(t, router, "beanie", hats)
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"", "[beanie chelsea sneaker]"},
+		{"?category=" + shoes, "[chelsea sneaker]"},
+		{"?category=" + shoes + "&descendants=false", "[sneaker]"},
+		{"?category=" + boots, "[chelsea]"},
+	}
+	for _, tt := range tests {
+		code, out := serve(t, router, "GET", "/products"+tt.query, "")
+		if got := productSkus(out); code != http.StatusOK || got != tt.want {
+			t.Errorf("GET /products%s = %d %s, want %s", tt.query, code, got, tt.want)
+		}
+	}
+	if code, _ := serve(t, router, "GET", "/products?category=nope", ""); code != http.StatusBadRequest {
+		t.Errorf("bad category id = %d", code)
+	}
+}
+
+func TestProductNeedsExistingCategories(t *testing.T) {
+	router := categoryRouter()
+	shoes := createCategory(t, router, "shoes", "")
+	id := createProduct(t, router, "sneaker", shoes)
+
+	missing := `{"sku":"boot","name":"boot","currency":"EUR","category_ids":["000000000000000000000001"]}`
+	if code, out := serve(t, router, "POST", "/product", missing); code != http.StatusBadRequest {
+		t.Errorf("create under a missing category = %d %v", code, out)
+	}
+	if code, out := serve(t, router, "PUT", "/product/"+id, missing); code != http.StatusBadRequest {
+		t.Errorf("edit onto a missing category = %d %v", code, out)
+	}
+	if code, out := serve(t, router, "POST", "/product", `{"sku":"sneaker","name":"again","currency":"EUR","category_ids":["`+shoes+`"]}`); code != http.StatusConflict {
+		t.Errorf("create with a taken sku = %d %v", code, out)
+	}
+}
+
+func TestDeleteCategoryWithProducts(t *testing.T) {
+	router := categoryRouter()
+	shoes := createCategory(t, router, "shoes", "")
+	createProduct(t, router, "sneaker", shoes)
+
+	if code, out := serve(t, router, "DELETE", "/categories/"+shoes, ""); code != http.StatusConflict {
+		t.Errorf("delete a category with products = %d %v", code, out)
+	}
+}
@@ -0,0 +1,413 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"golang_cms/helper"
+	"golang_cms/repository"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FieldKind says how a query string value is parsed for a list field.
+type FieldKind int
+
+const (
+	StringField FieldKind = iota
+	IntField
+	FloatField
+	BoolField
+	TimeField
+	ObjectIDField
+)
+
+// ListField is a field clients may filter and sort a list by. Field is the
+// bson name it is stored under.
+type ListField struct {
+	Field string
+	Kind  FieldKind
+}
+
+// ListFields is the allowlist of a list endpoint, keyed by the name used in
+// the query string. Fields not listed here cannot be filtered or sorted on.
+type ListFields map[string]ListField
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// ListQuery is a parsed list request: the repository query plus the page
+// it was asked for. Page is zero when the request used a cursor.
+type ListQuery struct {
+	repository.Query
+	Page int64
+}
+
+// listCursor is the content of a pagination cursor. Scope ties it to the
+// path and filters it was made for; Values are the sort values of the last
+// (or, for Before, the first) document of the page it continues from.
+type listCursor struct {
+	Scope  string            `bson:"s"`
+	Sort   []repository.Sort `bson:"o"`
+	Values []interface{}     `bson:"v"`
+	Before bool              `bson:"b"`
+}
+
+// cursorSortFields are the fields cursors may order by. They are set once
+// and never empty, which keyset paging needs for a stable order.
+var cursorSortFields = map[string]bool{"_id": true, "created_at": true}
+
+// parseListQuery reads a list request from the query string:
+//
+//	page=2&limit=50         page number from 1 and page size up to 100
+//	sort=-price,name        sort fields, "-" for descending
+//	name=shoe               equality, same as name[eq]=shoe
+//	price[gte]=100          operators eq, ne, in, contains, gte and lte
+//	currency[in]=USD,EUR    in takes a comma separated list
+//	cursor=...              continue from a next or prev cursor instead of
+//	                        a page; the other parameters must stay the same
+//
+// Parameters named in reserved are left to the handler; any other parameter
+// must name a field in fields. defaultSort applies when no sort is given.
+func parseListQuery(c *gin.Context, fields ListFields, defaultSort []repository.Sort, reserved ...string) (ListQuery, error) {
+	params := c.Request.URL.Query()
+	lq := ListQuery{Page: 1}
+	lq.Filter = repository.Filter{}
+	lq.Sort = defaultSort
+	lq.Limit = defaultPageLimit
+
+	skip := map[string]bool{"page": true, "limit": true, "sort": true, "cursor": true}
+	for _, name := range reserved {
+		skip[name] = true
+	}
+
+	if value := params.Get("page"); value != "" {
+		page, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || page < 1 {
+			return lq, fmt.Errorf("invalid page %q", value)
+		}
+		lq.Page = page
+	}
+	if value := params.Get("limit"); value != "" {
+		limit, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			return lq, fmt.Errorf("invalid limit %q, must be between 1 and %d", value, maxPageLimit)
+		}
+		lq.Limit = limit
+	}
+	lq.Skip = (lq.Page - 1) * lq.Limit
+
+	if value := params.Get("sort"); value != "" {
+		lq.Sort = nil
+		for _, name := range strings.Split(value, ",") {
+			desc := strings.HasPrefix(name, "-")
+			name = strings.TrimPrefix(name, "-")
+			field, ok := fields[name]
+			if !ok {
+				return lq, fmt.Errorf("cannot sort by %q", name)
+			}
+			lq.Sort = append(lq.Sort, repository.Sort{Field: field.Field, Desc: desc})
+		}
+	}
+
+	for param, values := range params {
+		if skip[param] {
+			continue
+		}
+		name, op := splitFilterParam(param)
+		field, ok := fields[name]
+		if !ok {
+			return lq, fmt.Errorf("cannot filter by %q", name)
+		}
+
+		for _, value := range values {
+			cond, err := parseCond(field, op, value)
+			if err != nil {
+				return lq, fmt.Errorf("invalid filter %s: %w", param, err)
+			}
+			conds, _ := lq.Filter[field.Field].([]repository.Cond)
+			lq.Filter[field.Field] = append(conds, cond)
+		}
+	}
+
+	if token := params.Get("cursor"); token != "" {
+		if params.Get("page") != "" {
+			return lq, errors.New("page and cursor cannot be used together")
+		}
+		cursor, err := decodeCursor(token)
+		if err != nil || cursor.Scope != cursorScope(c) {
+			return lq, helper.ErrInvalidCursor
+		}
+		lq.Page, lq.Skip, lq.Sort = 0, 0, cursor.Sort
+		if cursor.Before {
+			lq.Before = cursor.Values
+		} else {
+			lq.After = cursor.Values
+		}
+	}
+
+	return lq, nil
+}
+
+// cursorScope identifies the list a cursor belongs to: the request path and
+// every parameter but the ones that move through it.
+func cursorScope(c *gin.Context) string {
+	params := c.Request.URL.Query()
+	params.Del("page")
+	params.Del("limit")
+	params.Del("cursor")
+	return c.Request.URL.Path + "?" + params.Encode()
+}
+
+func encodeCursor(cursor listCursor) (string, error) {
+	data, err := bson.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return helper.SignCursor(data), nil
+}
+
+func decodeCursor(token string) (listCursor, error) {
+	var cursor listCursor
+	data, err := helper.VerifyCursor(token)
+	if err != nil {
+		return cursor, err
+	}
+	err = bson.Unmarshal(data, &cursor)
+	return cursor, err
+}
+
+// cursorable reports whether pages in this order can be reached by cursor.
+func cursorable(sort []repository.Sort) bool {
+	for _, field := range sort {
+		if !cursorSortFields[field.Field] {
+			return false
+		}
+	}
+	return true
+}
+
+// splitFilterParam splits "price[gte]" into "price" and "gte". A parameter
+// without brackets compares by equality.
+func splitFilterParam(param string) (string, repository.Op) {
+	open := strings.Index(param, "[")
+	if open < 0 || !strings.HasSuffix(param, "]") {
+		return param, repository.OpEq
+	}
+	return param[:open], repository.Op(param[open+1 : len(param)-1])
+}
+
+func parseCond(field ListField, op repository.Op, raw string) (repository.Cond, error) {
+	switch op {
+	case repository.OpIn:
+		var values []interface{}
+		for _, part := range strings.Split(raw, ",") {
+			value, err := parseFieldValue(field.Kind, part)
+			if err != nil {
+				return repository.Cond{}, err
+			}
+			values = append(values, value)
+		}
+		return repository.In(values...), nil
+	case repository.OpContains:
+		if field.Kind != StringField {
+			return repository.Cond{}, errors.New("contains only applies to text fields")
+		}
+		return repository.Contains(raw), nil
+	case repository.OpEq, repository.OpNe, repository.OpGte, repository.OpLte:
+		value, err := parseFieldValue(field.Kind, raw)
+		if err != nil {
+			return repository.Cond{}, err
+		}
+		return repository.Cond{Op: op, Values: []interface{}{value}}, nil
+	}
+	return repository.Cond{}, fmt.Errorf("unknown operator %q", op)
+}
+
+func parseFieldValue(kind FieldKind, raw string) (interface{}, error) {
+	switch kind {
+	case IntField:
+		return strconv.ParseInt(raw, 10, 64)
+	case FloatField:
+		return strconv.ParseFloat(raw, 64)
+	case BoolField:
+		return strconv.ParseBool(raw)
+	case TimeField:
+		t, err := time.Parse(time.RFC3339, raw)
+		return t.UTC(), err
+	case ObjectIDField:
+		return primitive.ObjectIDFromHex(raw)
+	}
+	return raw, nil
+}
+
+// listPage is one page of a list with the cursors of its neighbours, which
+// are empty when there is no such page or the list order has no cursors.
+type listPage[T any] struct {
+	docs  []T
+	total int64
+	next  string
+	prev  string
+}
+
+// fetchList runs lq through list, asking for one document more than the
+// page holds to learn whether another page follows.
+func fetchList[T any](ctx context.Context, c *gin.Context, lq ListQuery, list func(context.Context, repository.Query) ([]T, int64, error)) (listPage[T], error) {
+	q := lq.Query
+	q.Limit = lq.Limit + 1
+	docs, total, err := list(ctx, q)
+	if err != nil {
+		return listPage[T]{}, err
+	}
+
+	// paging backwards, the extra document is the one furthest back
+	more := int64(len(docs)) > lq.Limit
+	if more && lq.Before != nil {
+		docs = docs[1:]
+	} else if more {
+		docs = docs[:lq.Limit]
+	}
+
+	page := listPage[T]{docs: docs, total: total}
+	if docs == nil {
+		page.docs = []T{}
+	}
+	if len(docs) == 0 || !cursorable(lq.Sort) {
+		return page, nil
+	}
+
+	hasNext := more
+	hasPrev := lq.Skip > 0 || lq.After != nil
+	if lq.Before != nil {
+		hasNext, hasPrev = true, more
+	}
+
+	scope := cursorScope(c)
+	if hasNext {
+		values, err := repository.SortValues(docs[len(docs)-1], lq.Sort)
+		if err != nil {
+			return page, err
+		}
+		if page.next, err = encodeCursor(listCursor{Scope: scope, Sort: lq.Sort, Values: values}); err != nil {
+			return page, err
+		}
+	}
+	if hasPrev {
+		values, err := repository.SortValues(docs[0], lq.Sort)
+		if err != nil {
+			return page, err
+		}
+		if page.prev, err = encodeCursor(listCursor{Scope: scope, Sort: lq.Sort, Values: values, Before: true}); err != nil {
+			return page, err
+		}
+	}
+	return page, nil
+}
+
+// pageLinks builds the links to the first, last, previous and next pages
+// of the current request, keeping its other parameters. A request made
+// with a cursor gets cursor links and no last page.
+func pageLinks[T any](c *gin.Context, lq ListQuery, page listPage[T]) gin.H {
+	link := func(set map[string]string) string {
+		params := c.Request.URL.Query()
+		params.Del("page")
+		params.Del("cursor")
+		params.Set("limit", strconv.FormatInt(lq.Limit, 10))
+		for key, value := range set {
+			params.Set(key, value)
+		}
+		return (&url.URL{Path: c.Request.URL.Path, RawQuery: params.Encode()}).String()
+	}
+	pageLink := func(number int64) string {
+		return link(map[string]string{"page" : strconv.FormatInt(number, 10)})
+	}
+	cursorLink := func(cursor string) interface{} {
+		if cursor == "" {
+			return nil
+		}
+		return link(map[string]string{"cursor" : cursor})
+	}
+
+	if lq.Page == 0 {
+		return gin.H{
+			"self" : link(map[string]string{"cursor" : c.Query("cursor")}),
+			"first" : pageLink(1),
+			"last" : nil,
+			"prev" : cursorLink(page.prev),
+			"next" : cursorLink(page.next),
+		}
+	}
+
+	pages := totalPages(page.total, lq.Limit)
+	links := gin.H{
+		"self" : pageLink(lq.Page),
+		"first" : pageLink(1),
+		"last" : pageLink(pages),
+		"prev" : nil,
+		"next" : nil,
+	}
+	if lq.Page > 1 {
+		links["prev"] = pageLink(lq.Page - 1)
+	}
+	if lq.Page < pages {
+		links["next"] = pageLink(lq.Page + 1)
+	}
+	return links
+}
+
+// totalPages is the number of pages of limit items holding total items; an
+// empty list still has one page.
+func totalPages(total int64, limit int64) int64 {
+	if total == 0 {
+		return 1
+	}
+	return (total + limit - 1) / limit
+}
+
+// listMeta describes the page and carries the cursors of its neighbours.
+func listMeta[T any](lq ListQuery, page listPage[T]) gin.H {
+	meta := gin.H{
+		"total" : page.total,
+		"limit" : lq.Limit,
+		"next_cursor" : nil,
+		"prev_cursor" : nil,
+	}
+	if lq.Page > 0 {
+		meta["page"] = lq.Page
+		meta["total_pages"] = totalPages(page.total, lq.Limit)
+	}
+	if page.next != "" {
+		meta["next_cursor"] = page.next
+	}
+	if page.prev != "" {
+		meta["prev_cursor"] = page.prev
+	}
+	return meta
+}
+
+// listDocs fetches a page through list and writes it with its total,
+// cursors and links, or writes the error.
+func listDocs[T any](ctx context.Context, c *gin.Context, lq ListQuery, list func(context.Context, repository.Query) ([]T, int64, error)) {
+	page, err := fetchList(ctx, c, lq, list)
+	if err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"Status" : 200,
+		"Message" : "Data fetched successfully!",
+		"Data" : page.docs,
+		"Meta" : listMeta(lq, page),
+		"Links" : pageLinks(c, lq, page),
+	})
+}
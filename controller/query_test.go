@@ -0,0 +1,198 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"golang_cms/model"
+	"golang_cms/repository"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var testFields = ListFields{
+	"name" : {Field: "name", Kind: StringField},
+	"price" : {Field: "price", Kind: FloatField},
+	"stock" : {Field: "stock", Kind: IntField},
+	"active" : {Field: "active", Kind: BoolField},
+	"created_at" : {Field: "created_at", Kind: TimeField},
+	"id" : {Field: "_id", Kind: ObjectIDField},
+}
+
+func parseTestQuery(t *testing.T, query string, reserved ...string) (ListQuery, error) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/items?"+query, nil)
+	return parseListQuery(c, testFields, []repository.Sort{{Field: "name"}}, reserved...)
+}
+
+func TestParseListQueryPaging(t *testing.T) {
+	lq, err := parseTestQuery(t, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lq.Page != 1 || lq.Limit != defaultPageLimit || lq.Skip != 0 || !reflect.DeepEqual(lq.Sort, []repository.Sort{{Field: "name"}}) {
+		t.Errorf("defaults = %+v", lq)
+	}
+
+	lq, err = parseTestQuery(t, "page=3&limit=10")
+	if err != nil || lq.Page != 3 || lq.Limit != 10 || lq.Skip != 20 {
+		t.Errorf("page 3 of 10 = %+v, %v", lq, err)
+	}
+
+	for _, query := range []string{"page=0", "page=x", "limit=0", fmt.Sprintf("limit=%d", maxPageLimit+1), "limit=-5"} {
+		if _, err := parseTestQuery(t, query); err == nil {
+			t.Errorf("%s accepted", query)
+		}
+	}
+}
+
+func TestParseListQuerySort(t *testing.T) {
+	lq, err := parseTestQuery(t, "sort=-price,name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []repository.Sort{{Field: "price", Desc: true}, {Field: "name"}}
+	if !reflect.DeepEqual(lq.Sort, want) {
+		t.Errorf("sort = %+v, want %+v", lq.Sort, want)
+	}
+
+	lq, err = parseTestQuery(t, "sort=id")
+	if err != nil || !reflect.DeepEqual(lq.Sort, []repository.Sort{{Field: "_id"}}) {
+		t.Errorf("sort by id = %+v, %v", lq.Sort, err)
+	}
+
+	if _, err := parseTestQuery(t, "sort=password"); err == nil {
+		t.Error("sorting by a field not allowed accepted")
+	}
+}
+
+func TestParseListQueryFilters(t *testing.T) {
+	id := primitive.NewObjectID()
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	query := "name=shoe&price[gte]=9.5&price[lte]=20&stock[ne]=0&active=true&name[contains]=sh" +
+		"&created_at[gte]=2024-05-01T14:00:00%2B02:00&id[in]=" + id.Hex()
+	lq, err := parseTestQuery(t, query)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string][]repository.Cond{
+		"name" : []repository.Cond{repository.Eq("shoe"), repository.Contains("sh")},
+		"price" : []repository.Cond{{Op: repository.OpGte, Values: []interface{}{9.5}}, {Op: repository.OpLte, Values: []interface{}{20.0}}},
+		"stock" : []repository.Cond{{Op: repository.OpNe, Values: []interface{}{int64(0)}}},
+		"active" : []repository.Cond{repository.Eq(true)},
+		"created_at" : []repository.Cond{{Op: repository.OpGte, Values: []interface{}{created}}},
+		"_id" : []repository.Cond{repository.In(id)},
+	}
+	for field, conds := range want {
+		got, _ := lq.Filter[field].([]repository.Cond)
+		if len(got) != len(conds) {
+			t.Errorf("%s = %+v, want %+v", field, got, conds)
+			continue
+		}
+		// conditions on one field keep no order between parameters
+		for _, cond := range conds {
+			found := false
+			for _, g := range got {
+				found = found || reflect.DeepEqual(g, cond)
+			}
+			if !found {
+				t.Errorf("%s = %+v, missing %+v", field, got, cond)
+			}
+		}
+	}
+	if len(lq.Filter) != len(want) {
+		t.Errorf("filter = %+v", lq.Filter)
+	}
+
+	lq, err = parseTestQuery(t, "stock[in]=1,2,3")
+	if err != nil || !reflect.DeepEqual(lq.Filter["stock"], []repository.Cond{repository.In(int64(1), int64(2), int64(3))}) {
+		t.Errorf("in = %+v, %v", lq.Filter["stock"], err)
+	}
+}
+
+func TestParseListQueryRejects(t *testing.T) {
+	tests := []string{
+		"password=x",
+		"price[contains]=1",
+		"price[gt]=1",
+		"price=cheap",
+		"stock[in]=1,two",
+		"active=maybe",
+		"created_at=yesterday",
+		"id=123",
+	}
+	for _, query := range tests {
+		if _, err := parseTestQuery(t, query); err == nil {
+			t.Errorf("%s accepted", query)
+		}
+	}
+}
+
+func TestParseListQueryReserved(t *testing.T) {
+	lq, err := parseTestQuery(t, "category=abc&name=x", "category")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := lq.Filter["category"]; ok || len(lq.Filter) != 1 {
+		t.Errorf("filter = %+v", lq.Filter)
+	}
+}
+
+// descRouter serves the desc resource over the memory repositories with
+// count descs already stored.
+func descRouter(t *testing.T, count int) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	repos := repository.NewMemoryRepositories()
+	for i := 0; i < count; i++ {
+		desc := model.Desc{Id: primitive.NewObjectID(), Title: fmt.Sprintf("title %02d", i), Desc: "d"}
+		if _, err := repos.Desc.Create(context.Background(), desc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	router := gin.New()
+	NewDescResource(repos.Desc, repos.Revision).Register(router, "/desc", "/descs", allowAll)
+	return router
+}
+
+func TestListPages(t *testing.T) {
+	router := descRouter(t, 5)
+
+	code, out := serve(t, router, "GET", "/descs?limit=2&page=2", "")
+	if code != http.StatusOK {
+		t.Fatalf("list = %d %v", code, out)
+	}
+	if docs := out["Data"].([]interface{}); len(docs) != 2 {
+		t.Errorf("got %d docs", len(docs))
+	}
+	meta := out["Meta"].(map[string]interface{})
+	if meta["total"] != 5.0 || meta["page"] != 2.0 || meta["total_pages"] != 3.0 {
+		t.Errorf("meta = %v", meta)
+	}
+	links := out["Links"].(map[string]interface{})
+	for name, want := range map[string]string{"first": "page=1", "prev": "page=1", "next": "page=3", "last": "page=3"} {
+		link, _ := links[name].(string)
+		if !strings.Contains(link, want) || !strings.Contains(link, "limit=2") {
+			t.Errorf("%s link = %v, want %s", name, links[name], want)
+		}
+	}
+
+	code, out = serve(t, router, "GET", "/descs?limit=2&page=9", "")
+	if code != http.StatusOK || len(out["Data"].([]interface{})) != 0 {
+		t.Errorf("page past the end = %d %v", code, out)
+	}
+
+	if code, _ := serve(t, router, "GET", "/descs?secret=x", ""); code != http.StatusBadRequest {
+		t.Errorf("filter on a field not allowed = %d", code)
+	}
+}
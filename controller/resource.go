@@ -0,0 +1,314 @@
+package controller
+
+import (
+	"context"
+	"golang_cms/rbac"
+	"golang_cms/repository"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var validasiResource = validator.New()
+
+// Resource serves create, get, list, update and delete for one content type
+// so every type validates, maps and responds the same way.
+type Resource[T any] struct {
+	Repo    repository.CrudRepository[T]
+	IDParam string
+
+	// Permission is the resource the permissions of its routes are named
+	// after, as in "banner:write".
+	Permission string
+
+	// Map builds the document to store from the request body and the id
+	// it is stored under. It must copy every field clients may set.
+	Map func(input T, id primitive.ObjectID) T
+
+	// Validate checks the request body before it is mapped. It defaults to
+	// the struct's validate tags.
+	Validate func(input *T) error
+
+	// BeforeSave, when set, runs on the mapped document before it is
+	// created or updated. It may fill in derived fields or refuse the write
+	// by returning an error; the response uses statusOf for its status.
+	BeforeSave func(ctx context.Context, doc *T) error
+
+	// BeforeDelete, when set, can refuse a delete by returning an error;
+	// the response uses statusOf for its status.
+	BeforeDelete func(ctx context.Context, id primitive.ObjectID) error
+
+	// Fields are the fields List can be filtered and sorted by.
+	Fields ListFields
+
+	// History, when set, records a revision of every create, update and
+	// restore, and Register adds the revision routes.
+	History *History[T]
+}
+
+// Guard returns the middleware that only lets through users granted every
+// one of permissions, such as middleware.RequirePermission.
+type Guard func(permissions ...string) gin.HandlerFunc
+
+func NewResource[T any](repo repository.CrudRepository[T], permission string, idParam string, mapFn func(input T, id primitive.ObjectID) T) *Resource[T] {
+	r := &Resource[T]{
+		Repo:       repo,
+		IDParam:    idParam,
+		Permission: permission,
+		Map:        mapFn,
+		Validate: func(input *T) error {
+			return validasiResource.Struct(input)
+		},
+		Fields: ListFields{"id" : {Field: "_id", Kind: ObjectIDField}},
+	}
+	if _, ok := workflowOf(new(T)); ok {
+		for name, field := range workflowFields {
+			r.Fields[name] = field
+		}
+	}
+	return r
+}
+
+// Register adds the routes of the resource: itemPath for a single document
+// (POST itemPath, GET/PUT/DELETE itemPath/:id) and listPath for the list,
+// plus PUT itemPath/:id/status for types with a publishing status, the
+// trash routes for types deleted to the trash first and
+// itemPath/:id/revisions when the resource keeps a history. Reads need the
+// read permission of the resource and everything else its write
+// permission; guard enforces them.
+func (r *Resource[T]) Register(routes gin.IRoutes, itemPath string, listPath string, guard Guard) {
+	idPath := itemPath + "/:" + r.IDParam
+	read := guard(rbac.Permission(r.Permission, rbac.ActionRead))
+	write := guard(rbac.Permission(r.Permission, rbac.ActionWrite))
+
+	routes.POST(itemPath, write, r.Create)
+	routes.GET(idPath, read, r.Get)
+	routes.PUT(idPath, write, r.Update)
+	routes.DELETE(idPath, write, r.Delete)
+	routes.GET(listPath, read, r.List)
+
+	if _, ok := workflowOf(new(T)); ok {
+		routes.PUT(idPath + "/status", write, r.SetStatus)
+	}
+	if _, ok := trashOf(new(T)); ok {
+		r.registerTrash(routes, idPath, listPath, read, write)
+	}
+	if r.History != nil {
+		r.registerHistory(routes, idPath, read, write)
+	}
+}
+
+func (r *Resource[T]) bind(c *gin.Context) (T, bool) {
+	var input T
+	if err := c.ShouldBind(&input)
+	err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return input, false
+	}
+
+	if validationErr := r.Validate(&input)
+	validationErr != nil {
+		respondError(c, http.StatusBadRequest, validationErr)
+		return input, false
+	}
+
+	return input, true
+}
+
+func (r *Resource[T]) beforeSave(ctx context.Context, c *gin.Context, doc *T) bool {
+	if r.BeforeSave == nil {
+		return true
+	}
+	if err := r.BeforeSave(ctx, doc)
+	err != nil {
+		respondError(c, statusOf(err), err)
+		return false
+	}
+	return true
+}
+
+func (r *Resource[T]) id(c *gin.Context) (primitive.ObjectID, bool) {
+	objId, err := primitive.ObjectIDFromHex(c.Param(r.IDParam))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"Status" : 400,
+			"Message" : "Invalid id!",
+		})
+		return objId, false
+	}
+	return objId, true
+}
+
+// find loads document id for a read or a write. A document in the trash
+// is reported as not found; only the trash routes reach it.
+func (r *Resource[T]) find(ctx context.Context, c *gin.Context, id primitive.ObjectID) (T, bool) {
+	doc, err := r.Repo.FindByID(ctx, id)
+	if err == nil && isTrashed(&doc) {
+		err = repository.ErrNotFound
+	}
+	if err != nil {
+		respondError(c, statusOf(err), err)
+		return doc, false
+	}
+	return doc, true
+}
+
+func (r *Resource[T]) Create(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	input, ok := r.bind(c)
+	if !ok {
+		return
+	}
+
+	id := primitive.NewObjectID()
+	doc := r.Map(input, id)
+	if !r.workflow(c, &doc, nil) || !r.beforeSave(ctx, c, &doc) {
+		return
+	}
+
+	created, err := r.Repo.Create(ctx, doc)
+	if err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+
+	if r.History != nil {
+		r.History.recordWritten(ctx, c, id, created, RevisionCreated, nil)
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"Status" : 201,
+		"Message" : "Data created successfully!",
+		"Data" : gin.H{"InsertedID" : id},
+	})
+}
+
+func (r *Resource[T]) Get(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	objId, ok := r.id(c)
+	if !ok {
+		return
+	}
+
+	doc, ok := r.find(ctx, c, objId)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"Status" : 200,
+		"Message" : "Data fetched successfully!",
+		"Data" : doc,
+	})
+}
+
+func (r *Resource[T]) List(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	lq, err := parseListQuery(c, r.Fields, nil)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if _, ok := trashOf(new(T)); ok {
+		lq.Filter["deleted_at"] = nil
+	}
+
+	listDocs(ctx, c, lq, r.Repo.List)
+}
+
+func (r *Resource[T]) Update(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	objId, ok := r.id(c)
+	if !ok {
+		return
+	}
+
+	input, ok := r.bind(c)
+	if !ok {
+		return
+	}
+
+	stored, ok := r.find(ctx, c, objId)
+	if !ok {
+		return
+	}
+
+	doc := r.Map(input, objId)
+	if !r.workflow(c, &doc, &stored) || !r.beforeSave(ctx, c, &doc) {
+		return
+	}
+
+	if r.History != nil {
+		if err := r.History.Baseline(ctx, objId, func() (T, error) { return stored, nil })
+		err != nil {
+			respondError(c, statusOf(err), err)
+			return
+		}
+	}
+
+	updated, err := r.Repo.Update(ctx, doc)
+	if err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+
+	if r.History != nil {
+		r.History.recordWritten(ctx, c, objId, updated, RevisionUpdated, nil)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"Status" : 200,
+		"Message" : "Data updated successfully!",
+		"Data" : updated,
+	})
+}
+
+func (r *Resource[T]) Delete(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	objId, ok := r.id(c)
+	if !ok {
+		return
+	}
+
+	if _, ok := trashOf(new(T)); ok {
+		if r.moveToTrash(ctx, c, objId) {
+			c.JSON(http.StatusOK, gin.H{
+				"Status" : 200,
+				"Message" : "Data moved to trash!",
+			})
+		}
+		return
+	}
+
+	if r.BeforeDelete != nil {
+		if err := r.BeforeDelete(ctx, objId)
+		err != nil {
+			respondError(c, statusOf(err), err)
+			return
+		}
+	}
+
+	if err := r.Repo.Delete(ctx, objId)
+	err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"Status" : 200,
+		"Message" : "Data deleted successfully!",
+	})
+}
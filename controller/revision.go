@@ -0,0 +1,286 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang_cms/helper"
+	"golang_cms/model"
+	"golang_cms/repository"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	RevisionCreated  = "create"
+	RevisionUpdated  = "update"
+	RevisionRestored = "restore"
+	RevisionBaseline = "baseline"
+)
+
+// revisionFields are the fields a list of revisions can be filtered and
+// sorted by; the document always comes from the URL.
+var revisionFields = ListFields{
+	"number" : {Field: "number", Kind: IntField},
+	"action" : {Field: "action", Kind: StringField},
+	"author" : {Field: "author", Kind: StringField},
+	"author_email" : {Field: "author_email", Kind: StringField},
+	"created_at" : {Field: "created_at", Kind: TimeField},
+}
+
+// History records a revision of every document its Resource creates or
+// updates, and serves the revisions of a document under its item path.
+type History[T any] struct {
+	Repo        repository.RevisionRepository
+	ContentType string
+}
+
+func NewHistory[T any](repo repository.RevisionRepository, contentType string) *History[T] {
+	return &History[T]{Repo: repo, ContentType: contentType}
+}
+
+// Record stores doc as the newest revision of document id. The author is
+// the user of the request, when it was authenticated.
+func (h *History[T]) Record(ctx context.Context, c *gin.Context, id primitive.ObjectID, doc T, action string, restoredFrom *int) error {
+	number, err := h.Repo.NextNumber(ctx, h.ContentType, id)
+	if err != nil {
+		return err
+	}
+	return h.create(ctx, id, number, doc, action, restoredFrom, c.GetString("uid"), c.GetString("email"))
+}
+
+// recordWritten records the revision of a write that is already stored.
+// The write stands whether or not its revision can be recorded, and failing
+// the request would only make the client repeat it, so a failure is logged
+// instead. The next revision of the document has its latest state again.
+func (h *History[T]) recordWritten(ctx context.Context, c *gin.Context, id primitive.ObjectID, doc T, action string, restoredFrom *int) {
+	if err := h.Record(ctx, c, id, doc, action, restoredFrom)
+	err != nil {
+		log.Printf("revision history: recording the %s revision of %s %s failed: %v", action, h.ContentType, id.Hex(), err)
+	}
+}
+
+// Baseline records the current state of a document that has no history
+// yet, which it has when it was created before revisions were kept. It
+// runs before an update so that the first edit can still be rolled back.
+func (h *History[T]) Baseline(ctx context.Context, id primitive.ObjectID, load func() (T, error)) error {
+	if _, err := h.Repo.Latest(ctx, h.ContentType, id)
+	!errors.Is(err, repository.ErrNotFound) {
+		return err
+	}
+
+	doc, err := load()
+	if err != nil {
+		return err
+	}
+	return h.create(ctx, id, 1, doc, RevisionBaseline, nil, "", "")
+}
+
+func (h *History[T]) create(ctx context.Context, id primitive.ObjectID, number int, doc T, action string, restoredFrom *int, author string, authorEmail string) error {
+	fields, err := helper.Snapshot(doc)
+	if err != nil {
+		return err
+	}
+
+	_, err = h.Repo.Create(ctx, model.Revision{
+		Id: primitive.NewObjectID(),
+		ContentType: h.ContentType,
+		DocumentId: id,
+		Number: number,
+		Action: action,
+		RestoredFrom: restoredFrom,
+		Snapshot: fields,
+		Author: author,
+		AuthorEmail: authorEmail,
+		CreatedAt: time.Now().UTC().Truncate(time.Millisecond),
+	})
+	return err
+}
+
+// revision loads revision :number of the document in the URL.
+func (h *History[T]) revision(ctx context.Context, c *gin.Context, id primitive.ObjectID, param string) (model.Revision, bool) {
+	number, err := strconv.Atoi(c.Param(param))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"Status" : 400,
+			"Message" : "Invalid revision number!",
+		})
+		return model.Revision{}, false
+	}
+
+	revision, err := h.Repo.FindByNumber(ctx, h.ContentType, id, number)
+	if err != nil {
+		respondError(c, statusOf(err), err)
+		return revision, false
+	}
+	return revision, true
+}
+
+// registerHistory adds the revision routes under idPath, the path of a
+// single document of the resource.
+func (r *Resource[T]) registerHistory(routes gin.IRoutes, idPath string, read gin.HandlerFunc, write gin.HandlerFunc) {
+	routes.GET(idPath + "/revisions", read, r.ListRevisions)
+	routes.GET(idPath + "/revisions/diff", read, r.DiffRevisions)
+	routes.GET(idPath + "/revisions/:number", read, r.GetRevision)
+	routes.POST(idPath + "/revisions/:number/restore", write, r.RestoreRevision)
+}
+
+// ListRevisions lists the revisions of a document, newest first unless
+// sorted otherwise.
+func (r *Resource[T]) ListRevisions(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	objId, ok := r.id(c)
+	if !ok {
+		return
+	}
+
+	lq, err := parseListQuery(c, revisionFields, []repository.Sort{{Field: "number", Desc: true}})
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	lq.Filter["content_type"] = r.History.ContentType
+	lq.Filter["document_id"] = objId
+
+	listDocs(ctx, c, lq, r.History.Repo.List)
+}
+
+func (r *Resource[T]) GetRevision(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	objId, ok := r.id(c)
+	if !ok {
+		return
+	}
+
+	revision, ok := r.History.revision(ctx, c, objId, "number")
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"Status" : 200,
+		"Message" : "Data fetched successfully!",
+		"Data" : revision,
+	})
+}
+
+// DiffRevisions compares revision ?from= with revision ?to=, which defaults
+// to the newest revision.
+func (r *Resource[T]) DiffRevisions(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	objId, ok := r.id(c)
+	if !ok {
+		return
+	}
+
+	numbers := map[string]int{}
+	for _, param := range []string{"from", "to"} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, fmt.Errorf("invalid revision number %q", value))
+			return
+		}
+		numbers[param] = number
+	}
+	if _, ok := numbers["from"]; !ok {
+		respondError(c, http.StatusBadRequest, fmt.Errorf("query parameter from is required"))
+		return
+	}
+
+	from, err := r.History.Repo.FindByNumber(ctx, r.History.ContentType, objId, numbers["from"])
+	if err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+	var to model.Revision
+	if number, ok := numbers["to"]; ok {
+		to, err = r.History.Repo.FindByNumber(ctx, r.History.ContentType, objId, number)
+	} else {
+		to, err = r.History.Repo.Latest(ctx, r.History.ContentType, objId)
+	}
+	if err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"Status" : 200,
+		"Message" : "Data fetched successfully!",
+		"Data" : gin.H{
+			"from" : from.Number,
+			"to" : to.Number,
+			"changes" : helper.Diff(from.Snapshot, to.Snapshot),
+		},
+	})
+}
+
+// RestoreRevision makes an old revision the current version of the
+// document again. The restore is itself recorded as a new revision, so
+// nothing in the history is lost.
+func (r *Resource[T]) RestoreRevision(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	objId, ok := r.id(c)
+	if !ok {
+		return
+	}
+
+	revision, ok := r.History.revision(ctx, c, objId, "number")
+	if !ok {
+		return
+	}
+
+	raw, err := json.Marshal(revision.Snapshot)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	var input T
+	if err := json.Unmarshal(raw, &input)
+	err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	stored, ok := r.find(ctx, c, objId)
+	if !ok {
+		return
+	}
+
+	doc := r.Map(input, objId)
+	if !r.workflow(c, &doc, &stored) || !r.beforeSave(ctx, c, &doc) {
+		return
+	}
+
+	restored, err := r.Repo.Update(ctx, doc)
+	if err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+
+	number := revision.Number
+	r.History.recordWritten(ctx, c, objId, restored, RevisionRestored, &number)
+
+	c.JSON(http.StatusOK, gin.H{
+		"Status" : 200,
+		"Message" : "Data restored successfully!",
+		"Data" : restored,
+	})
+}
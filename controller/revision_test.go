@@ -0,0 +1,69 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"golang_cms/model"
+	"golang_cms/repository"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// failingRevisions refuses to store revisions while failing is set.
+type failingRevisions struct {
+	repository.RevisionRepository
+	failing bool
+}
+
+func (r *failingRevisions) Create(ctx context.Context, revision model.Revision) (model.Revision, error) {
+	if r.failing {
+		return model.Revision{}, errors.New("revision store unavailable")
+	}
+	return r.RevisionRepository.Create(ctx, revision)
+}
+
+func TestRevisionFailureDoesNotFailWrite(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repos := repository.NewMemoryRepositories()
+	revisions := &failingRevisions{RevisionRepository: repos.Revision}
+	router := gin.New()
+	NewDescResource(repos.Desc, revisions).Register(router, "/desc", "/descs", allowAll)
+
+	code, out := serve(t, router, "POST", "/desc", `{"title":"a","desc":"first"}`)
+	if code != http.StatusCreated {
+		t.Fatalf("create = %d %v", code, out)
+	}
+	id := out["Data"].(map[string]interface{})["InsertedID"].(string)
+
+	revisions.failing = true
+	if code, out := serve(t, router, "PUT", "/desc/"+id, `{"title":"a","desc":"second"}`); code != http.StatusOK {
+		t.Fatalf("update with failing history = %d %v", code, out)
+	}
+	objId, _ := primitive.ObjectIDFromHex(id)
+	stored, err := repos.Desc.FindByID(context.Background(), objId)
+	if err != nil || stored.Desc != "second" {
+		t.Fatalf("stored = %+v, %v", stored, err)
+	}
+
+	revisions.failing = false
+	if code, out := serve(t, router, "PUT", "/desc/"+id, `{"title":"a","desc":"third"}`); code != http.StatusOK {
+		t.Fatalf("update = %d %v", code, out)
+	}
+	latest, err := repos.Revision.Latest(context.Background(), "desc", objId)
+	if err != nil || latest.Number != 2 || latest.Snapshot["desc"] != "third" {
+		t.Fatalf("latest revision = %+v, %v", latest, err)
+	}
+
+	revisions.failing = true
+	if code, out := serve(t, router, "POST", "/desc/"+id+"/revisions/1/restore", ""); code != http.StatusOK {
+		t.Fatalf("restore with failing history = %d %v", code, out)
+	}
+	stored, _ = repos.Desc.FindByID(context.Background(), objId)
+	if stored.Desc != "first" {
+		t.Fatalf("restored = %+v", stored)
+	}
+}
@@ -0,0 +1,372 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"golang_cms/model"
+	"golang_cms/rbac"
+	"golang_cms/repository"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var validasiRole = validator.New()
+
+// ErrLastAdmin is returned when the only admin would lose their role.
+var ErrLastAdmin = errors.New("the last admin cannot be given another role")
+
+// ErrNotHeld is returned when a role with permissions the caller does not
+// hold is created, changed or assigned.
+var ErrNotHeld = errors.New("you cannot grant permissions you do not hold")
+
+// ErrAdminOnly is returned when someone other than an admin grants every
+// permission.
+var ErrAdminOnly = errors.New("only admins can grant every permission")
+
+// roleName is what a custom role may be called: lowercase letters, digits,
+// dashes and underscores.
+var roleName = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+
+// builtinOrder is the order the built-in roles are listed in, from the most
+// to the least access.
+var builtinOrder = []string{rbac.RoleAdmin, rbac.RoleEditor, rbac.RoleAuthor, rbac.RoleViewer}
+
+// RoleInput is the body of a custom role; its name is fixed once created,
+// since users refer to their role by name.
+type RoleInput struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions" validate:"required,min=1"`
+}
+
+// RoleAssignment is the body of a role change of a user.
+type RoleAssignment struct {
+	Role string `json:"role" validate:"required"`
+}
+
+// RoleController manages the custom roles and which role each user has.
+type RoleController struct {
+	roles repository.RoleRepository
+	users repository.UserRepository
+	authz *rbac.Authorizer
+}
+
+func NewRoleController(roles repository.RoleRepository, users repository.UserRepository, authz *rbac.Authorizer) *RoleController {
+	return &RoleController{roles: roles, users: users, authz: authz}
+}
+
+// userTypeOf is the user type that goes with role, kept so that the token
+// claims of older clients still tell admins apart.
+func userTypeOf(role string) *string {
+	userType := "USER"
+	if role == rbac.RoleAdmin {
+		userType = "ADMIN"
+	}
+	return &userType
+}
+
+// bind reads and validates a role body, including its permissions.
+func (rc *RoleController) bind(c *gin.Context) (RoleInput, bool) {
+	var input RoleInput
+	if err := c.ShouldBindJSON(&input)
+	err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return input, false
+	}
+	if validationErr := validasiRole.Struct(&input)
+	validationErr != nil {
+		respondError(c, http.StatusBadRequest, validationErr)
+		return input, false
+	}
+	for _, permission := range input.Permissions {
+		if err := rbac.Validate(permission)
+		err != nil {
+			respondError(c, statusOf(err), err)
+			return input, false
+		}
+	}
+	return input, true
+}
+
+// grantable checks that the caller of the request may hand out every set
+// of permissions: nobody can grant more than their own role does, and only
+// admins can grant "*". API tokens carry their permissions spelled out, so
+// even those of admins cannot grant "*".
+func grantable(c *gin.Context, sets ...[]string) error {
+	granted, _ := c.Get("permissions")
+	held, _ := granted.([]string)
+	for _, permissions := range sets {
+		if isAdmin(permissions) && !isAdmin(held) {
+			return ErrAdminOnly
+		}
+		if !rbac.Covers(held, permissions) {
+			return ErrNotHeld
+		}
+	}
+	return nil
+}
+
+func isAdmin(permissions []string) bool {
+	for _, permission := range permissions {
+		if permission == rbac.Wildcard {
+			return true
+		}
+	}
+	return false
+}
+
+// custom loads the custom role in the URL; built-in roles cannot be changed.
+func (rc *RoleController) custom(ctx context.Context, c *gin.Context) (model.Role, bool) {
+	name := c.Param("role")
+	if rbac.IsBuiltin(name) {
+		respondError(c, statusOf(rbac.ErrBuiltinRole), rbac.ErrBuiltinRole)
+		return model.Role{}, false
+	}
+	role, err := rc.roles.FindByName(ctx, name)
+	if err != nil {
+		respondError(c, statusOf(err), err)
+		return role, false
+	}
+	return role, true
+}
+
+// GetPermissions lists every permission a role can grant.
+func (rc *RoleController) GetPermissions(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"Status" : 200,
+		"Message" : "Data fetched successfully!",
+		"Data" : rbac.Permissions(),
+	})
+}
+
+// GetRoles lists the built-in roles followed by the custom ones.
+func (rc *RoleController) GetRoles(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	roles := []model.Role{}
+	for _, name := range builtinOrder {
+		role, _ := rc.authz.Role(ctx, name)
+		roles = append(roles, role)
+	}
+	custom, _, err := rc.roles.List(ctx, repository.Query{Sort: []repository.Sort{{Field: "name"}}})
+	if err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+	roles = append(roles, custom...)
+
+	c.JSON(http.StatusOK, gin.H{
+		"Status" : 200,
+		"Message" : "Data fetched successfully!",
+		"Data" : roles,
+	})
+}
+
+func (rc *RoleController) GetRole(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	role, err := rc.authz.Role(ctx, c.Param("role"))
+	if errors.Is(err, rbac.ErrUnknownRole) {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"Status" : 200,
+		"Message" : "Data fetched successfully!",
+		"Data" : role,
+	})
+}
+
+func (rc *RoleController) CreateRole(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	input, ok := rc.bind(c)
+	if !ok {
+		return
+	}
+	if !roleName.MatchString(input.Name) || len(input.Name) > 32 {
+		respondError(c, http.StatusBadRequest, fmt.Errorf("invalid role name %q, use up to 32 lowercase letters, digits, dashes and underscores", input.Name))
+		return
+	}
+	if rbac.IsBuiltin(input.Name) {
+		respondError(c, statusOf(rbac.ErrBuiltinRole), rbac.ErrBuiltinRole)
+		return
+	}
+	if err := grantable(c, input.Permissions)
+	err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	role, err := rc.roles.Create(ctx, model.Role{
+		Id: primitive.NewObjectID(),
+		Name: input.Name,
+		Description: input.Description,
+		Permissions: input.Permissions,
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+	if err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"Status" : 201,
+		"Message" : "Data created successfully!",
+		"Data" : role,
+	})
+}
+
+// UpdateRole changes the description and permissions of a custom role.
+// Users with the role get the new permissions on their next request. The
+// caller must hold the permissions of the role both before and after.
+func (rc *RoleController) UpdateRole(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	role, ok := rc.custom(ctx, c)
+	if !ok {
+		return
+	}
+	input, ok := rc.bind(c)
+	if !ok {
+		return
+	}
+	if err := grantable(c, role.Permissions, input.Permissions)
+	err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+
+	role.Description = input.Description
+	role.Permissions = input.Permissions
+	role.UpdatedAt = time.Now().UTC().Truncate(time.Millisecond)
+	updated, err := rc.roles.Update(ctx, role)
+	if err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"Status" : 200,
+		"Message" : "Data updated successfully!",
+		"Data" : updated,
+	})
+}
+
+// DeleteRole deletes a custom role no user has anymore.
+func (rc *RoleController) DeleteRole(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	role, ok := rc.custom(ctx, c)
+	if !ok {
+		return
+	}
+
+	count, err := rc.users.CountByRole(ctx, role.Name)
+	if err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+	if count > 0 {
+		err := fmt.Errorf("%w: %d users have role %q", ErrInUse, count, role.Name)
+		respondError(c, statusOf(err), err)
+		return
+	}
+
+	if err := rc.roles.Delete(ctx, role.Id)
+	err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"Status" : 200,
+		"Message" : "Data deleted successfully!",
+	})
+}
+
+// AssignRole gives the user in the URL another role. The caller must hold
+// the permissions of both the old and the new role, so that nobody can
+// raise their own access or take it from those who have more.
+func (rc *RoleController) AssignRole(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	var input RoleAssignment
+	if err := c.ShouldBindJSON(&input)
+	err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if validationErr := validasiRole.Struct(&input)
+	validationErr != nil {
+		respondError(c, http.StatusBadRequest, validationErr)
+		return
+	}
+	role, err := rc.authz.Role(ctx, input.Role)
+	if err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+
+	user, err := rc.users.FindByUserID(ctx, c.Param("user_id"))
+	if err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+	current, err := rc.authz.Role(ctx, rbac.RoleOf(user))
+	if err != nil && !errors.Is(err, rbac.ErrUnknownRole) {
+		respondError(c, statusOf(err), err)
+		return
+	}
+	if err := grantable(c, current.Permissions, role.Permissions)
+	err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+
+	if rbac.RoleOf(user) == rbac.RoleAdmin && input.Role != rbac.RoleAdmin {
+		admins, err := rc.users.CountByRole(ctx, rbac.RoleAdmin)
+		if err != nil {
+			respondError(c, statusOf(err), err)
+			return
+		}
+		if admins <= 1 {
+			respondError(c, statusOf(ErrLastAdmin), ErrLastAdmin)
+			return
+		}
+	}
+
+	user.Role = input.Role
+	user.User_type = userTypeOf(input.Role)
+	user.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	updated, err := rc.users.Update(ctx, user)
+	if err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"Status" : 200,
+		"Message" : "Data updated successfully!",
+		"Data" : updated,
+	})
+}
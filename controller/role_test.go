@@ -0,0 +1,140 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"golang_cms/model"
+	"golang_cms/rbac"
+	"golang_cms/repository"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// roleRouter serves the role routes to callers with the role named by the
+// X-Role header, in place of the authentication and permission middleware.
+func roleRouter(t *testing.T) (*gin.Engine, repository.Repositories) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	repos := repository.NewMemoryRepositories()
+	authz := rbac.NewAuthorizer(repos.User, repos.Role)
+	rc := NewRoleController(repos.Role, repos.User, authz)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		role, err := authz.Role(c, c.GetHeader("X-Role"))
+		if err != nil {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		c.Set("permissions", role.Permissions)
+		c.Next()
+	})
+	router.POST("/roles", rc.CreateRole)
+	router.PUT("/roles/:role", rc.UpdateRole)
+	router.PUT("/users/:user_id/role", rc.AssignRole)
+	return router, repos
+}
+
+func addUser(t *testing.T, repos repository.Repositories, uid string, role string) {
+	t.Helper()
+	email := uid + "@example.com"
+	_, err := repos.User.Create(context.Background(), model.User{ID: primitive.NewObjectID(), User_id: uid, Email: &email, Phone: &email, Role: role})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func addRole(t *testing.T, repos repository.Repositories, name string, permissions ...string) {
+	t.Helper()
+	_, err := repos.Role.Create(context.Background(), model.Role{Id: primitive.NewObjectID(), Name: name, Permissions: permissions})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func serveAs(t *testing.T, router http.Handler, role, method, path, body string) int {
+	t.Helper()
+	req := newJSONRequest(method, path, body)
+	req.Header.Set("X-Role", role)
+	return serveRequest(router, req).Code
+}
+
+func TestCreateRoleCannotGrantMoreThanCaller(t *testing.T) {
+	router, repos := roleRouter(t)
+	addRole(t, repos, "manager", "banner:*", "role:*")
+
+	tests := []struct {
+		caller      string
+		permissions string
+		want        int
+	}{
+		{"manager", `["banner:read","banner:publish"]`, http.StatusCreated},
+		{"manager", `["banner:*"]`, http.StatusCreated},
+		{"manager", `["meta:read"]`, http.StatusForbidden},
+		{"manager", `["role:write","user:write"]`, http.StatusForbidden},
+		{"manager", `["*"]`, http.StatusForbidden},
+		{"admin", `["*"]`, http.StatusCreated},
+	}
+	for i, tt := range tests {
+		body := `{"name":"r` + string(rune('a'+i)) + `","permissions":` + tt.permissions + `}`
+		if got := serveAs(t, router, tt.caller, "POST", "/roles", body); got != tt.want {
+			t.Errorf("%s creating %s = %d, want %d", tt.caller, tt.permissions, got, tt.want)
+		}
+	}
+}
+
+func TestUpdateRoleCannotGrantMoreThanCaller(t *testing.T) {
+	router, repos := roleRouter(t)
+	addRole(t, repos, "manager", "banner:*", "role:*")
+	addRole(t, repos, "writer", "banner:write")
+	addRole(t, repos, "auditor", "audit:read")
+
+	if got := serveAs(t, router, "manager", "PUT", "/roles/writer", `{"permissions":["banner:read","banner:write"]}`); got != http.StatusOK {
+		t.Errorf("narrowing a held role = %d", got)
+	}
+	if got := serveAs(t, router, "manager", "PUT", "/roles/writer", `{"permissions":["banner:write","user:write"]}`); got != http.StatusForbidden {
+		t.Errorf("adding a permission not held = %d", got)
+	}
+	if got := serveAs(t, router, "manager", "PUT", "/roles/auditor", `{"permissions":["banner:read"]}`); got != http.StatusForbidden {
+		t.Errorf("changing a role with permissions not held = %d", got)
+	}
+	role, _ := repos.Role.FindByName(context.Background(), "auditor")
+	if len(role.Permissions) != 1 || role.Permissions[0] != "audit:read" {
+		t.Errorf("auditor = %v", role.Permissions)
+	}
+}
+
+func TestAssignRoleCannotGrantMoreThanCaller(t *testing.T) {
+	router, repos := roleRouter(t)
+	addRole(t, repos, "manager", "banner:*", "meta:*", "role:*")
+	addUser(t, repos, "self", "manager")
+	addUser(t, repos, "viewer", rbac.RoleViewer)
+	addUser(t, repos, "boss", rbac.RoleAdmin)
+	addUser(t, repos, "other-boss", rbac.RoleAdmin)
+
+	tests := []struct {
+		caller, user, role string
+		want               int
+	}{
+		{"manager", "self", rbac.RoleAdmin, http.StatusForbidden},
+		{"manager", "self", rbac.RoleEditor, http.StatusForbidden},
+		{"manager", "boss", rbac.RoleViewer, http.StatusForbidden},
+		{"manager", "viewer", "manager", http.StatusForbidden},
+		{"admin", "viewer", "manager", http.StatusOK},
+		{"admin", "boss", rbac.RoleViewer, http.StatusOK},
+		{"admin", "other-boss", rbac.RoleViewer, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		got := serveAs(t, router, tt.caller, "PUT", "/users/"+tt.user+"/role", `{"role":"`+tt.role+`"}`)
+		if got != tt.want {
+			t.Errorf("%s giving %s the role %s = %d, want %d", tt.caller, tt.user, tt.role, got, tt.want)
+		}
+	}
+	self, _ := repos.User.FindByUserID(context.Background(), "self")
+	if self.Role != "manager" {
+		t.Errorf("self has role %q", self.Role)
+	}
+}
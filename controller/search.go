@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"golang_cms/rbac"
+	"golang_cms/search"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SearchController serves full-text search over every content type.
+type SearchController struct {
+	index search.Index
+}
+
+func NewSearchController(index search.Index) *SearchController {
+	return &SearchController{index: index}
+}
+
+// Search answers /search?q=...&type=banner,meta&limit=20 with the best
+// matches first, each with a snippet of the text it matched in. Only the
+// types the user may read are searched.
+func (sc *SearchController) Search(c *gin.Context) {
+	sc.search(c, false)
+}
+
+// PublicSearch is Search over published content only, open to anyone.
+func (sc *SearchController) PublicSearch(c *gin.Context) {
+	sc.search(c, true)
+}
+
+// readPermission is the permission needed to search typ. Sub kategori are
+// read along with their kategori.
+func readPermission(typ string) string {
+	if typ == search.ChildKategori.Type {
+		typ = search.Kategori.Type
+	}
+	return rbac.Permission(typ, rbac.ActionRead)
+}
+
+func (sc *SearchController) search(c *gin.Context, published bool) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	q := search.Query{Text: strings.TrimSpace(c.Query("q")), Limit: search.DefaultLimit, Published: published}
+	if q.Text == "" {
+		respondError(c, http.StatusBadRequest, fmt.Errorf("query parameter q is required"))
+		return
+	}
+
+	if value := c.Query("type")
+	value != "" {
+		for _, typ := range strings.Split(value, ",") {
+			if _, ok := search.SourceOf(typ)
+			!ok {
+				respondError(c, http.StatusBadRequest, fmt.Errorf("unknown type %q", typ))
+				return
+			}
+			q.Types = append(q.Types, typ)
+		}
+	}
+
+	if value := c.Query("limit")
+	value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > search.MaxLimit {
+			respondError(c, http.StatusBadRequest, fmt.Errorf("invalid limit %q, must be between 1 and %d", value, search.MaxLimit))
+			return
+		}
+		q.Limit = limit
+	}
+
+	if !published {
+		if len(q.Types) == 0 {
+			for _, source := range search.Sources {
+				if allowed(c, readPermission(source.Type)) {
+					q.Types = append(q.Types, source.Type)
+				}
+			}
+			if len(q.Types) == 0 {
+				respondError(c, http.StatusForbidden, fmt.Errorf("not allowed to read any content"))
+				return
+			}
+		}
+		for _, typ := range q.Types {
+			if !allowed(c, readPermission(typ)) {
+				respondError(c, http.StatusForbidden, fmt.Errorf("not allowed to search %q", typ))
+				return
+			}
+		}
+	}
+
+	hits, err := sc.index.Search(ctx, q)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"Status" : 200,
+		"Message" : "Success",
+		"Data" : hits,
+	})
+}
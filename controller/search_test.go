@@ -0,0 +1,42 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"golang_cms/search"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSearch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	index := search.NewMemoryIndex()
+	doc := search.Document{Type: "desc", Id: "1", Fields: []search.Field{{Name: "title", Text: "Red shoes"}}}
+	if err := index.Put(context.Background(), doc); err != nil {
+		t.Fatal(err)
+	}
+	router := gin.New()
+	sc := NewSearchController(index)
+	router.GET("/search", allowAll(), sc.Search)
+	router.GET("/anonymous/search", sc.Search)
+
+	code, out := serve(t, router, "GET", "/search?q=red&type=desc,banner", "")
+	hits, _ := out["Data"].([]interface{})
+	if code != http.StatusOK || len(hits) != 1 {
+		t.Fatalf("search = %d %v", code, out)
+	}
+	if snippet := hits[0].(map[string]interface{})["snippet"]; snippet != "<mark>Red</mark> shoes" {
+		t.Errorf("snippet = %v", snippet)
+	}
+
+	for _, query := range []string{"", "?q=", "?q=red&type=nope", "?q=red&limit=0", "?q=red&limit=1000"} {
+		if code, out := serve(t, router, "GET", "/search"+query, ""); code != http.StatusBadRequest {
+			t.Errorf("GET /search%s = %d %v", query, code, out)
+		}
+	}
+	if code, out := serve(t, router, "GET", "/anonymous/search?q=red", ""); code != http.StatusForbidden {
+		t.Errorf("search without read permissions = %d %v", code, out)
+	}
+}
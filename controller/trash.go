@@ -0,0 +1,171 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"golang_cms/model"
+	"golang_cms/repository"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrNotTrashed is returned when a document that is not in the trash is
+// restored or purged.
+var ErrNotTrashed = errors.New("data is not in the trash")
+
+// trashFields are the extra fields the trash of a content type can be
+// filtered and sorted by.
+var trashFields = ListFields{
+	"deleted_at" : {Field: "deleted_at", Kind: TimeField},
+	"deleted_by" : {Field: "deleted_by", Kind: StringField},
+}
+
+// trashOf returns the trash marker of doc, which must be a pointer, when
+// its type is deleted to the trash first.
+func trashOf(doc interface{}) (*model.Trash, bool) {
+	trashable, ok := doc.(model.Trashable)
+	if !ok {
+		return nil, false
+	}
+	return trashable.TrashState(), true
+}
+
+// isTrashed reports whether doc, a pointer, is in the trash.
+func isTrashed(doc interface{}) bool {
+	trash, ok := trashOf(doc)
+	return ok && trash.DeletedAt != nil
+}
+
+// registerTrash adds the trash routes: GET /trash<listPath> for the trashed
+// documents, POST idPath/restore and DELETE idPath/purge for one of them.
+func (r *Resource[T]) registerTrash(routes gin.IRoutes, idPath string, listPath string, read gin.HandlerFunc, write gin.HandlerFunc) {
+	routes.GET("/trash" + listPath, read, r.ListTrash)
+	routes.POST(idPath + "/restore", write, r.RestoreTrash)
+	routes.DELETE(idPath + "/purge", write, r.Purge)
+}
+
+// trashed loads the document in the URL, which must be in the trash.
+func (r *Resource[T]) trashed(ctx context.Context, c *gin.Context) (T, bool) {
+	var doc T
+	objId, ok := r.id(c)
+	if !ok {
+		return doc, false
+	}
+
+	doc, err := r.Repo.FindByID(ctx, objId)
+	if err != nil {
+		respondError(c, statusOf(err), err)
+		return doc, false
+	}
+	if !isTrashed(&doc) {
+		respondError(c, statusOf(ErrNotTrashed), ErrNotTrashed)
+		return doc, false
+	}
+	return doc, true
+}
+
+// moveToTrash marks a document as deleted by the user of the request
+// instead of removing it.
+func (r *Resource[T]) moveToTrash(ctx context.Context, c *gin.Context, objId primitive.ObjectID) bool {
+	doc, ok := r.find(ctx, c, objId)
+	if !ok {
+		return false
+	}
+
+	if r.BeforeDelete != nil {
+		if err := r.BeforeDelete(ctx, objId)
+		err != nil {
+			respondError(c, statusOf(err), err)
+			return false
+		}
+	}
+
+	trash, _ := trashOf(&doc)
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	trash.DeletedAt = &now
+	trash.DeletedBy = c.GetString("uid")
+	if _, err := r.Repo.Update(ctx, doc)
+	err != nil {
+		respondError(c, statusOf(err), err)
+		return false
+	}
+	return true
+}
+
+// ListTrash lists the trashed documents, most recently deleted first unless
+// sorted otherwise.
+func (r *Resource[T]) ListTrash(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	fields := ListFields{}
+	for name, field := range r.Fields {
+		fields[name] = field
+	}
+	for name, field := range trashFields {
+		fields[name] = field
+	}
+
+	lq, err := parseListQuery(c, fields, []repository.Sort{{Field: "deleted_at", Desc: true}})
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	conds, _ := lq.Filter["deleted_at"].([]repository.Cond)
+	lq.Filter["deleted_at"] = append(conds, repository.Ne(nil))
+
+	listDocs(ctx, c, lq, r.Repo.List)
+}
+
+// RestoreTrash takes a document out of the trash, as it was when deleted.
+func (r *Resource[T]) RestoreTrash(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	doc, ok := r.trashed(ctx, c)
+	if !ok {
+		return
+	}
+
+	trash, _ := trashOf(&doc)
+	*trash = model.Trash{}
+	restored, err := r.Repo.Update(ctx, doc)
+	if err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"Status" : 200,
+		"Message" : "Data restored successfully!",
+		"Data" : restored,
+	})
+}
+
+// Purge deletes a trashed document for good.
+func (r *Resource[T]) Purge(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	objId, ok := r.id(c)
+	if !ok {
+		return
+	}
+	if _, ok := r.trashed(ctx, c); !ok {
+		return
+	}
+
+	if err := r.Repo.Delete(ctx, objId)
+	err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"Status" : 200,
+		"Message" : "Data deleted permanently!",
+	})
+}
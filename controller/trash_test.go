@@ -0,0 +1,75 @@
+package controller
+
+import (
+	"net/http"
+	"testing"
+
+	"golang_cms/rbac"
+	"golang_cms/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+func trashRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	repos := repository.NewMemoryRepositories()
+	router := gin.New()
+	NewDescResource(repos.Desc, repos.Revision).Register(router, "/desc", "/descs", allowAll)
+	return router
+}
+
+func TestTrashRestore(t *testing.T) {
+	router := trashRouter(t)
+	id := createDesc(t, router, rbac.RoleEditor, `{"title":"a","desc":"b"}`)
+	createDesc(t, router, rbac.RoleEditor, `{"title":"c","desc":"d"}`)
+
+	if code, out := serve(t, router, "POST", "/desc/"+id+"/restore", ""); code != http.StatusConflict {
+		t.Errorf("restore outside the trash = %d %v", code, out)
+	}
+	if code, out := serve(t, router, "DELETE", "/desc/"+id, ""); code != http.StatusOK {
+		t.Fatalf("delete = %d %v", code, out)
+	}
+
+	if code, _ := serve(t, router, "GET", "/desc/"+id, ""); code != http.StatusNotFound {
+		t.Errorf("get trashed = %d", code)
+	}
+	if code, _ := serve(t, router, "PUT", "/desc/"+id, `{"title":"x","desc":"y"}`); code != http.StatusNotFound {
+		t.Errorf("update trashed = %d", code)
+	}
+	if _, out := serve(t, router, "GET", "/descs", ""); len(out["Data"].([]interface{})) != 1 {
+		t.Errorf("list with a trashed desc = %v", out["Data"])
+	}
+	_, out := serve(t, router, "GET", "/trash/descs", "")
+	trashed, _ := out["Data"].([]interface{})
+	if len(trashed) != 1 || trashed[0].(map[string]interface{})["id"] != id || trashed[0].(map[string]interface{})["deleted_at"] == nil {
+		t.Errorf("trash = %v", out["Data"])
+	}
+
+	if code, out := serve(t, router, "POST", "/desc/"+id+"/restore", ""); code != http.StatusOK {
+		t.Fatalf("restore = %d %v", code, out)
+	}
+	code, out := serve(t, router, "GET", "/desc/"+id, "")
+	if doc := out["Data"].(map[string]interface{}); code != http.StatusOK || doc["title"] != "a" || doc["deleted_at"] != nil {
+		t.Errorf("restored = %d %v", code, out)
+	}
+}
+
+func TestTrashPurge(t *testing.T) {
+	router := trashRouter(t)
+	id := createDesc(t, router, rbac.RoleEditor, `{"title":"a","desc":"b"}`)
+
+	if code, out := serve(t, router, "DELETE", "/desc/"+id+"/purge", ""); code != http.StatusConflict {
+		t.Errorf("purge outside the trash = %d %v", code, out)
+	}
+	serve(t, router, "DELETE", "/desc/"+id, "")
+	if code, out := serve(t, router, "DELETE", "/desc/"+id+"/purge", ""); code != http.StatusOK {
+		t.Fatalf("purge = %d %v", code, out)
+	}
+	if code, _ := serve(t, router, "POST", "/desc/"+id+"/restore", ""); code != http.StatusNotFound {
+		t.Errorf("restore after purge = %d", code)
+	}
+	if _, out := serve(t, router, "GET", "/trash/descs", ""); len(out["Data"].([]interface{})) != 0 {
+		t.Errorf("trash after purge = %v", out["Data"])
+	}
+}
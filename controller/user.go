@@ -2,10 +2,12 @@ package controller
 
 import (
 	"context"
-	"fmt"
-	"golang_cms/config"
+	"errors"
 	"golang_cms/helper"
 	"golang_cms/model"
+	"golang_cms/rbac"
+	"golang_cms/repository"
+	"golang_cms/session"
 	"log"
 	"net/http"
 	"strconv"
@@ -13,15 +15,36 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 	"golang.org/x/crypto/bcrypt"
 )
 
-var collectionUser *mongo.Collection = config.GetCollection(config.DB, "User")
 var validasiUser = validator.New()
 
+// userFields are the fields the user list can be filtered and sorted by.
+// Passwords and tokens are deliberately left out.
+var userFields = ListFields{
+	"user_id" : {Field: "user_id", Kind: StringField},
+	"first_name" : {Field: "first_name", Kind: StringField},
+	"last_name" : {Field: "last_name", Kind: StringField},
+	"email" : {Field: "email", Kind: StringField},
+	"phone" : {Field: "phone", Kind: StringField},
+	"user_type" : {Field: "user_type", Kind: StringField},
+	"role" : {Field: "role", Kind: StringField},
+	"created_at" : {Field: "created_at", Kind: TimeField},
+	"updated_at" : {Field: "updated_at", Kind: TimeField},
+}
+
+type UserController struct {
+	repo     repository.UserRepository
+	authz    *rbac.Authorizer
+	sessions *session.Manager
+}
+
+func NewUserController(repo repository.UserRepository, authz *rbac.Authorizer, sessions *session.Manager) *UserController {
+	return &UserController{repo: repo, authz: authz, sessions: sessions}
+}
+
 func HashPassword(password string) string {
 	bytes, err := bcrypt.GenerateFromPassword([] byte(password), 14)
 	if err != nil {
@@ -42,11 +65,12 @@ func VerifyPassword(userPassword string, providedPassword string) (bool, string)
 	}
 
 	return check, message
-} 
+}
 
-func Register(c *gin.Context) {
-	var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+func (uc *UserController) Register(c *gin.Context) {
+	var ctx, cancel = context.WithTimeout(c, 100*time.Second)
 	var user model.User
+	defer cancel()
 
 	if err := c.BindJSON(&user)
 	err != nil {
@@ -60,10 +84,8 @@ func Register(c *gin.Context) {
 		return
 	}
 
-	count, err := collectionUser.CountDocuments(ctx, bson.M{"email" : user.Email})
-	defer cancel()
+	emailCount, err := uc.repo.CountByEmail(ctx, *user.Email)
 	if err != nil {
-		log.Panic(err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error" : "Error occured when checking email"})
 		return
 	}
@@ -71,15 +93,13 @@ func Register(c *gin.Context) {
 	password := HashPassword(*user.Password)
 	user.Password = &password
 
-	count, err = collectionUser.CountDocuments(ctx, bson.M{"phone" : user.Phone})
-	defer cancel()
+	phoneCount, err := uc.repo.CountByPhone(ctx, *user.Phone)
 	if err != nil {
-		log.Panic(err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error" : "Error occured when checking phone number"})
 		return
 	}
 
-	if count > 0 {
+	if emailCount > 0 || phoneCount > 0 {
 		c.JSON(http.StatusBadRequest, gin.H{"error" : "This email or phone number already exist!"})
 		return
 	}
@@ -88,194 +108,185 @@ func Register(c *gin.Context) {
 	user.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 	user.ID = primitive.NewObjectID()
 	user.User_id = user.ID.Hex()
-	token, refreshToken, _ := helper.GenerateAllTokens(*user.Email, *user.First_name, *user.Last_name, *user.User_type, *&user.User_id)
-	user.Token = &token
-	user.Refresh_token = &refreshToken
-
-	resultInsertionNumber, insertErr := collectionUser.InsertOne(ctx, user)
-	if insertErr != nil {
+	//user baru selalu viewer sampai admin memberi role lain; admin pertama dibuat dengan perintah grant-admin
+	user.Role = rbac.RoleViewer
+	user.User_type = userTypeOf(user.Role)
+	//token tidak disimpan di user lagi, login membuat sesi baru
+	user.Token = nil
+	user.Refresh_token = nil
+
+	if _, insertErr := uc.repo.Create(ctx, user)
+	insertErr != nil {
 		message := "Failed to create user account"
+		if errors.Is(insertErr, repository.ErrDuplicate) {
+			message = "This email or phone number already exist!"
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error" : message})
 		return
 	}
 
-	defer cancel()
-
-	c.JSON(http.StatusOK, resultInsertionNumber)
+	c.JSON(http.StatusOK, gin.H{"InsertedID" : user.ID})
 }
 
-func Login(c *gin.Context) {
-	var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-	var user model.User
-	var foundUser model.User
-
-	if err := c.BindJSON(&user)
-	err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error" : err.Error()})
-		return
-	}
-
-	err := collectionUser.FindOne(ctx, bson.M{"email" : user.Email}).Decode(&foundUser)
+func (uc *UserController) GetUsers(c *gin.Context) {
+	var ctx, cancel = context.WithTimeout(c, 100*time.Second)
 	defer cancel()
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error" : "Incorrect email or password!"})
-		return
-	}
-
-	passwordIsValid, message := VerifyPassword(*user.Password, *foundUser.Password)
-	defer cancel()
-	if passwordIsValid != true {
-		c.JSON(http.StatusBadRequest, gin.H{"error" : message})
-		return
-	}
-
-	if foundUser.Email == nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error" : "User not found!"})
-		return
-	}
-
-	token, refreshToken, _ := helper.GenerateAllTokens(*foundUser.Email, *foundUser.First_name, *foundUser.Last_name, *foundUser.User_type, foundUser.User_id)
-
-
-	helper.UpdateAllTokens(token, refreshToken, foundUser.User_id)
-	err = collectionUser.FindOne(ctx, bson.M{"user_id" : foundUser.User_id}).Decode(&foundUser)
-
 
+	lq, err := parseListQuery(c, userFields, nil, "recordPerPage")
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error" : err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusOK, foundUser)
-}
-
-func GetUsers(c *gin.Context) {
-	if err := helper.CheckUserType(c, "ADMIN")
-	err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error" : err.Error()})
 		return
 	}
-
-	var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-
-	recordPerPage, err := strconv.Atoi(c.Query("recordPerPage"))
-	if err != nil || recordPerPage < 1 {
-		recordPerPage = 10
-	}
-
-	page, errs := strconv.Atoi(c.Query("page"))
-	if errs != nil || page < 1 {
-		page = 1
+	//recordPerPage masih diterima sebagai nama lama dari limit
+	if c.Query("limit") == "" {
+		if recordPerPage, err := strconv.ParseInt(c.Query("recordPerPage"), 10, 64)
+		err == nil && recordPerPage > 0 && recordPerPage <= maxPageLimit {
+			lq.Limit = recordPerPage
+			if lq.Page > 0 {
+				lq.Skip = (lq.Page - 1) * lq.Limit
+			}
+		}
 	}
 
-	startIndex := (page - 1) * recordPerPage
-	startIndex, err = strconv.Atoi(c.Query("startIndex"))
-
-	matchStage := bson.D{{"$match", bson.D{{}}}}
-	groupStage := bson.D{{"$group", bson.D{{"_id", bson.D{{"_id", "null"}}}, {"total_count", bson.D{{"$sum", 1}}}, {"data", bson.D{{"$push", "$$ROOT"}}}}}}
-	projectStage := bson.D{
-		{"$project", bson.D{
-			{"_id", 0},
-			{"total_count", 1},
-			{"user_items", bson.D{{"$slice", []interface{}{"$data", startIndex, recordPerPage}}}},
-		}}}
-	
-	result, err := collectionUser.Aggregate(ctx, mongo.Pipeline{
-		matchStage, groupStage, projectStage})
-	
-	defer cancel()
+	page, err := fetchList(ctx, c, lq, uc.repo.List)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error" : "Error occured when listing user items"})
+		return
 	}
 
-	var allUsers []bson.M
-
-	if err = result.All(ctx, &allUsers)
-	err != nil {
-		log.Fatal(err)
-	}
-	c.JSON(http.StatusOK, allUsers[0])
+	c.JSON(http.StatusOK, gin.H{
+		"total_count" : page.total,
+		"user_items" : page.docs,
+		"meta" : listMeta(lq, page),
+		"links" : pageLinks(c, lq, page),
+	})
 }
 
-func GetUser(c *gin.Context) {
+func (uc *UserController) GetUser(c *gin.Context) {
 	userId := c.Param("user_id")
 
-	if err := helper.MatchUserTypeToUid(c, userId)
-	err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error" : err.Error()})
-		return
-	}
-
-	var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-	var user model.User
-
-	err := collectionUser.FindOne(ctx, bson.M{"user_id" : userId}).Decode(&user)
+	var ctx, cancel = context.WithTimeout(c, 100*time.Second)
 	defer cancel()
+
+	user, err := uc.repo.FindByUserID(ctx, userId)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error" : err.Error()})
+		c.JSON(statusOf(err), gin.H{"error" : err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, user)
 }
 
-func GetUserEmail(c *gin.Context) {
+func (uc *UserController) GetUserEmail(c *gin.Context) {
 	userEmail := c.Param("Email")
 
-	if err := helper.MatchUserTypeToUid(c, userEmail)
-	err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error" : err.Error()})
-		return
-	}
-
-	var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-	var user model.User
-
-	err := collectionUser.FindOne(ctx, bson.M{"Email" : user.Email}).Decode(&user)
-	fmt.Println(user)
+	var ctx, cancel = context.WithTimeout(c, 100*time.Second)
 	defer cancel()
 
+	user, err := uc.repo.FindByEmail(ctx, userEmail)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error" : err.Error()})
+		c.JSON(statusOf(err), gin.H{"error" : err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, user)
 }
 
-func UpdateUser(c *gin.Context) {
+func (uc *UserController) UpdateUser(c *gin.Context) {
 	userId := c.Param("user_id")
 
-	if err := helper.MatchUserTypeToUid(c, userId)
+	var ctx, cancel = context.WithTimeout(c, 100*time.Second)
+	defer cancel()
+
+	//token API tidak boleh mengubah akun, termasuk password dan email pemiliknya
+	if c.GetString("api_token") != "" {
+		c.JSON(http.StatusForbidden, gin.H{"error" : ErrApiTokenRequest.Error()})
+		return
+	}
+
+	var input model.User
+	if err := c.BindJSON(&input)
 	err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error" : err.Error()})
 		return
 	}
 
-	var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-
-	var user model.User
-	
-	update := bson.M{"first_name" : user.First_name, "last_name" : user.Last_name, "Password" : user.Password, "Email" : user.Email, "Phone" : user.Phone}
-	result, err := collectionUser.UpdateOne(ctx, bson.M{"user_id" : userId}, bson.M{"$set" : update})
-	defer cancel()
+	if input.Email != nil {
+		if validationErr := validasiUser.Var(*input.Email, "email,required")
+		validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error" : validationErr.Error()})
+			return
+		}
+	}
 
+	user, err := uc.repo.FindByUserID(ctx, userId)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error" : err.Error()})
+		c.JSON(statusOf(err), gin.H{"error" : err.Error()})
 		return
 	}
 
-	var updatedUser model.User
+	//mengubah user lain, termasuk password dan emailnya, butuh semua permission user itu juga
+	if user.User_id != c.GetString("uid") {
+		role, err := uc.authz.Role(ctx, rbac.RoleOf(user))
+		if err != nil && !errors.Is(err, rbac.ErrUnknownRole) {
+			c.JSON(statusOf(err), gin.H{"error" : err.Error()})
+			return
+		}
+		if err := grantable(c, role.Permissions)
+		err != nil {
+			c.JSON(statusOf(err), gin.H{"error" : err.Error()})
+			return
+		}
+	}
 
-	if result.MatchedCount == 1 {
-		err := collectionUser.FindOne(ctx, bson.M{"user_id" : userId}).Decode(&updatedUser)
+	if input.Email != nil && (user.Email == nil || *input.Email != *user.Email) {
+		emailCount, err := uc.repo.CountByEmail(ctx, *input.Email)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error" : "Error occured when checking email"})
+			return
+		}
+		if emailCount > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error" : "This email or phone number already exist!"})
+			return
+		}
+	}
 
+	if input.First_name != nil {
+		user.First_name = input.First_name
+	}
+	if input.Last_name != nil {
+		user.Last_name = input.Last_name
+	}
+	credentialsChanged := input.Password != nil || (input.Email != nil && (user.Email == nil || *input.Email != *user.Email))
+	if input.Password != nil {
+		password := HashPassword(*input.Password)
+		user.Password = &password
+	}
+	if input.Email != nil {
+		user.Email = input.Email
+	}
+	if input.Phone != nil {
+		user.Phone = input.Phone
+	}
+	user.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"Status" : 400,
-				"Message" : err.Error(),
-			})
+	updatedUser, err := uc.repo.Update(ctx, user)
+	if err != nil {
+		c.JSON(statusOf(err), gin.H{"error" : err.Error()})
+		return
+	}
+
+	//password atau email yang berubah mengakhiri semua sesi user, kecuali sesi yang mengubahnya sendiri
+	if credentialsChanged {
+		keep := ""
+		value, _ := c.Get("claims")
+		if claims, ok := value.(*helper.SignedDetails); ok && claims.Uid == updatedUser.User_id {
+			keep = claims.Session
+		}
+		if err := uc.sessions.EndOthers(ctx, updatedUser.User_id, keep)
+		err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error" : err.Error()})
+			return
 		}
 	}
 
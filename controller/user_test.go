@@ -0,0 +1,175 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"golang_cms/helper"
+	"golang_cms/model"
+	"golang_cms/rbac"
+	"golang_cms/repository"
+	"golang_cms/session"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func newUserController(repos repository.Repositories) *UserController {
+	sessions := session.NewManager(repos.Refresh, repos.Revoked, repos.User, time.Hour, 0)
+	return NewUserController(repos.User, rbac.NewAuthorizer(repos.User, repos.Role), sessions)
+}
+
+func TestRegisterNeverGrantsAdmin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repos := repository.NewMemoryRepositories()
+	router := gin.New()
+	router.POST("/users/register", newUserController(repos).Register)
+
+	// even the first user to register is a viewer
+	body := `{"first_name":"Ada","last_name":"Lovelace","password":"correct horse","email":"ada@example.com","phone":"0812","user_type":"ADMIN"}`
+	if code, out := serve(t, router, "POST", "/users/register", body); code != http.StatusOK {
+		t.Fatalf("register = %d %v", code, out)
+	}
+	user, err := repos.User.FindByEmail(context.Background(), "ada@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rbac.RoleOf(user) != rbac.RoleViewer || *user.User_type != "USER" {
+		t.Errorf("registered user has role %q and type %q", user.Role, *user.User_type)
+	}
+}
+
+func TestUpdateUserRejectsApiTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repos := repository.NewMemoryRepositories()
+	email := "ada@example.com"
+	if _, err := repos.User.Create(context.Background(), model.User{ID: primitive.NewObjectID(), User_id: "ada", Email: &email, Phone: &email}); err != nil {
+		t.Fatal(err)
+	}
+	apiToken := func(c *gin.Context) {
+		c.Set("uid", "ada")
+		c.Set("api_token", primitive.NewObjectID().Hex())
+	}
+	router := gin.New()
+	router.PUT("/user/:user_id", apiToken, newUserController(repos).UpdateUser)
+
+	if code, out := serve(t, router, "PUT", "/user/ada", `{"password":"taken over"}`); code != http.StatusForbidden {
+		t.Errorf("update with an API token = %d %v", code, out)
+	}
+	if user, _ := repos.User.FindByUserID(context.Background(), "ada"); user.Password != nil {
+		t.Error("password changed through an API token")
+	}
+}
+
+// updateUserRouter serves PUT /user/:user_id to the user named by the X-Uid
+// header, holding the comma separated permissions of X-Permissions and
+// logged in to the session of X-Session, with a viewer ada, an editor ed and
+// an admin root registered.
+func updateUserRouter(t *testing.T) (*gin.Engine, repository.Repositories) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	repos := repository.NewMemoryRepositories()
+	for uid, role := range map[string]string{"ada": rbac.RoleViewer, "ed": rbac.RoleEditor, "root": rbac.RoleAdmin} {
+		email := uid + "@example.com"
+		if _, err := repos.User.Create(context.Background(), model.User{ID: primitive.NewObjectID(), User_id: uid, Email: &email, Phone: &email, Role: role}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	caller := func(c *gin.Context) {
+		c.Set("uid", c.GetHeader("X-Uid"))
+		c.Set("permissions", strings.Split(c.GetHeader("X-Permissions"), ","))
+		if session := c.GetHeader("X-Session"); session != "" {
+			c.Set("claims", &helper.SignedDetails{Uid: c.GetHeader("X-Uid"), Session: session})
+		}
+	}
+	router := gin.New()
+	router.PUT("/user/:user_id", caller, newUserController(repos).UpdateUser)
+	return router, repos
+}
+
+func TestUpdateUser(t *testing.T) {
+	router, repos := updateUserRouter(t)
+	editor := strings.Join(append([]string{"user:read", "user:write"}, rbac.Builtin[rbac.RoleEditor]...), ",")
+	viewer := strings.Join(rbac.Builtin[rbac.RoleViewer], ",")
+
+	tests := []struct {
+		name        string
+		uid         string
+		permissions string
+		target      string
+		body        string
+		want        int
+	}{
+		{"own record", "ada", viewer, "ada", `{"first_name":"Ada"}`, http.StatusOK},
+		{"a user with fewer permissions", "ed", editor, "ada", `{"first_name":"Ada"}`, http.StatusOK},
+		{"a user with the same permissions", "ed", editor, "ed", `{"first_name":"Ed"}`, http.StatusOK},
+		{"an admin", "ed", editor, "root", `{"email":"ed2@example.com"}`, http.StatusForbidden},
+		{"a user with other permissions", "ada", "user:write", "ed", `{"first_name":"Ed"}`, http.StatusForbidden},
+		{"invalid email", "ada", viewer, "ada", `{"email":"not an email"}`, http.StatusBadRequest},
+		{"empty email", "ada", viewer, "ada", `{"email":""}`, http.StatusBadRequest},
+		{"taken email", "ada", viewer, "ada", `{"email":"ed@example.com"}`, http.StatusBadRequest},
+		{"unchanged email", "ada", viewer, "ada", `{"email":"ada@example.com"}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := newJSONRequest("PUT", "/user/"+tt.target, tt.body)
+		req.Header.Set("X-Uid", tt.uid)
+		req.Header.Set("X-Permissions", tt.permissions)
+		if w := serveRequest(router, req); w.Code != tt.want {
+			t.Errorf("%s = %d %s, want %d", tt.name, w.Code, w.Body, tt.want)
+		}
+	}
+
+	if root, _ := repos.User.FindByUserID(context.Background(), "root"); *root.Email != "root@example.com" {
+		t.Errorf("admin email changed to %s", *root.Email)
+	}
+}
+
+func TestUpdateUserEndsSessions(t *testing.T) {
+	ctx := context.Background()
+	helper.SetSecretKey("user-test-key")
+	router, repos := updateUserRouter(t)
+	sessions := session.NewManager(repos.Refresh, repos.Revoked, repos.User, time.Hour, 0)
+	ada, _ := repos.User.FindByUserID(ctx, "ada")
+	current, _ := sessions.Start(ctx, ada)
+	other, _ := sessions.Start(ctx, ada)
+	claims, _ := helper.ValidateToken(current.AccessToken)
+
+	update := func(uid, permissions, body string) {
+		t.Helper()
+		req := newJSONRequest("PUT", "/user/ada", body)
+		req.Header.Set("X-Uid", uid)
+		req.Header.Set("X-Permissions", permissions)
+		if uid == "ada" {
+			req.Header.Set("X-Session", claims.Session)
+		}
+		if w := serveRequest(router, req); w.Code != http.StatusOK {
+			t.Fatalf("update %s = %d %s", body, w.Code, w.Body)
+		}
+	}
+
+	update("ada", "", `{"first_name":"Ada"}`)
+	if _, err := sessions.Refresh(ctx, other.RefreshToken); err != nil {
+		t.Fatalf("a name change ended the sessions: %v", err)
+	}
+	other, _ = sessions.Start(ctx, ada)
+
+	// the session the email was changed in goes on
+	update("ada", "", `{"email":"lovelace@example.com"}`)
+	if _, err := sessions.Refresh(ctx, other.RefreshToken); !errors.Is(err, session.ErrInvalidRefreshToken) {
+		t.Errorf("other session after an email change = %v", err)
+	}
+	current, err := sessions.Refresh(ctx, current.RefreshToken)
+	if err != nil {
+		t.Fatalf("current session after an email change = %v", err)
+	}
+
+	// someone else changing the password ends every session
+	update("root", rbac.Wildcard, `{"password":"correct horse"}`)
+	if _, err := sessions.Refresh(ctx, current.RefreshToken); !errors.Is(err, session.ErrInvalidRefreshToken) {
+		t.Errorf("session after an admin changed the password = %v", err)
+	}
+}
@@ -0,0 +1,257 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"golang_cms/model"
+	"golang_cms/rbac"
+	"golang_cms/repository"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrInvalidSchedule is returned when the publish_at and unpublish_at of a
+// document contradict each other or its status.
+var ErrInvalidSchedule = errors.New("invalid publishing schedule")
+
+// ErrPublishDenied is returned when a user who may write a content type but
+// not publish it changes more than its draft or in review status.
+var ErrPublishDenied = errors.New("not allowed to publish, archive or schedule, leave the document draft or in_review")
+
+// workflowFields are the list fields of every content type that has a
+// publishing status.
+var workflowFields = ListFields{
+	"status" : {Field: "status", Kind: StringField},
+	"publish_at" : {Field: "publish_at", Kind: TimeField},
+	"unpublish_at" : {Field: "unpublish_at", Kind: TimeField},
+}
+
+// WorkflowInput is the body of a status change: the new status and,
+// optionally, when the document is published or archived.
+type WorkflowInput struct {
+	Status      string     `json:"status" validate:"required,oneof=draft in_review published archived"`
+	PublishAt   *time.Time `json:"publish_at"`
+	UnpublishAt *time.Time `json:"unpublish_at"`
+}
+
+// workflowOf returns the publishing state of doc, which must be a pointer,
+// when its type has one.
+func workflowOf(doc interface{}) (*model.Workflow, bool) {
+	publishable, ok := doc.(model.Publishable)
+	if !ok {
+		return nil, false
+	}
+	return publishable.WorkflowState(), true
+}
+
+// settleWorkflow completes the publishing state a client sent. Without a
+// status the document keeps current, its state before the write, or starts
+// as a draft when it is new. A document cannot be published ahead of its
+// publish_at; it is scheduled by leaving it a draft or in review instead.
+func settleWorkflow(state *model.Workflow, current *model.Workflow, now time.Time) error {
+	if state.Status == "" {
+		if current != nil {
+			*state = *current
+		} else {
+			state.Status = model.StatusDraft
+		}
+	}
+
+	if state.PublishAt != nil && state.UnpublishAt != nil && !state.UnpublishAt.After(*state.PublishAt) {
+		return fmt.Errorf("%w: unpublish_at must be after publish_at", ErrInvalidSchedule)
+	}
+	if state.Status == model.StatusPublished && state.PublishAt != nil && state.PublishAt.After(now) {
+		return fmt.Errorf("%w: a published document cannot have a future publish_at, leave it draft or in_review to schedule it", ErrInvalidSchedule)
+	}
+	return nil
+}
+
+// publishes reports whether going from current to state publishes, archives
+// or schedules a document rather than only moving it between draft and in
+// review. current is nil for a new document.
+func publishes(state model.Workflow, current *model.Workflow) bool {
+	before := model.Workflow{Status: model.StatusDraft}
+	if current != nil {
+		before = *current
+	}
+	if !sameTime(state.PublishAt, before.PublishAt) || !sameTime(state.UnpublishAt, before.UnpublishAt) {
+		return true
+	}
+	editorial := func(status string) bool { return status == model.StatusDraft || status == model.StatusInReview }
+	return state.Status != before.Status && !(editorial(state.Status) && editorial(before.Status))
+}
+
+func sameTime(a *time.Time, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// mayPublish refuses the change from current to state unless the user of
+// the request may publish resource, or the change does not publish.
+func mayPublish(c *gin.Context, resource string, state model.Workflow, current *model.Workflow) error {
+	if publishes(state, current) && !allowed(c, rbac.Permission(resource, rbac.ActionPublish)) {
+		return ErrPublishDenied
+	}
+	return nil
+}
+
+// isLive reports whether a document with the given state is shown by the
+// public endpoints.
+func isLive(state model.Workflow) bool {
+	return state.Status == model.StatusPublished
+}
+
+// workflow settles the publishing state of doc before it is written. For an
+// update, stored is the document as it is before the write.
+func (r *Resource[T]) workflow(c *gin.Context, doc *T, stored *T) bool {
+	state, ok := workflowOf(doc)
+	if !ok {
+		return true
+	}
+
+	var current *model.Workflow
+	if stored != nil {
+		current, _ = workflowOf(stored)
+	}
+
+	if err := settleWorkflow(state, current, time.Now())
+	err != nil {
+		respondError(c, statusOf(err), err)
+		return false
+	}
+	if err := mayPublish(c, r.Permission, *state, current)
+	err != nil {
+		respondError(c, statusOf(err), err)
+		return false
+	}
+	return true
+}
+
+// SetStatus changes only the publishing state of a document, so a reviewer
+// can publish, schedule or archive it without sending its content again.
+// Users who may not publish can still move it between draft and in review.
+func (r *Resource[T]) SetStatus(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	objId, ok := r.id(c)
+	if !ok {
+		return
+	}
+
+	var input WorkflowInput
+	if err := c.ShouldBindJSON(&input)
+	err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if validationErr := validasiResource.Struct(&input)
+	validationErr != nil {
+		respondError(c, http.StatusBadRequest, validationErr)
+		return
+	}
+
+	doc, ok := r.find(ctx, c, objId)
+	if !ok {
+		return
+	}
+	stored := doc
+
+	state, _ := workflowOf(&doc)
+	current := *state
+	*state = model.Workflow{Status: input.Status, PublishAt: input.PublishAt, UnpublishAt: input.UnpublishAt}
+	if err := settleWorkflow(state, nil, time.Now())
+	err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+	if err := mayPublish(c, r.Permission, *state, &current)
+	err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+
+	if r.History != nil {
+		if err := r.History.Baseline(ctx, objId, func() (T, error) { return stored, nil })
+		err != nil {
+			respondError(c, statusOf(err), err)
+			return
+		}
+	}
+
+	updated, err := r.Repo.Update(ctx, doc)
+	if err != nil {
+		respondError(c, statusOf(err), err)
+		return
+	}
+
+	if r.History != nil {
+		r.History.recordWritten(ctx, c, objId, updated, RevisionUpdated, nil)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"Status" : 200,
+		"Message" : "Data updated successfully!",
+		"Data" : updated,
+	})
+}
+
+// RegisterPublic adds the read-only routes of the resource that only show
+// published documents: GET itemPath/:id and GET listPath.
+func (r *Resource[T]) RegisterPublic(routes gin.IRoutes, itemPath string, listPath string) {
+	routes.GET(itemPath + "/:" + r.IDParam, r.PublicGet)
+	routes.GET(listPath, r.PublicList)
+}
+
+// PublicGet fetches a document as long as it is published; any other
+// document is reported as not found.
+func (r *Resource[T]) PublicGet(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	objId, ok := r.id(c)
+	if !ok {
+		return
+	}
+
+	doc, ok := r.find(ctx, c, objId)
+	if !ok {
+		return
+	}
+	if state, ok := workflowOf(&doc); ok && !isLive(*state) {
+		respondError(c, http.StatusNotFound, repository.ErrNotFound)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"Status" : 200,
+		"Message" : "Data fetched successfully!",
+		"Data" : doc,
+	})
+}
+
+// PublicList lists the published documents, whatever status the client
+// filters by.
+func (r *Resource[T]) PublicList(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	lq, err := parseListQuery(c, r.Fields, nil)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if _, ok := workflowOf(new(T)); ok {
+		lq.Filter["status"] = model.StatusPublished
+	}
+	if _, ok := trashOf(new(T)); ok {
+		lq.Filter["deleted_at"] = nil
+	}
+
+	listDocs(ctx, c, lq, r.Repo.List)
+}
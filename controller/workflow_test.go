@@ -0,0 +1,126 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"golang_cms/rbac"
+	"golang_cms/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// builtinGuard grants the permissions of the built-in role named by the
+// X-Role header of the request.
+func builtinGuard(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		granted := rbac.Builtin[c.GetHeader("X-Role")]
+		for _, permission := range permissions {
+			if !rbac.Allows(granted, permission) {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+		}
+		c.Set("uid", c.GetHeader("X-Role"))
+		c.Set("permissions", granted)
+		c.Next()
+	}
+}
+
+func workflowRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	repos := repository.NewMemoryRepositories()
+	desc := NewDescResource(repos.Desc, repos.Revision)
+	router := gin.New()
+	desc.Register(router, "/desc", "/descs", builtinGuard)
+	desc.RegisterPublic(router.Group("/public"), "/desc", "/descs")
+	return router
+}
+
+func serveRole(t *testing.T, router http.Handler, role, method, path, body string) (int, map[string]interface{}) {
+	t.Helper()
+	req := newJSONRequest(method, path, body)
+	req.Header.Set("X-Role", role)
+	w := serveRequest(router, req)
+	var out map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &out)
+	return w.Code, out
+}
+
+// createDesc creates a desc as role and returns its id.
+func createDesc(t *testing.T, router http.Handler, role, body string) string {
+	t.Helper()
+	code, out := serveRole(t, router, role, "POST", "/desc", body)
+	if code != http.StatusCreated {
+		t.Fatalf("create = %d %v", code, out)
+	}
+	return out["Data"].(map[string]interface{})["InsertedID"].(string)
+}
+
+func TestWorkflowNewDocumentsAreDrafts(t *testing.T) {
+	router := workflowRouter(t)
+	id := createDesc(t, router, rbac.RoleAuthor, `{"title":"a","desc":"b"}`)
+
+	code, out := serveRole(t, router, rbac.RoleAuthor, "GET", "/desc/"+id, "")
+	if status := out["Data"].(map[string]interface{})["status"]; code != http.StatusOK || status != "draft" {
+		t.Errorf("new desc = %d, status %v", code, status)
+	}
+	if code, _ := serveRole(t, router, "", "GET", "/public/desc/"+id, ""); code != http.StatusNotFound {
+		t.Errorf("public draft = %d", code)
+	}
+}
+
+func TestWorkflowTransitions(t *testing.T) {
+	router := workflowRouter(t)
+	id := createDesc(t, router, rbac.RoleAuthor, `{"title":"a","desc":"b"}`)
+	status := "/desc/" + id + "/status"
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	past := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+
+	steps := []struct {
+		role string
+		body string
+		want int
+	}{
+		{rbac.RoleAuthor, `{"status":"in_review"}`, http.StatusOK},
+		{rbac.RoleAuthor, `{"status":"draft"}`, http.StatusOK},
+		{rbac.RoleAuthor, `{"status":"published"}`, http.StatusForbidden},
+		{rbac.RoleAuthor, `{"status":"draft","publish_at":"` + future + `"}`, http.StatusForbidden},
+		{rbac.RoleViewer, `{"status":"in_review"}`, http.StatusForbidden},
+		{rbac.RoleEditor, `{"status":"unknown"}`, http.StatusBadRequest},
+		{rbac.RoleEditor, `{"status":"published","publish_at":"` + future + `"}`, http.StatusBadRequest},
+		{rbac.RoleEditor, `{"status":"draft","publish_at":"` + future + `","unpublish_at":"` + past + `"}`, http.StatusBadRequest},
+		{rbac.RoleEditor, `{"status":"in_review","publish_at":"` + future + `"}`, http.StatusOK},
+		{rbac.RoleEditor, `{"status":"published"}`, http.StatusOK},
+		{rbac.RoleAuthor, `{"status":"draft"}`, http.StatusForbidden},
+		{rbac.RoleEditor, `{"status":"archived"}`, http.StatusOK},
+	}
+	for i, step := range steps {
+		if code, out := serveRole(t, router, step.role, "PUT", status, step.body); code != step.want {
+			t.Fatalf("step %d: %s setting %s = %d %v, want %d", i, step.role, step.body, code, out, step.want)
+		}
+	}
+}
+
+func TestWorkflowPublicShowsPublished(t *testing.T) {
+	router := workflowRouter(t)
+	draft := createDesc(t, router, rbac.RoleEditor, `{"title":"draft","desc":"b"}`)
+	published := createDesc(t, router, rbac.RoleEditor, `{"title":"live","desc":"b","status":"published"}`)
+	if code, _ := serveRole(t, router, rbac.RoleAuthor, "POST", "/desc", `{"title":"x","desc":"b","status":"published"}`); code != http.StatusForbidden {
+		t.Errorf("author creating a published desc = %d", code)
+	}
+
+	if code, _ := serveRole(t, router, "", "GET", "/public/desc/"+published, ""); code != http.StatusOK {
+		t.Errorf("public published = %d", code)
+	}
+	if code, _ := serveRole(t, router, "", "GET", "/public/desc/"+draft, ""); code != http.StatusNotFound {
+		t.Errorf("public draft = %d", code)
+	}
+	code, out := serveRole(t, router, "", "GET", "/public/descs?status=draft", "")
+	if docs := out["Data"].([]interface{}); code != http.StatusOK || len(docs) != 1 {
+		t.Errorf("public list = %d %v", code, out)
+	}
+}
@@ -0,0 +1,51 @@
+package helper
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	SetSecretKey("cursor-test-key")
+	data := []byte("position\x00with binary\xff")
+	cursor := SignCursor(data)
+	if strings.ContainsAny(cursor, "+/=") {
+		t.Errorf("cursor %q is not url safe", cursor)
+	}
+	got, err := VerifyCursor(cursor)
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("VerifyCursor = %q, %v", got, err)
+	}
+}
+
+func TestCursorTampering(t *testing.T) {
+	SetSecretKey("cursor-test-key")
+	cursor := SignCursor([]byte("page two"))
+	payload, signature, _ := strings.Cut(cursor, ".")
+	other := SignCursor([]byte("page three"))
+	otherPayload, _, _ := strings.Cut(other, ".")
+
+	tests := map[string]string{
+		"no signature":    payload,
+		"empty":           "",
+		"swapped payload": otherPayload + "." + signature,
+		"bad base64":      payload + ".!!!",
+		"truncated":       cursor[:len(cursor)-2],
+		"signature only":  "." + signature,
+		"extra dot":       cursor + ".x",
+	}
+	for name, token := range tests {
+		if _, err := VerifyCursor(token); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("%s: VerifyCursor = %v, want ErrInvalidCursor", name, err)
+		}
+	}
+
+	// a new key invalidates every cursor made with the old one
+	SetSecretKey("another-key")
+	defer SetSecretKey("cursor-test-key")
+	if _, err := VerifyCursor(cursor); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("cursor of the old key = %v", err)
+	}
+}
@@ -0,0 +1,49 @@
+package helper
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+
+	"golang_cms/model"
+)
+
+// Snapshot is the JSON form of doc, which is what clients see of it. A nil
+// doc has an empty snapshot.
+func Snapshot(doc interface{}) (map[string]interface{}, error) {
+	if doc == nil {
+		return map[string]interface{}{}, nil
+	}
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// Diff lists the fields that differ between two snapshots, by name.
+func Diff(from map[string]interface{}, to map[string]interface{}) []model.FieldChange {
+	seen := map[string]bool{}
+	names := []string{}
+	for _, fields := range []map[string]interface{}{from, to} {
+		for name := range fields {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	changes := []model.FieldChange{}
+	for _, name := range names {
+		if !reflect.DeepEqual(from[name], to[name]) {
+			changes = append(changes, model.FieldChange{Field: name, From: from[name], To: to[name]})
+		}
+	}
+	return changes
+}
@@ -0,0 +1,28 @@
+package helper
+
+import (
+	"reflect"
+	"testing"
+
+	"golang_cms/model"
+)
+
+func TestDiff(t *testing.T) {
+	from := map[string]interface{}{"title": "a", "desc": "b", "status": "draft", "same": 1.0}
+	to := map[string]interface{}{"title": "A", "desc": "b", "status": "published", "same": 1.0, "added": true}
+
+	want := []model.FieldChange{
+		{Field: "added", From: nil, To: true},
+		{Field: "status", From: "draft", To: "published"},
+		{Field: "title", From: "a", To: "A"},
+	}
+	// map order varies between runs, so the order must not
+	for i := 0; i < 20; i++ {
+		if got := Diff(from, to); !reflect.DeepEqual(got, want) {
+			t.Fatalf("Diff = %v, want %v", got, want)
+		}
+	}
+	if got := Diff(to, to); len(got) != 0 {
+		t.Errorf("Diff of equal snapshots = %v", got)
+	}
+}
@@ -1,59 +1,99 @@
 package helper
 
 import (
-	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
-	"golang_cms/config"
-	"log"
-	"os"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// SignedDetails are the claims of an access token. Subject is the uid of
+// the user, Id a random token ID and Session the session the token was
+// issued in.
 type SignedDetails struct {
 	Email string
 	First_name string
 	Last_name string
 	Uid string
 	User_type string
+	Session string
 	jwt.StandardClaims
 }
 
-var userCollection *mongo.Collection = config.GetCollection(config.DB, "user")
-var secretKey string = os.Getenv("SECRET_KEY")
+var secretKey string
 
-func GenerateAllTokens(email string, firstName string, lastName string, userType string, uid string) (signedToken string, signedRefreshToken string, err error) {
+var accessTokenTTL = 15 * time.Minute
+
+// SetSecretKey sets the key used to sign and verify tokens.
+func SetSecretKey(key string) {
+	secretKey = key
+}
+
+// SetAccessTokenTTL sets how long access tokens are valid.
+func SetAccessTokenTTL(ttl time.Duration) {
+	accessTokenTTL = ttl
+}
+
+// AccessTokenTTL is how long access tokens are valid.
+func AccessTokenTTL() time.Duration {
+	return accessTokenTTL
+}
+
+// GenerateAccessToken signs a short-lived access token for user uid in
+// session sessionId.
+func GenerateAccessToken(email string, firstName string, lastName string, userType string, uid string, sessionId string) (signedToken string, err error) {
+	jti, err := RandomToken(16)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
 	claims := &SignedDetails{
 		Email: email,
 		First_name: firstName,
 		Last_name: lastName,
 		Uid: uid,
 		User_type: userType,
+		Session: sessionId,
 		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Local().Add(time.Hour * time.Duration(168)).Unix(),
+			Subject: uid,
+			Id: jti,
+			IssuedAt: now.Unix(),
+			ExpiresAt: now.Add(accessTokenTTL).Unix(),
 		},
 	}
 
-	refreshClaims := &SignedDetails{
-		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Local().Add(time.Hour * time.Duration(168)).Unix(),
-		},
-	}
-
-	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([] byte(secretKey))
-	refreshToken, err := jwt.NewWithClaims(jwt.SigningMethodES256, refreshClaims).SignedString([] byte(secretKey))
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([] byte(secretKey))
+}
 
+// GenerateRefreshToken returns a random opaque refresh token and the hash
+// it is stored under; the token itself is only ever given to the client.
+func GenerateRefreshToken() (token string, hash string, err error) {
+	token, err = RandomToken(32)
 	if err != nil {
-		log.Panic(err)
-		return
+		return "", "", err
 	}
+	return token, HashToken(token), nil
+}
+
+// HashToken is the SHA-256 of an opaque token, hex encoded. The tokens are
+// random enough that they need no salt.
+func HashToken(token string) string {
+	sum := sha256.Sum256([] byte(token))
+	return hex.EncodeToString(sum[:])
+}
 
-	return token, refreshToken, err
+// RandomToken returns size random bytes, base64url encoded.
+func RandomToken(size int) (string, error) {
+	buf := make([] byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
 }
 
 func ValidateToken(signedToken string) (claims *SignedDetails, message string) {
@@ -61,6 +101,9 @@ func ValidateToken(signedToken string) (claims *SignedDetails, message string) {
 		signedToken,
 		&SignedDetails{},
 		func(token *jwt.Token) (interface{}, error) {
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+			}
 			return [] byte(secretKey), nil
 		},
 	)
@@ -72,52 +115,16 @@ func ValidateToken(signedToken string) (claims *SignedDetails, message string) {
 
 	claims, ok := token.Claims.(*SignedDetails)
 
-	if !ok {
+	//token tanpa ID tidak bisa dicabut, jadi tidak diterima
+	if !ok || !token.Valid || claims.Id == "" {
 		message = fmt.Sprintf("Invalid token!")
-		message = err.Error()
-		return
+		return nil, message
 	}
 
 	if claims.ExpiresAt < time.Now().Local().Unix() {
 		message = fmt.Sprintf("Expired token!")
-		message = err.Error()
-		return
+		return nil, message
 	}
 
 	return claims, message
 }
-
-func UpdateAllTokens(signedToken string, signedRefreshToken string, userId string) {
-	var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-	var updateObj primitive.D
-
-	Updated_at, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-
-	updateObj = append(updateObj, bson.E{"updated_at", Updated_at})
-	updateObj = append(updateObj, bson.E{"token", signedToken})
-	updateObj = append(updateObj, bson.E{"refresh_token", signedRefreshToken})
-
-	upsert := true
-	filter := bson.M{"user_id" : userId}
-	opt := options.UpdateOptions{
-		Upsert: &upsert,
-	}
-
-	_, err := userCollection.UpdateOne(
-		ctx,
-		filter,
-		bson.D{
-			{"$set", updateObj},
-		},
-		&opt,
-	)
-
-	defer cancel()
-
-	if err != nil {
-		log.Panic(err)
-		return
-	}
-
-	return
-}
\ No newline at end of file
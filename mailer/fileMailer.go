@@ -0,0 +1,67 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FileMailer writes every email to a directory as an .eml file instead of
+// sending it, for local development and tests.
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+// NewFileMailer writes emails from from to dir, creating it when it is
+// missing.
+func NewFileMailer(dir string, from string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileMailer{dir: dir, from: from}, nil
+}
+
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	data, err := msg.encode(m.from)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(m.dir, fmt.Sprintf("%s-*.eml", time.Now().UTC().Format("20060102T150405")))
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	log.Printf("mailer: wrote email %q to %v as %s", msg.Subject, msg.To, filepath.Base(file.Name()))
+	return nil
+}
+
+// LogMailer prints the text of every email to the log instead of sending
+// it, for local development. The secrets of the email are masked.
+type LogMailer struct{}
+
+func (LogMailer) Send(ctx context.Context, msg Message) error {
+	if _, err := msg.addresses(); err != nil {
+		return err
+	}
+	text := msg.Text
+	for _, secret := range msg.Secrets {
+		if secret != "" {
+			text = strings.ReplaceAll(text, secret, "[redacted]")
+		}
+	}
+	log.Printf("mailer: email to %v\nSubject: %s\n\n%s", msg.To, msg.Subject, text)
+	return nil
+}
@@ -0,0 +1,107 @@
+// Package mailer sends emails, such as password reset links. Emails are
+// rendered from the templates of the package and handed to a Mailer, which
+// delivers them over SMTP or, during development, writes or logs them.
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"golang_cms/helper"
+)
+
+// Message is an email with a plain text and an HTML body. Secrets are the
+// parts of the body, such as reset tokens, that only the recipient may see;
+// they are sent but never logged.
+type Message struct {
+	To      []string
+	Subject string
+	Text    string
+	HTML    string
+	Secrets []string
+}
+
+// Mailer delivers messages. Implementations must be safe for concurrent
+// use.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// addresses parses the recipients of msg, which also keeps header
+// injection out of the To line.
+func (msg Message) addresses() ([]string, error) {
+	if len(msg.To) == 0 {
+		return nil, fmt.Errorf("email has no recipients")
+	}
+	addresses := make([]string, len(msg.To))
+	for i, to := range msg.To {
+		address, err := mail.ParseAddress(to)
+		if err != nil {
+			return nil, fmt.Errorf("invalid recipient %q: %w", to, err)
+		}
+		addresses[i] = address.Address
+	}
+	return addresses, nil
+}
+
+// encode renders msg as a MIME email from from, with the text and HTML
+// bodies as alternatives.
+func (msg Message) encode(from string) ([]byte, error) {
+	sender, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender %q: %w", from, err)
+	}
+	to, err := msg.addresses()
+	if err != nil {
+		return nil, err
+	}
+	id, err := helper.RandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	_, domain, _ := strings.Cut(sender.Address, "@")
+
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "From: %s\r\n", sender.String())
+	fmt.Fprintf(&out, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&out, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&out, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&out, "Message-ID: <%s@%s>\r\n", id, domain)
+	fmt.Fprintf(&out, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&out, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", parts.Boundary())
+	out.Write(body.Bytes())
+	return out.Bytes(), nil
+}
@@ -0,0 +1,76 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRenderPasswordReset(t *testing.T) {
+	msg, err := Render(TemplatePasswordReset, "ada@example.com", PasswordReset{Name: "Ada <x>", Link: "https://cms.example/reset?token=abc", Token: "abc", ExpiresMinutes: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Subject == "" || len(msg.To) != 1 || msg.To[0] != "ada@example.com" {
+		t.Fatalf("msg = %+v", msg)
+	}
+	if !strings.Contains(msg.Text, "https://cms.example/reset?token=abc") {
+		t.Errorf("text does not hold the link:\n%s", msg.Text)
+	}
+	if !strings.Contains(msg.HTML, "Ada &lt;x&gt;") {
+		t.Errorf("html does not escape the name:\n%s", msg.HTML)
+	}
+}
+
+func TestLogMailerRedactsSecrets(t *testing.T) {
+	var out bytes.Buffer
+	log.SetOutput(&out)
+	defer log.SetOutput(os.Stderr)
+
+	msg, err := Render(TemplatePasswordReset, "ada@example.com", PasswordReset{Link: "https://cms.example/reset?token=s3cr3t-t0ken", Token: "s3cr3t-t0ken", ExpiresMinutes: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg.Secrets = []string{"s3cr3t-t0ken"}
+	if err := (LogMailer{}).Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "s3cr3t-t0ken") {
+		t.Errorf("the log holds the secret:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "[redacted]") || !strings.Contains(out.String(), "ada@example.com") {
+		t.Errorf("log = %s", out.String())
+	}
+}
+
+func TestLogMailerRejectsBadAddresses(t *testing.T) {
+	err := (LogMailer{}).Send(context.Background(), Message{To: []string{"ada@example.com\r\nBcc: eve@example.com"}, Subject: "x", Text: "x"})
+	if err == nil {
+		t.Fatal("header injection accepted")
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := t.TempDir()
+	m, err := NewFileMailer(filepath.Join(dir, "mail"), "no-reply@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Send(context.Background(), Message{To: []string{"ada@example.com"}, Subject: "Hello", Text: "plain", HTML: "<p>html</p>"}); err != nil {
+		t.Fatal(err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "mail", "*.eml"))
+	if len(files) != 1 {
+		t.Fatalf("wrote %d files", len(files))
+	}
+	data, _ := os.ReadFile(files[0])
+	for _, want := range []string{"To: ada@example.com", "Subject: Hello", "plain", "<p>html</p>"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("email does not hold %q:\n%s", want, data)
+		}
+	}
+}
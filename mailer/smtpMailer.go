@@ -0,0 +1,126 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+)
+
+// ErrNoTLS is returned when the SMTP server does not offer STARTTLS and the
+// connection must be encrypted.
+var ErrNoTLS = errors.New("smtp server does not offer STARTTLS")
+
+// SMTPMailer delivers emails through an SMTP server. Port 465 speaks TLS
+// from the start; on other ports the connection is upgraded with STARTTLS.
+// A server that does not offer STARTTLS is refused unless it is localhost or
+// the mailer is insecure.
+type SMTPMailer struct {
+	host     string
+	port     int
+	username string
+	password string
+	from     string
+	insecure bool
+}
+
+// NewSMTPMailer sends emails from from through host:port, logging in when
+// username is set. insecure allows a plain connection to any host.
+func NewSMTPMailer(host string, port int, username string, password string, from string, insecure bool) *SMTPMailer {
+	return &SMTPMailer{host: host, port: port, username: username, password: password, from: from, insecure: insecure}
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	to, err := msg.addresses()
+	if err != nil {
+		return err
+	}
+	data, err := msg.encode(m.from)
+	if err != nil {
+		return err
+	}
+	// the envelope takes the bare address, not the display name of From
+	sender, err := mail.ParseAddress(m.from)
+	if err != nil {
+		return err
+	}
+
+	client, err := m.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if m.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", m.username, m.password, m.host)); err != nil {
+			return fmt.Errorf("smtp auth: %w", err)
+		}
+	}
+	if err := client.Mail(sender.Address); err != nil {
+		return err
+	}
+	for _, address := range to {
+		if err := client.Rcpt(address); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// dial connects to the server, bounded by the deadline of ctx.
+func (m *SMTPMailer) dial(ctx context.Context) (*smtp.Client, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(m.host, strconv.Itoa(m.port)))
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	config := &tls.Config{ServerName: m.host}
+	if m.port == 465 {
+		conn = tls.Client(conn, config)
+	}
+	client, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if m.port != 465 {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(config); err != nil {
+				client.Close()
+				return nil, err
+			}
+		} else if m.requiresTLS() {
+			client.Close()
+			return nil, ErrNoTLS
+		}
+	}
+	return client, nil
+}
+
+// requiresTLS reports whether the connection must be encrypted: always,
+// except to localhost or when the mailer is insecure.
+func (m *SMTPMailer) requiresTLS() bool {
+	if m.insecure || m.host == "localhost" {
+		return false
+	}
+	ip := net.ParseIP(m.host)
+	return ip == nil || !ip.IsLoopback()
+}
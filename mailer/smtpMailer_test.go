@@ -0,0 +1,134 @@
+package mailer
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+)
+
+// fakeSMTP accepts one connection on host, answers every command with
+// success, never offers STARTTLS and returns the commands it was sent on
+// commands.
+func fakeSMTP(t *testing.T, host string) (port int, commands <-chan []string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", net.JoinHostPort(host, "0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	out := make(chan []string, 1)
+	go func() {
+		var seen []string
+		defer func() { out <- seen }()
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			seen = append(seen, line)
+			switch verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0]); verb {
+			case "EHLO":
+				reply("250-localhost")
+				reply("250 8BITMIME")
+			case "DATA":
+				reply("354 go ahead")
+				for {
+					if line, err := r.ReadString('\n'); err != nil || line == ".\r\n" {
+						break
+					}
+				}
+				reply("250 queued")
+			case "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+	return listener.Addr().(*net.TCPAddr).Port, out
+}
+
+func TestSMTPMailerEnvelope(t *testing.T) {
+	port, commands := fakeSMTP(t, "127.0.0.1")
+	m := NewSMTPMailer("127.0.0.1", port, "", "", "CMS <no-reply@example.com>", false)
+	msg := Message{To: []string{"Ada <ada@example.com>"}, Subject: "hi", Text: "hi"}
+	if err := m.Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+
+	seen := strings.Join(<-commands, "\n")
+	for _, want := range []string{"MAIL FROM:<no-reply@example.com>", "RCPT TO:<ada@example.com>"} {
+		if !strings.Contains(seen, want) {
+			t.Errorf("no %q in:\n%s", want, seen)
+		}
+	}
+}
+
+// publicIP returns an address of this machine that is not loopback.
+func publicIP(t *testing.T) string {
+	t.Helper()
+	addrs, _ := net.InterfaceAddrs()
+	for _, addr := range addrs {
+		if ipnet, ok := addr.(*net.IPNet); ok && !ipnet.IP.IsLoopback() && ipnet.IP.To4() != nil {
+			return ipnet.IP.String()
+		}
+	}
+	t.Skip("no address other than loopback")
+	return ""
+}
+
+func TestSMTPMailerRequiresTLS(t *testing.T) {
+	msg := Message{To: []string{"ada@example.com"}, Subject: "hi", Text: "hi"}
+	host := publicIP(t)
+
+	port, commands := fakeSMTP(t, host)
+	m := NewSMTPMailer(host, port, "user", "secret", "no-reply@example.com", false)
+	if err := m.Send(context.Background(), msg); !errors.Is(err, ErrNoTLS) {
+		t.Errorf("plain server on %s: %v, want ErrNoTLS", host, err)
+	}
+	if seen := strings.Join(<-commands, "\n"); strings.Contains(seen, "AUTH") || strings.Contains(seen, "MAIL") {
+		t.Errorf("sent over a plain connection:\n%s", seen)
+	}
+
+	port, _ = fakeSMTP(t, host)
+	m = NewSMTPMailer(host, port, "", "", "no-reply@example.com", true)
+	if err := m.Send(context.Background(), msg); err != nil {
+		t.Errorf("insecure mailer: %v", err)
+	}
+}
+
+func TestSMTPMailerRequiresTLSExceptLocalhost(t *testing.T) {
+	tests := []struct {
+		host     string
+		insecure bool
+		want     bool
+	}{
+		{"smtp.example.com", false, true},
+		{"192.0.2.1", false, true},
+		{"smtp.example.com", true, false},
+		{"localhost", false, false},
+		{"127.0.0.1", false, false},
+		{"::1", false, false},
+	}
+	for _, tt := range tests {
+		m := NewSMTPMailer(tt.host, 587, "", "", "no-reply@example.com", tt.insecure)
+		if got := m.requiresTLS(); got != tt.want {
+			t.Errorf("requiresTLS(%s, insecure %v) = %v, want %v", tt.host, tt.insecure, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+)
+
+// Templates of every email, in templates/<name>.txt and <name>.html. The
+// text template also defines "<name>.subject", the subject of the email.
+//
+//go:embed templates
+var templateFiles embed.FS
+
+var (
+	textTemplates = texttemplate.Must(texttemplate.ParseFS(templateFiles, "templates/*.txt"))
+	htmlTemplates = htmltemplate.Must(htmltemplate.ParseFS(templateFiles, "templates/*.html"))
+)
+
+const (
+	// TemplatePasswordReset is sent with the link to reset a password. Its
+	// data is a PasswordReset.
+	TemplatePasswordReset = "password_reset"
+)
+
+// PasswordReset is the data of the password reset email. Link is empty
+// when no reset page is configured, and the token is given on its own.
+type PasswordReset struct {
+	Name           string
+	Link           string
+	Token          string
+	ExpiresMinutes int
+}
+
+// Render renders the email template name with data into a message to to.
+func Render(name string, to string, data interface{}) (Message, error) {
+	text := textTemplates.Lookup(name + ".txt")
+	html := htmlTemplates.Lookup(name + ".html")
+	if text == nil || html == nil {
+		return Message{}, fmt.Errorf("no email template %q", name)
+	}
+
+	var subject, textBody, htmlBody bytes.Buffer
+	if err := textTemplates.ExecuteTemplate(&subject, name+".subject", data); err != nil {
+		return Message{}, err
+	}
+	if err := text.Execute(&textBody, data); err != nil {
+		return Message{}, err
+	}
+	if err := html.Execute(&htmlBody, data); err != nil {
+		return Message{}, err
+	}
+	return Message{
+		To:      []string{to},
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    textBody.String(),
+		HTML:    htmlBody.String(),
+	}, nil
+}
@@ -1,40 +1,53 @@
 package main
 
 import (
+	"context"
+	"golang_cms/app"
 	"golang_cms/config"
-	"golang_cms/routes"
+	"log"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
-
-	"github.com/gin-contrib/cors"
-	"github.com/gin-gonic/gin"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "grant-admin" {
+		if err := runGrantAdmin(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 
-	//run database
-	config.ConnectDB()
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	port := os.Getenv("PORT")
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	if port == "" {
-		port = "8888"
+	//run database
+	application, err := app.New(ctx, cfg)
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	router := gin.New()
-	router.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"*"},
-		AllowMethods:     []string{"PUT", "PATCH", "GET", "POST", "OPTIONS", "DELETE"},
-		AllowHeaders:     []string{"*"},
-		ExposeHeaders:    []string{"*"},
-		AllowCredentials: false,
-		AllowOriginFunc: func(origin string) bool {
-			return origin == "*"
-		},
-		MaxAge: 12 * time.Hour,
-	}))
+	runErr := application.Run(ctx)
 
-	routes.AuthRoutes(router)
-	routes.UserRoutes(router)
-	router.Run(":" + port)
+	closeCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := application.Close(closeCtx); err != nil {
+		log.Println(err)
+	}
+	if runErr != nil {
+		log.Fatal(runErr)
+	}
+	log.Println("Server stopped")
 }
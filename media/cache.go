@@ -0,0 +1,161 @@
+package media
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tempPrefix starts the names of entries still being written.
+const tempPrefix = ".cache-"
+
+// DiskCache keeps resized images in a directory. Entries never go stale,
+// since the content of a media never changes under its id; they are removed
+// with the media itself, or least recently used first once the cache holds
+// more than maxSize bytes.
+type DiskCache struct {
+	dir     string
+	maxSize int64
+
+	mu   sync.Mutex
+	size int64
+}
+
+// NewDiskCache opens the cache in dir, which may already hold entries. A
+// maxSize of zero or less leaves the cache unbounded.
+func NewDiskCache(dir string, maxSize int64) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	c := &DiskCache{dir: dir, maxSize: maxSize}
+	entries, err := c.entries()
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		c.size += entry.Size()
+	}
+	return c, nil
+}
+
+// Get returns the cached entry name, ok being false when there is none.
+// An entry read is marked as recently used.
+func (c *DiskCache) Get(name string) (data []byte, ok bool, err error) {
+	path := filepath.Join(c.dir, name)
+	data, err = os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return data, true, nil
+}
+
+// Put stores an entry through a temporary file, so concurrent readers see
+// either nothing or the whole entry, and evicts the least recently used
+// entries when the cache grows over its size.
+func (c *DiskCache) Put(name string, data []byte) error {
+	tmp, err := os.CreateTemp(c.dir, tempPrefix+"*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(c.dir, name)); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.size += int64(len(data))
+	if c.maxSize > 0 && c.size > c.maxSize {
+		return c.evict()
+	}
+	return nil
+}
+
+// evict removes the least recently used entries until the cache fits in
+// its size again. The size is counted afresh from the directory, which
+// also corrects it for entries that were replaced.
+func (c *DiskCache) evict() error {
+	entries, err := c.entries()
+	if err != nil {
+		return err
+	}
+	c.size = 0
+	for _, entry := range entries {
+		c.size += entry.Size()
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ModTime().Before(entries[j].ModTime()) })
+
+	for _, entry := range entries {
+		if c.size <= c.maxSize {
+			break
+		}
+		if err := os.Remove(filepath.Join(c.dir, entry.Name())); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		c.size -= entry.Size()
+	}
+	return nil
+}
+
+// Forget removes every entry whose name starts with prefix.
+func (c *DiskCache) Forget(prefix string) error {
+	entries, err := c.entries()
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), prefix) {
+			err := os.Remove(filepath.Join(c.dir, entry.Name()))
+			if err == nil {
+				c.size -= entry.Size()
+			} else if !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// entries lists the complete entries of the cache.
+func (c *DiskCache) entries() ([]fs.FileInfo, error) {
+	dirEntries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return nil, err
+	}
+	var entries []fs.FileInfo
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() || strings.HasPrefix(dirEntry.Name(), tempPrefix) {
+			continue
+		}
+		info, err := dirEntry.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, info)
+	}
+	return entries, nil
+}
@@ -0,0 +1,50 @@
+package media
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDiskCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := NewDiskCache(dir, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	for i, name := range []string{"a", "b"} {
+		if err := cache.Put(name, []byte("1234")); err != nil {
+			t.Fatal(err)
+		}
+		// a was written first
+		used := now.Add(time.Duration(i-2) * time.Hour)
+		os.Chtimes(filepath.Join(dir, name), used, used)
+	}
+
+	// reading a makes b the least recently used
+	if _, ok, err := cache.Get("a"); !ok || err != nil {
+		t.Fatalf("get a = %v, %v", ok, err)
+	}
+	if err := cache.Put("c", []byte("1234")); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		if _, ok, _ := cache.Get(name); ok != want {
+			t.Errorf("%s cached = %v, want %v", name, ok, want)
+		}
+	}
+
+	// entries already on disk count towards the size
+	reopened, err := NewDiskCache(dir, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopened.size != 8 {
+		t.Errorf("reopened size = %d, want 8", reopened.size)
+	}
+	if err := reopened.Forget("a"); err != nil || reopened.size != 4 {
+		t.Errorf("size after Forget = %d, %v", reopened.size, err)
+	}
+}
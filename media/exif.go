@@ -0,0 +1,129 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// JPEG markers, see ITU T.81 annex B.
+const (
+	markerSOI   = 0xD8
+	markerSOS   = 0xDA
+	markerAPP1  = 0xE1
+	markerAPP13 = 0xED
+
+	orientationTag = 0x0112
+)
+
+var exifHeader = []byte("Exif\x00\x00")
+
+// jpegSegment is a marker segment before the scan data. start and end are
+// the offsets of the whole segment, marker included.
+type jpegSegment struct {
+	marker     byte
+	start, end int
+	payload    []byte
+}
+
+// jpegSegments lists the segments of a JPEG up to its first scan. ok is
+// false when data is not a well formed JPEG.
+func jpegSegments(data []byte) (segments []jpegSegment, scan int, ok bool) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != markerSOI {
+		return nil, 0, false
+	}
+
+	at := 2
+	for at+4 <= len(data) {
+		if data[at] != 0xFF {
+			return nil, 0, false
+		}
+		marker := data[at+1]
+		if marker == 0xFF {
+			at++
+			continue
+		}
+		if marker == markerSOS {
+			return segments, at, true
+		}
+
+		length := int(binary.BigEndian.Uint16(data[at+2:]))
+		end := at + 2 + length
+		if length < 2 || end > len(data) {
+			return nil, 0, false
+		}
+		segments = append(segments, jpegSegment{marker: marker, start: at, end: end, payload: data[at+4 : end]})
+		at = end
+	}
+	return nil, 0, false
+}
+
+// exifOrientation reads the orientation tag of a JPEG, 1 (upright) when it
+// has none. Values 2 to 8 are the mirrored and rotated orientations of the
+// EXIF specification.
+func exifOrientation(data []byte) int {
+	segments, _, ok := jpegSegments(data)
+	if !ok {
+		return 1
+	}
+
+	for _, segment := range segments {
+		if segment.marker != markerAPP1 || !bytes.HasPrefix(segment.payload, exifHeader) {
+			continue
+		}
+		tiff := segment.payload[len(exifHeader):]
+		if len(tiff) < 8 {
+			return 1
+		}
+
+		var order binary.ByteOrder
+		switch string(tiff[:2]) {
+		case "II":
+			order = binary.LittleEndian
+		case "MM":
+			order = binary.BigEndian
+		default:
+			return 1
+		}
+
+		ifd := int(order.Uint32(tiff[4:]))
+		if ifd+2 > len(tiff) {
+			return 1
+		}
+		entries := int(order.Uint16(tiff[ifd:]))
+		for i := 0; i < entries; i++ {
+			entry := ifd + 2 + i*12
+			if entry+12 > len(tiff) {
+				return 1
+			}
+			if order.Uint16(tiff[entry:]) == orientationTag {
+				orientation := int(order.Uint16(tiff[entry+8:]))
+				if orientation < 1 || orientation > 8 {
+					return 1
+				}
+				return orientation
+			}
+		}
+		return 1
+	}
+	return 1
+}
+
+// stripJPEGMetadata drops the EXIF, XMP and IPTC segments of a JPEG without
+// touching the image data, so camera details and locations are not
+// published. Other segments, such as the ICC color profile, are kept.
+func stripJPEGMetadata(data []byte) []byte {
+	segments, scan, ok := jpegSegments(data)
+	if !ok {
+		return data
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:2]...)
+	for _, segment := range segments {
+		if segment.marker == markerAPP1 || segment.marker == markerAPP13 {
+			continue
+		}
+		out = append(out, data[segment.start:segment.end]...)
+	}
+	return append(out, data[scan:]...)
+}
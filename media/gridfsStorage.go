@@ -0,0 +1,76 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GridFSStorage keeps files in the "media" GridFS bucket of a database,
+// using the key as the file id.
+type GridFSStorage struct {
+	db *mongo.Database
+}
+
+func NewGridFSStorage(db *mongo.Database) *GridFSStorage {
+	return &GridFSStorage{db: db}
+}
+
+// bucket opens the bucket for one operation. Buckets take deadlines rather
+// than contexts, so each call gets its own bucket with the deadline of ctx.
+func (s *GridFSStorage) bucket(ctx context.Context) (*gridfs.Bucket, error) {
+	bucket, err := gridfs.NewBucket(s.db, options.GridFSBucket().SetName("media"))
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := bucket.SetReadDeadline(deadline); err != nil {
+			return nil, err
+		}
+		if err := bucket.SetWriteDeadline(deadline); err != nil {
+			return nil, err
+		}
+	}
+	return bucket, nil
+}
+
+func (s *GridFSStorage) Save(ctx context.Context, key string, r io.Reader) error {
+	bucket, err := s.bucket(ctx)
+	if err != nil {
+		return err
+	}
+	return bucket.UploadFromStreamWithID(key, key, r)
+}
+
+func (s *GridFSStorage) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	bucket, err := s.bucket(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := bucket.OpenDownloadStream(key)
+	if errors.Is(err, gridfs.ErrFileNotFound) {
+		return nil, ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	return stream, nil
+}
+
+func (s *GridFSStorage) Delete(ctx context.Context, key string) error {
+	bucket, err := s.bucket(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = bucket.Delete(key)
+	if errors.Is(err, gridfs.ErrFileNotFound) {
+		return ErrNotExist
+	}
+	return err
+}
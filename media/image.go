@@ -0,0 +1,256 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math"
+
+	"golang.org/x/image/draw"
+)
+
+// ErrNotImage is returned when a transform is asked of a file that is not
+// an image.
+var ErrNotImage = errors.New("media is not an image")
+
+// ErrUnsupportedFormat is returned for output formats that cannot be
+// encoded.
+var ErrUnsupportedFormat = errors.New("output format is not supported")
+
+// Fit says how an image is made to fit a box of Width by Height.
+type Fit string
+
+const (
+	// FitContain scales the image to fit inside the box, keeping its
+	// aspect ratio; one side may come out shorter than asked.
+	FitContain Fit = "contain"
+	// FitCover scales the image to cover the box, keeping its aspect
+	// ratio, and crops what sticks out around the center.
+	FitCover Fit = "cover"
+	// FitFill stretches the image to exactly the box.
+	FitFill Fit = "fill"
+)
+
+// Output formats. FormatAuto keeps JPEG and PNG sources as they are and
+// turns other sources into JPEG, or PNG when they have transparency. WebP
+// is written lossless.
+const (
+	FormatAuto = ""
+	FormatJPEG = "jpeg"
+	FormatPNG  = "png"
+	FormatWebP = "webp"
+)
+
+// Transform describes an image derived from an upload. A zero Width or
+// Height follows from the other side and the aspect ratio; both zero keeps
+// the original size.
+type Transform struct {
+	Width  int
+	Height int
+	Fit    Fit
+	Format string
+}
+
+// Validate checks t against the largest side allowed.
+func (t Transform) Validate(maxDimension int) error {
+	if t.Width < 0 || t.Height < 0 || t.Width > maxDimension || t.Height > maxDimension {
+		return fmt.Errorf("width and height must be between 0 and %d", maxDimension)
+	}
+	switch t.Fit {
+	case FitContain, FitCover, FitFill:
+	default:
+		return fmt.Errorf("unknown fit %q, want contain, cover or fill", t.Fit)
+	}
+	switch t.Format {
+	case FormatAuto, FormatJPEG, FormatPNG, FormatWebP:
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedFormat, t.Format)
+	}
+	return nil
+}
+
+// Rendered is an encoded image produced by a transform.
+type Rendered struct {
+	Data        []byte
+	ContentType string
+	Width       int
+	Height      int
+}
+
+// checkPixels reads the size of the image in data from its header alone
+// and refuses images of more than maxPixels, which would take too much
+// memory to decode.
+func checkPixels(data []byte, maxPixels int64) error {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrNotImage, err)
+	}
+	if pixels := int64(config.Width) * int64(config.Height); pixels > maxPixels {
+		return fmt.Errorf("%w: %dx%d image has more than %d pixels", ErrTooLarge, config.Width, config.Height, maxPixels)
+	}
+	return nil
+}
+
+// decode reads an image of at most maxPixels, turned upright according to
+// its EXIF orientation.
+func decode(data []byte, maxPixels int64) (image.Image, string, error) {
+	if err := checkPixels(data, maxPixels); err != nil {
+		return nil, "", err
+	}
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrNotImage, err)
+	}
+	if format == FormatJPEG {
+		img = orient(img, exifOrientation(data))
+	}
+	return img, format, nil
+}
+
+// render applies t to the image in data and encodes the result, which
+// carries no metadata since the encoders write none.
+func render(data []byte, t Transform, options ImageOptions) (Rendered, error) {
+	src, sourceFormat, err := decode(data, options.MaxPixels)
+	if err != nil {
+		return Rendered{}, err
+	}
+
+	img := resize(src, t, options.MaxDimension)
+	format := t.Format
+	if format == FormatAuto {
+		format = autoFormat(sourceFormat, img)
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case FormatJPEG:
+		err = jpeg.Encode(&buf, flatten(img), &jpeg.Options{Quality: options.Quality})
+	case FormatPNG:
+		err = png.Encode(&buf, img)
+	case FormatWebP:
+		err = encodeWebP(&buf, img)
+	default:
+		err = fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+	}
+	if err != nil {
+		return Rendered{}, err
+	}
+
+	bounds := img.Bounds()
+	return Rendered{
+		Data:        buf.Bytes(),
+		ContentType: "image/" + format,
+		Width:       bounds.Dx(),
+		Height:      bounds.Dy(),
+	}, nil
+}
+
+func autoFormat(sourceFormat string, img image.Image) string {
+	if sourceFormat == FormatJPEG || sourceFormat == FormatPNG {
+		return sourceFormat
+	}
+	if opaque, ok := img.(interface{ Opaque() bool }); ok && !opaque.Opaque() {
+		return FormatPNG
+	}
+	return FormatJPEG
+}
+
+// resize scales src as t asks, never to less than one pixel a side nor to
+// more than maxDimension.
+func resize(src image.Image, t Transform, maxDimension int) image.Image {
+	bounds := src.Bounds()
+	sw, sh := float64(bounds.Dx()), float64(bounds.Dy())
+	if (t.Width == 0 && t.Height == 0) || sw == 0 || sh == 0 {
+		return src
+	}
+
+	w, h := float64(t.Width), float64(t.Height)
+	crop := bounds
+	switch {
+	case t.Height == 0:
+		h = sh * w / sw
+	case t.Width == 0:
+		w = sw * h / sh
+	case t.Fit == FitContain:
+		scale := math.Min(w/sw, h/sh)
+		w, h = sw*scale, sh*scale
+	case t.Fit == FitCover:
+		scale := math.Max(w/sw, h/sh)
+		cw, ch := int(math.Round(w/scale)), int(math.Round(h/scale))
+		x := bounds.Min.X + (bounds.Dx()-cw)/2
+		y := bounds.Min.Y + (bounds.Dy()-ch)/2
+		crop = image.Rect(x, y, x+cw, y+ch)
+	}
+	// the side that follows from the aspect ratio of a narrow image can
+	// come out far longer than the one asked for
+	if longest := math.Max(w, h); longest > float64(maxDimension) {
+		w, h = w*float64(maxDimension)/longest, h*float64(maxDimension)/longest
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, atLeastOne(w), atLeastOne(h)))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, crop, draw.Src, nil)
+	return dst
+}
+
+func atLeastOne(v float64) int {
+	if n := int(math.Round(v)); n > 1 {
+		return n
+	}
+	return 1
+}
+
+// flatten draws img over white, since JPEG has no transparency.
+func flatten(img image.Image) image.Image {
+	if opaque, ok := img.(interface{ Opaque() bool }); ok && opaque.Opaque() {
+		return img
+	}
+	dst := image.NewRGBA(img.Bounds())
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, img.Bounds().Min, draw.Over)
+	return dst
+}
+
+// orient turns an image stored with the given EXIF orientation upright.
+func orient(src image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return src
+	}
+
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	rgba := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(rgba, rgba.Bounds(), src, b.Min, draw.Src)
+
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // mirrored horizontally
+				dx, dy = w-1-x, y
+			case 3: // rotated 180°
+				dx, dy = w-1-x, h-1-y
+			case 4: // mirrored vertically
+				dx, dy = x, h-1-y
+			case 5: // mirrored along the top-left diagonal
+				dx, dy = y, x
+			case 6: // needs a 90° clockwise turn
+				dx, dy = h-1-y, x
+			case 7: // mirrored along the top-right diagonal
+				dx, dy = h-1-y, w-1-x
+			case 8: // needs a 90° counter-clockwise turn
+				dx, dy = y, w-1-x
+			}
+			copy(dst.Pix[dst.PixOffset(dx, dy):][:4], rgba.Pix[rgba.PixOffset(x, y):][:4])
+		}
+	}
+	return dst
+}
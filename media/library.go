@@ -0,0 +1,266 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"net/http"
+	"strconv"
+	"strings"
+
+	_ "golang.org/x/image/webp"
+)
+
+// ErrTooLarge is returned for uploads over the size limit.
+var ErrTooLarge = errors.New("file is too large")
+
+// ErrUnsupportedType is returned for uploads whose content is not of an
+// allowed type.
+var ErrUnsupportedType = errors.New("file type is not allowed")
+
+// DefaultAllowedTypes are the content types accepted when none are
+// configured.
+var DefaultAllowedTypes = []string{"image/jpeg", "image/png", "image/gif", "image/webp"}
+
+// Limits restrict what may be uploaded.
+type Limits struct {
+	MaxSize      int64
+	AllowedTypes []string
+}
+
+// Variant is an image derived from every uploaded image, under a name.
+type Variant struct {
+	Name string
+	Transform
+}
+
+// ImageOptions configure the images derived from uploads. Quality is the
+// JPEG quality, 1 to 100. Images of more than MaxPixels are refused before
+// they are decoded. Resized images are rounded up to a multiple of
+// ResizeStep a side, so that only so many sizes of an image are cached.
+type ImageOptions struct {
+	Variants     []Variant
+	MaxDimension int
+	MaxPixels    int64
+	ResizeStep   int
+	Quality      int
+}
+
+const (
+	defaultMaxDimension = 4096
+	defaultMaxPixels    = 40_000_000
+	defaultResizeStep   = 50
+	defaultQuality      = 85
+)
+
+// Library is the storage of uploaded files together with the limits they
+// are checked against and the images derived from them.
+type Library struct {
+	Storage Storage
+	Limits  Limits
+	Images  ImageOptions
+
+	cache *DiskCache
+}
+
+func NewLibrary(storage Storage, cache *DiskCache, limits Limits, images ImageOptions) *Library {
+	if len(limits.AllowedTypes) == 0 {
+		limits.AllowedTypes = DefaultAllowedTypes
+	}
+	if images.MaxDimension <= 0 {
+		images.MaxDimension = defaultMaxDimension
+	}
+	if images.MaxPixels <= 0 {
+		images.MaxPixels = defaultMaxPixels
+	}
+	if images.ResizeStep <= 0 {
+		images.ResizeStep = defaultResizeStep
+	}
+	if images.Quality <= 0 || images.Quality > 100 {
+		images.Quality = defaultQuality
+	}
+	return &Library{Storage: storage, Limits: limits, Images: images, cache: cache}
+}
+
+// Info is what Inspect learns from the content of a file.
+type Info struct {
+	ContentType string
+	Width       int
+	Height      int
+	Checksum    string
+}
+
+// Prepare checks an upload and returns the content to store with what it
+// learned about it. The content type is sniffed from data, which is trusted
+// over whatever the client claimed. JPEG metadata is stripped, and a JPEG
+// stored sideways per its EXIF orientation is re-encoded upright, so the
+// stored file never depends on metadata it no longer has. Images with more
+// pixels than allowed are refused before anything decodes them.
+func (l *Library) Prepare(data []byte) ([]byte, Info, error) {
+	if int64(len(data)) > l.Limits.MaxSize {
+		return nil, Info{}, ErrTooLarge
+	}
+
+	contentType := sniff(data)
+	if !l.allowed(contentType) {
+		return nil, Info{}, fmt.Errorf("%w: %s", ErrUnsupportedType, contentType)
+	}
+	if IsImage(contentType) {
+		if err := checkPixels(data, l.Images.MaxPixels); errors.Is(err, ErrNotImage) {
+			return nil, Info{}, fmt.Errorf("%w: unreadable %s image", ErrUnsupportedType, contentType)
+		} else if err != nil {
+			return nil, Info{}, err
+		}
+	}
+
+	if contentType == "image/jpeg" {
+		if exifOrientation(data) != 1 {
+			upright, err := render(data, Transform{Fit: FitContain, Format: FormatJPEG}, l.Images)
+			if err != nil {
+				return nil, Info{}, fmt.Errorf("%w: unreadable %s image", ErrUnsupportedType, contentType)
+			}
+			data = upright.Data
+		} else {
+			data = stripJPEGMetadata(data)
+		}
+	}
+
+	sum := sha256.Sum256(data)
+	info := Info{ContentType: contentType, Checksum: hex.EncodeToString(sum[:])}
+	if IsImage(contentType) {
+		config, _, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return nil, Info{}, fmt.Errorf("%w: unreadable %s image", ErrUnsupportedType, contentType)
+		}
+		info.Width, info.Height = config.Width, config.Height
+	}
+	return data, info, nil
+}
+
+// IsImage reports whether content of contentType can be transformed.
+func IsImage(contentType string) bool {
+	return strings.HasPrefix(contentType, "image/")
+}
+
+func sniff(data []byte) string {
+	contentType := http.DetectContentType(data)
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	return contentType
+}
+
+// StoredVariant is a variant rendered and saved under Key.
+type StoredVariant struct {
+	Name        string
+	Key         string
+	ContentType string
+	Width       int
+	Height      int
+	Size        int64
+}
+
+// SaveVariants renders every configured variant of the image in data and
+// saves each under key followed by "_" and its name. Nothing is left saved
+// when one of them fails.
+func (l *Library) SaveVariants(ctx context.Context, key string, data []byte) ([]StoredVariant, error) {
+	var saved []StoredVariant
+	for _, variant := range l.Images.Variants {
+		rendered, err := render(data, variant.Transform, l.Images)
+		if err == nil {
+			stored := StoredVariant{
+				Name:        variant.Name,
+				Key:         key + "_" + variant.Name,
+				ContentType: rendered.ContentType,
+				Width:       rendered.Width,
+				Height:      rendered.Height,
+				Size:        int64(len(rendered.Data)),
+			}
+			if err = l.Storage.Save(ctx, stored.Key, bytes.NewReader(rendered.Data)); err == nil {
+				saved = append(saved, stored)
+				continue
+			}
+		}
+
+		for _, stored := range saved {
+			l.Storage.Delete(ctx, stored.Key)
+		}
+		return nil, fmt.Errorf("variant %s: %w", variant.Name, err)
+	}
+	return saved, nil
+}
+
+// Round rounds the width and height of t up to the next multiple of the
+// resize step, without going over the largest dimension. Every transform
+// is rounded before it is rendered and cached.
+func (l *Library) Round(t Transform) Transform {
+	t.Width = l.roundUp(t.Width)
+	t.Height = l.roundUp(t.Height)
+	return t
+}
+
+func (l *Library) roundUp(side int) int {
+	if side <= 0 {
+		return side
+	}
+	step := l.Images.ResizeStep
+	if side = (side + step - 1) / step * step; side > l.Images.MaxDimension {
+		return l.Images.MaxDimension
+	}
+	return side
+}
+
+// Resize renders t, rounded, of the image stored under key, reading it
+// with load only when the result is not cached yet. Cached results are
+// returned without their dimensions.
+func (l *Library) Resize(key string, t Transform, load func() ([]byte, error)) (Rendered, error) {
+	t = l.Round(t)
+	name := cacheName(key, t)
+	if data, ok, err := l.cache.Get(name); err != nil {
+		return Rendered{}, err
+	} else if ok {
+		return Rendered{Data: data, ContentType: sniff(data)}, nil
+	}
+
+	data, err := load()
+	if err != nil {
+		return Rendered{}, err
+	}
+	rendered, err := render(data, t, l.Images)
+	if err != nil {
+		return Rendered{}, err
+	}
+	if err := l.cache.Put(name, rendered.Data); err != nil {
+		return Rendered{}, err
+	}
+	return rendered, nil
+}
+
+// Forget drops the cached renderings of the image stored under key.
+func (l *Library) Forget(key string) error {
+	return l.cache.Forget(key + "_")
+}
+
+func cacheName(key string, t Transform) string {
+	format := t.Format
+	if format == FormatAuto {
+		format = "auto"
+	}
+	return key + "_" + strconv.Itoa(t.Width) + "x" + strconv.Itoa(t.Height) + "_" + string(t.Fit) + "." + format
+}
+
+func (l *Library) allowed(contentType string) bool {
+	for _, allowed := range l.Limits.AllowedTypes {
+		if allowed == contentType {
+			return true
+		}
+	}
+	return false
+}
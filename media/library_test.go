@@ -0,0 +1,290 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"testing"
+)
+
+// testImage draws a w×h gradient so encoders cannot compress it away.
+func testImage(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x * 255 / w), G: uint8(y * 255 / h), B: 128, A: 255})
+		}
+	}
+	return img
+}
+
+func testPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, testImage(w, h)); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// testJPEG encodes a w×h JPEG carrying an EXIF segment with orientation.
+func testJPEG(t *testing.T, w, h int, orientation uint16) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, testImage(w, h), nil); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08" + // big endian, first IFD at 8
+		"\x00\x01" + // one entry
+		"\x01\x12\x00\x03\x00\x00\x00\x01" + // orientation, SHORT, one value
+		string([]byte{byte(orientation >> 8), byte(orientation), 0, 0}) +
+		"\x00\x00\x00\x00") // no next IFD
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := append([]byte{0xFF, markerAPP1, byte((len(payload) + 2) >> 8), byte(len(payload) + 2)}, payload...)
+	return append(append(append([]byte{}, data[:2]...), segment...), data[2:]...)
+}
+
+func newTestLibrary(t *testing.T, images ImageOptions) *Library {
+	t.Helper()
+	storage, err := NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache, err := NewDiskCache(t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewLibrary(storage, cache, Limits{MaxSize: 1 << 20}, images)
+}
+
+func TestPrepare(t *testing.T) {
+	library := newTestLibrary(t, ImageOptions{})
+
+	data, info, err := library.Prepare(testPNG(t, 30, 20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.ContentType != "image/png" || info.Width != 30 || info.Height != 20 || len(info.Checksum) != 64 {
+		t.Errorf("png info = %+v", info)
+	}
+	if !bytes.Equal(data, testPNG(t, 30, 20)) {
+		t.Error("png content changed")
+	}
+
+	if _, _, err := library.Prepare([]byte("<html><script>alert(1)</script></html>")); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("html: %v, want ErrUnsupportedType", err)
+	}
+	// a png signature followed by garbage sniffs as png but is no image
+	broken := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{1}, 64)...)
+	if _, _, err := library.Prepare(broken); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("broken png: %v, want ErrUnsupportedType", err)
+	}
+
+	library.Limits.MaxSize = 10
+	if _, _, err := library.Prepare(testPNG(t, 30, 20)); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("over the size limit: %v, want ErrTooLarge", err)
+	}
+}
+
+func TestPrepareJPEGMetadata(t *testing.T) {
+	library := newTestLibrary(t, ImageOptions{})
+
+	upright := testJPEG(t, 30, 20, 1)
+	data, info, err := library.Prepare(upright)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("Exif")) || len(data) >= len(upright) {
+		t.Error("EXIF segment kept")
+	}
+	if info.Width != 30 || info.Height != 20 {
+		t.Errorf("upright jpeg = %dx%d", info.Width, info.Height)
+	}
+
+	// orientation 6 is stored sideways and needs a quarter turn
+	data, info, err = library.Prepare(testJPEG(t, 30, 20, 6))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("Exif")) || info.Width != 20 || info.Height != 30 {
+		t.Errorf("rotated jpeg = %dx%d, exif kept %v", info.Width, info.Height, bytes.Contains(data, []byte("Exif")))
+	}
+}
+
+func TestPrepareRefusesTooManyPixels(t *testing.T) {
+	ctx := context.Background()
+	library := newTestLibrary(t, ImageOptions{MaxPixels: 30 * 20})
+	if _, _, err := library.Prepare(testPNG(t, 30, 20)); err != nil {
+		t.Errorf("image at the pixel limit: %v", err)
+	}
+
+	library.Images.MaxPixels = 30*20 - 1
+	uploads := map[string][]byte{
+		"png":          testPNG(t, 30, 20),
+		"jpeg":         testJPEG(t, 30, 20, 1),
+		"rotated jpeg": testJPEG(t, 30, 20, 6),
+	}
+	for name, data := range uploads {
+		if _, _, err := library.Prepare(data); !errors.Is(err, ErrTooLarge) {
+			t.Errorf("%s over the pixel limit: %v, want ErrTooLarge", name, err)
+		}
+	}
+
+	library.Images.Variants = []Variant{{Name: "thumb", Transform: Transform{Width: 10, Fit: FitContain}}}
+	if _, err := library.SaveVariants(ctx, "key", testPNG(t, 30, 20)); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("variants over the pixel limit: %v, want ErrTooLarge", err)
+	}
+	load := func() ([]byte, error) { return testPNG(t, 30, 20), nil }
+	if _, err := library.Resize("key", Transform{Width: 10, Fit: FitContain}, load); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("resize over the pixel limit: %v, want ErrTooLarge", err)
+	}
+}
+
+func TestResize(t *testing.T) {
+	src := testImage(40, 20)
+	tests := []struct {
+		t    Transform
+		w, h int
+	}{
+		{Transform{}, 40, 20},
+		{Transform{Width: 20}, 20, 10},
+		{Transform{Height: 5}, 10, 5},
+		{Transform{Width: 10, Height: 10, Fit: FitContain}, 10, 5},
+		{Transform{Width: 10, Height: 10, Fit: FitCover}, 10, 10},
+		{Transform{Width: 10, Height: 30, Fit: FitFill}, 10, 30},
+		{Transform{Width: 1, Height: 1, Fit: FitContain}, 1, 1},
+		{Transform{Height: 64}, 64, 32},
+		{Transform{Width: 40, Height: 64, Fit: FitCover}, 40, 64},
+	}
+	for _, tt := range tests {
+		bounds := resize(src, tt.t, 64).Bounds()
+		if bounds.Dx() != tt.w || bounds.Dy() != tt.h {
+			t.Errorf("%+v = %dx%d, want %dx%d", tt.t, bounds.Dx(), bounds.Dy(), tt.w, tt.h)
+		}
+	}
+}
+
+func TestResizeCapsDerivedSide(t *testing.T) {
+	tests := []struct {
+		src  image.Image
+		t    Transform
+		w, h int
+	}{
+		{testImage(1, 100), Transform{Width: 40}, 1, 64},
+		{testImage(100, 1), Transform{Height: 40}, 64, 1},
+		{testImage(2, 100), Transform{Width: 40, Height: 40, Fit: FitContain}, 1, 40},
+	}
+	for _, tt := range tests {
+		bounds := resize(tt.src, tt.t, 64).Bounds()
+		if bounds.Dx() != tt.w || bounds.Dy() != tt.h {
+			t.Errorf("%v %+v = %dx%d, want %dx%d", tt.src.Bounds().Size(), tt.t, bounds.Dx(), bounds.Dy(), tt.w, tt.h)
+		}
+	}
+}
+
+func TestTransformValidate(t *testing.T) {
+	valid := []Transform{
+		{Fit: FitContain},
+		{Width: 64, Height: 64, Fit: FitCover, Format: FormatPNG},
+	}
+	for _, tt := range valid {
+		if err := tt.Validate(64); err != nil {
+			t.Errorf("%+v: %v", tt, err)
+		}
+	}
+	invalid := []Transform{
+		{Width: 65, Fit: FitContain},
+		{Height: -1, Fit: FitContain},
+		{Fit: "stretch"},
+		{Fit: FitContain, Format: "gif"},
+	}
+	for _, tt := range invalid {
+		if err := tt.Validate(64); err == nil {
+			t.Errorf("%+v is valid", tt)
+		}
+	}
+}
+
+func TestSaveVariants(t *testing.T) {
+	ctx := context.Background()
+	library := newTestLibrary(t, ImageOptions{Variants: []Variant{
+		{Name: "thumb", Transform: Transform{Width: 10, Height: 10, Fit: FitCover, Format: FormatJPEG}},
+		{Name: "wide", Transform: Transform{Width: 20, Fit: FitContain}},
+	}})
+
+	variants, err := library.SaveVariants(ctx, "key", testPNG(t, 40, 20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(variants) != 2 {
+		t.Fatalf("got %d variants", len(variants))
+	}
+	thumb, wide := variants[0], variants[1]
+	if thumb.Key != "key_thumb" || thumb.ContentType != "image/jpeg" || thumb.Width != 10 || thumb.Height != 10 {
+		t.Errorf("thumb = %+v", thumb)
+	}
+	if wide.Key != "key_wide" || wide.ContentType != "image/png" || wide.Width != 20 || wide.Height != 10 {
+		t.Errorf("wide = %+v", wide)
+	}
+	for _, variant := range variants {
+		file, err := library.Storage.Open(ctx, variant.Key)
+		if err != nil {
+			t.Errorf("%s not stored: %v", variant.Name, err)
+			continue
+		}
+		file.Close()
+	}
+}
+
+func TestRound(t *testing.T) {
+	library := newTestLibrary(t, ImageOptions{MaxDimension: 64, ResizeStep: 10})
+	tests := []struct {
+		in, want Transform
+	}{
+		{Transform{}, Transform{}},
+		{Transform{Width: 1}, Transform{Width: 10}},
+		{Transform{Width: 10, Height: 11}, Transform{Width: 10, Height: 20}},
+		{Transform{Width: 61, Height: 64}, Transform{Width: 64, Height: 64}},
+	}
+	for _, tt := range tests {
+		if got := library.Round(tt.in); got != tt.want {
+			t.Errorf("Round(%+v) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestResizeCaches(t *testing.T) {
+	library := newTestLibrary(t, ImageOptions{ResizeStep: 5})
+	loads := 0
+	load := func() ([]byte, error) {
+		loads++
+		return testPNG(t, 40, 20), nil
+	}
+
+	first, err := library.Resize("key", Transform{Width: 10, Fit: FitContain}, load)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Width != 10 || first.Height != 5 || first.ContentType != "image/png" {
+		t.Errorf("first = %+v", first)
+	}
+	// 9 rounds up to the same size
+	second, err := library.Resize("key", Transform{Width: 9, Fit: FitContain}, load)
+	if err != nil || loads != 1 || !bytes.Equal(first.Data, second.Data) || second.ContentType != "image/png" {
+		t.Errorf("second resize loaded %d times, %v", loads, err)
+	}
+
+	if err := library.Forget("key"); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := os.ReadDir(library.cache.dir); len(entries) != 0 {
+		t.Errorf("%d cache entries left after Forget", len(entries))
+	}
+}
@@ -0,0 +1,58 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLocalStorage(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	storage, err := NewLocalStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := storage.Save(ctx, "abc", strings.NewReader("content")); err != nil {
+		t.Fatal(err)
+	}
+	file, err := storage.Open(ctx, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(file)
+	file.Close()
+	if string(data) != "content" {
+		t.Errorf("read back %q", data)
+	}
+
+	if err := storage.Delete(ctx, "abc"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := storage.Open(ctx, "abc"); !errors.Is(err, ErrNotExist) {
+		t.Errorf("open after delete: %v, want ErrNotExist", err)
+	}
+	if err := storage.Delete(ctx, "abc"); !errors.Is(err, ErrNotExist) {
+		t.Errorf("second delete: %v, want ErrNotExist", err)
+	}
+
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("%d files left behind", len(entries))
+	}
+}
+
+func TestLocalStorageRejectsPaths(t *testing.T) {
+	storage, err := NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"", "../escape", "a/b", ".hidden", ".."} {
+		if err := storage.Save(context.Background(), key, strings.NewReader("x")); err == nil {
+			t.Errorf("saved under %q", key)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+// Package media stores uploaded files and checks what they contain.
+package media
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrNotExist is returned when no file is stored under a key.
+var ErrNotExist = errors.New("media file does not exist")
+
+// Storage keeps the content of uploaded files under keys chosen by the
+// caller. Implementations must be safe for concurrent use.
+type Storage interface {
+	Save(ctx context.Context, key string, r io.Reader) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,546 @@
+package media
+
+import (
+	"container/heap"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/draw"
+	"io"
+)
+
+// WebP variants are encoded lossless (VP8L), the only WebP flavour that can
+// be written without a port of the lossy VP8 codec. The encoder keeps to
+// the parts of the format that pay off most for little code: the subtract
+// green and predictor transforms, a single set of prefix codes and
+// backward references for runs of equal pixels.
+
+// maxWebPDimension is the largest width or height VP8L can store.
+const maxWebPDimension = 1 << 14
+
+const (
+	webpPredictorBits = 4 // predictor modes are chosen per 16x16 tile
+	webpMaxRun        = 4096
+	webpMinRun        = 3
+
+	webpLiteralCodes  = 256
+	webpLengthCodes   = 24
+	webpDistanceCodes = 40
+)
+
+// webpPredictors are the predictor modes tried on every tile: left, top,
+// the average of both and the gradient left + top - top left.
+var webpPredictors = [...]uint32{1, 2, 7, 12}
+
+// webpCodeLengthOrder is the order code length code lengths are written in.
+var webpCodeLengthOrder = [19]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// encodeWebP writes img to w as a lossless WebP image.
+func encodeWebP(w io.Writer, img image.Image) error {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width < 1 || height < 1 || width > maxWebPDimension || height > maxWebPDimension {
+		return fmt.Errorf("%w: webp images are at most %d pixels a side", ErrUnsupportedFormat, maxWebPDimension)
+	}
+
+	nrgba := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(nrgba, nrgba.Bounds(), img, bounds.Min, draw.Src)
+	argb := make([]uint32, width*height)
+	alpha := false
+	for i := range argb {
+		p := nrgba.Pix[4*i : 4*i+4]
+		argb[i] = uint32(p[3])<<24 | uint32(p[0])<<16 | uint32(p[1])<<8 | uint32(p[2])
+		alpha = alpha || p[3] != 0xff
+	}
+
+	var bw bitWriter
+	bw.write(0x2f, 8)
+	bw.write(uint32(width-1), 14)
+	bw.write(uint32(height-1), 14)
+	bw.writeBool(alpha)
+	bw.write(0, 3) // version
+
+	// the decoder undoes the transforms in reverse order
+	bw.writeBool(true)
+	bw.write(2, 2) // subtract green
+	subtractGreen(argb)
+	bw.writeBool(true)
+	bw.write(0, 2) // predictor
+	bw.write(webpPredictorBits-2, 3)
+	modes, residuals := predict(argb, width, height)
+	writeEntropyImage(&bw, modes, false)
+	bw.writeBool(false)
+
+	writeEntropyImage(&bw, residuals, true)
+	data := bw.bytes()
+
+	pad := len(data) & 1
+	header := make([]byte, 20)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(12+len(data)+pad))
+	copy(header[8:], "WEBPVP8L")
+	binary.LittleEndian.PutUint32(header[16:], uint32(len(data)))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if pad == 1 {
+		_, err := w.Write([]byte{0})
+		return err
+	}
+	return nil
+}
+
+// subtractGreen takes green out of red and blue, which tend to follow it.
+func subtractGreen(argb []uint32) {
+	for i, p := range argb {
+		green := (p >> 8) & 0xff
+		argb[i] = subPixels(p, green<<16|green)
+	}
+}
+
+// predict chooses the predictor mode of every tile and returns the modes,
+// as an image of one pixel a tile, and what is left of each pixel after
+// its prediction.
+func predict(argb []uint32, width, height int) (modes []uint32, residuals []uint32) {
+	tiles := func(n int) int { return (n + 1<<webpPredictorBits - 1) >> webpPredictorBits }
+	tilesX, tilesY := tiles(width), tiles(height)
+	modes = make([]uint32, tilesX*tilesY)
+	residuals = make([]uint32, len(argb))
+
+	for ty := 0; ty < tilesY; ty++ {
+		for tx := 0; tx < tilesX; tx++ {
+			best, bestCost := webpPredictors[0], -1
+			for _, mode := range webpPredictors {
+				cost := 0
+				forTile(tx, ty, width, height, func(x, y int) {
+					cost += residualCost(subPixels(argb[y*width+x], predictPixel(argb, width, x, y, mode)))
+				})
+				if bestCost < 0 || cost < bestCost {
+					best, bestCost = mode, cost
+				}
+			}
+			modes[ty*tilesX+tx] = 0xff000000 | best<<8
+		}
+	}
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			mode := modes[(y>>webpPredictorBits)*tilesX+x>>webpPredictorBits] >> 8 & 0xf
+			residuals[y*width+x] = subPixels(argb[y*width+x], predictPixel(argb, width, x, y, mode))
+		}
+	}
+	return modes, residuals
+}
+
+// forTile calls f for the pixels of a tile that use its predictor mode;
+// the top row and left column are always predicted the same way.
+func forTile(tx, ty, width, height int, f func(x, y int)) {
+	size := 1 << webpPredictorBits
+	for y := ty * size; y < (ty+1)*size && y < height; y++ {
+		for x := tx * size; x < (tx+1)*size && x < width; x++ {
+			if x > 0 && y > 0 {
+				f(x, y)
+			}
+		}
+	}
+}
+
+// predictPixel predicts the pixel at x, y from its left and upper
+// neighbours with mode, following the rules of the decoder for the edges.
+func predictPixel(argb []uint32, width, x, y int, mode uint32) uint32 {
+	switch {
+	case x == 0 && y == 0:
+		return 0xff000000
+	case y == 0:
+		return argb[x-1]
+	case x == 0:
+		return argb[(y-1)*width]
+	}
+	left, top, topLeft := argb[y*width+x-1], argb[(y-1)*width+x], argb[(y-1)*width+x-1]
+	switch mode {
+	case 1:
+		return left
+	case 2:
+		return top
+	case 7:
+		return ((left^top)&0xfefefefe)>>1 + left&top
+	default:
+		var p uint32
+		for shift := 0; shift < 32; shift += 8 {
+			c := int((left>>shift)&0xff) + int((top>>shift)&0xff) - int((topLeft>>shift)&0xff)
+			if c < 0 {
+				c = 0
+			} else if c > 0xff {
+				c = 0xff
+			}
+			p |= uint32(c) << shift
+		}
+		return p
+	}
+}
+
+// residualCost estimates how many bits a residual takes, by how far its
+// channels are from zero.
+func residualCost(p uint32) int {
+	cost := 0
+	for shift := 0; shift < 32; shift += 8 {
+		c := int(int8(p >> shift))
+		if c < 0 {
+			c = -c
+		}
+		cost += c
+	}
+	return cost
+}
+
+// subPixels subtracts b from a channel by channel, modulo 256.
+func subPixels(a, b uint32) uint32 {
+	alphaGreen := 0x00ff00ff + a&0xff00ff00 - b&0xff00ff00
+	redBlue := 0xff00ff00 + a&0x00ff00ff - b&0x00ff00ff
+	return alphaGreen&0xff00ff00 | redBlue&0x00ff00ff
+}
+
+// writeEntropyImage writes the pixels with a single set of prefix codes.
+// Runs of a pixel equal to the one before it are written as backward
+// references to the pixel on the left. Only the main image says whether it
+// uses more than one set of codes.
+func writeEntropyImage(bw *bitWriter, argb []uint32, topLevel bool) {
+	type symbol struct {
+		literal uint32
+		run     int
+	}
+	var symbols []symbol
+	green := make([]int, webpLiteralCodes+webpLengthCodes)
+	red, blue, alpha := make([]int, 256), make([]int, 256), make([]int, 256)
+	distance := make([]int, webpDistanceCodes)
+	for i := 0; i < len(argb); {
+		run := 0
+		if i > 0 {
+			for i+run < len(argb) && run < webpMaxRun && argb[i+run] == argb[i-1] {
+				run++
+			}
+		}
+		if run >= webpMinRun {
+			code, _, _ := lz77Prefix(run)
+			green[webpLiteralCodes+code]++
+			distance[webpLeftDistance]++
+			symbols = append(symbols, symbol{run: run})
+			i += run
+			continue
+		}
+		p := argb[i]
+		green[p>>8&0xff]++
+		red[p>>16&0xff]++
+		blue[p&0xff]++
+		alpha[p>>24]++
+		symbols = append(symbols, symbol{literal: p})
+		i++
+	}
+
+	bw.writeBool(false) // no color cache
+	if topLevel {
+		bw.writeBool(false) // one set of prefix codes for the whole image
+	}
+	codes := [5]prefixCode{
+		newPrefixCode(green, 15),
+		newPrefixCode(red, 15),
+		newPrefixCode(blue, 15),
+		newPrefixCode(alpha, 15),
+		newPrefixCode(distance, 15),
+	}
+	for _, code := range codes {
+		bw.writePrefixCode(code)
+	}
+
+	for _, s := range symbols {
+		if s.run > 0 {
+			code, extraBits, extra := lz77Prefix(s.run)
+			codes[0].write(bw, webpLiteralCodes+code)
+			bw.write(extra, extraBits)
+			codes[4].write(bw, webpLeftDistance)
+			continue
+		}
+		p := s.literal
+		codes[0].write(bw, int(p>>8&0xff))
+		codes[1].write(bw, int(p>>16&0xff))
+		codes[2].write(bw, int(p&0xff))
+		codes[3].write(bw, int(p>>24))
+	}
+}
+
+// webpLeftDistance is the distance symbol of the pixel on the left: plane
+// code 2, the second entry of the distance map, with no extra bits.
+const webpLeftDistance = 1
+
+// lz77Prefix splits a backward reference length or distance into its
+// prefix symbol and extra bits.
+func lz77Prefix(value int) (symbol int, extraBits uint, extra uint32) {
+	d := value - 1
+	if d < 4 {
+		return d, 0, 0
+	}
+	high := 0
+	for d>>(high+1) != 0 {
+		high++
+	}
+	second := (d >> (high - 1)) & 1
+	extraBits = uint(high - 1)
+	return 2*high + second, extraBits, uint32(d) & (1<<extraBits - 1)
+}
+
+// prefixCode is a canonical Huffman code. A code of a single symbol takes
+// no bits at all.
+type prefixCode struct {
+	lengths []uint8
+	codes   []uint32 // bit reversed, ready for the LSB first bit writer
+	single  bool
+}
+
+// newPrefixCode builds the code for symbols seen counts times, no code
+// longer than maxLength bits.
+func newPrefixCode(counts []int, maxLength int) prefixCode {
+	code := prefixCode{lengths: huffmanLengths(counts, maxLength), codes: make([]uint32, len(counts))}
+	used := 0
+	for _, length := range code.lengths {
+		if length > 0 {
+			used++
+		}
+	}
+	code.single = used == 1
+
+	var lengthCount [16]uint32
+	for _, length := range code.lengths {
+		lengthCount[length]++
+	}
+	lengthCount[0] = 0
+	var next [16]uint32
+	for length, c := 1, uint32(0); length < len(next); length++ {
+		c = (c + lengthCount[length-1]) << 1
+		next[length] = c
+	}
+	for symbol, length := range code.lengths {
+		if length == 0 {
+			continue
+		}
+		c := next[length]
+		next[length]++
+		var reversed uint32
+		for i := uint8(0); i < length; i++ {
+			reversed = reversed<<1 | c>>i&1
+		}
+		code.codes[symbol] = reversed
+	}
+	return code
+}
+
+func (c prefixCode) write(bw *bitWriter, symbol int) {
+	if !c.single {
+		bw.write(c.codes[symbol], uint(c.lengths[symbol]))
+	}
+}
+
+// huffmanLengths returns the code length of every symbol. Counts are
+// flattened until the longest code fits in maxLength bits.
+func huffmanLengths(counts []int, maxLength int) []uint8 {
+	counts = append([]int(nil), counts...)
+	lengths := make([]uint8, len(counts))
+	for {
+		h := &huffmanHeap{}
+		for symbol, count := range counts {
+			if count > 0 {
+				h.nodes = append(h.nodes, huffmanNode{count: count, symbol: symbol, parent: -1})
+				h.order = append(h.order, len(h.nodes)-1)
+			}
+		}
+		switch len(h.order) {
+		case 0:
+			return lengths
+		case 1:
+			lengths[h.nodes[0].symbol] = 1
+			return lengths
+		}
+
+		leaves := len(h.nodes)
+		heap.Init(h)
+		for h.Len() > 1 {
+			a, b := heap.Pop(h).(int), heap.Pop(h).(int)
+			h.nodes = append(h.nodes, huffmanNode{count: h.nodes[a].count + h.nodes[b].count, symbol: -1, parent: -1})
+			h.nodes[a].parent, h.nodes[b].parent = len(h.nodes)-1, len(h.nodes)-1
+			heap.Push(h, len(h.nodes)-1)
+		}
+
+		longest := 0
+		for i := 0; i < leaves; i++ {
+			depth := 0
+			for n := i; h.nodes[n].parent >= 0; n = h.nodes[n].parent {
+				depth++
+			}
+			lengths[h.nodes[i].symbol] = uint8(depth)
+			if depth > longest {
+				longest = depth
+			}
+		}
+		if longest <= maxLength {
+			return lengths
+		}
+		for symbol, count := range counts {
+			if count > 0 {
+				counts[symbol] = (count + 1) / 2
+			}
+		}
+	}
+}
+
+type huffmanNode struct {
+	count  int
+	symbol int
+	parent int
+}
+
+// huffmanHeap orders node indexes by count.
+type huffmanHeap struct {
+	nodes []huffmanNode
+	order []int
+}
+
+func (h *huffmanHeap) Len() int { return len(h.order) }
+func (h *huffmanHeap) Less(i, j int) bool {
+	return h.nodes[h.order[i]].count < h.nodes[h.order[j]].count
+}
+func (h *huffmanHeap) Swap(i, j int)      { h.order[i], h.order[j] = h.order[j], h.order[i] }
+func (h *huffmanHeap) Push(x interface{}) { h.order = append(h.order, x.(int)) }
+func (h *huffmanHeap) Pop() interface{} {
+	last := h.order[len(h.order)-1]
+	h.order = h.order[:len(h.order)-1]
+	return last
+}
+
+// bitWriter packs bits least significant first, as VP8L reads them.
+type bitWriter struct {
+	buf   []byte
+	bits  uint64
+	nBits uint
+}
+
+func (bw *bitWriter) write(value uint32, n uint) {
+	bw.bits |= uint64(value) << bw.nBits
+	bw.nBits += n
+	for bw.nBits >= 8 {
+		bw.buf = append(bw.buf, byte(bw.bits))
+		bw.bits >>= 8
+		bw.nBits -= 8
+	}
+}
+
+func (bw *bitWriter) writeBool(b bool) {
+	if b {
+		bw.write(1, 1)
+	} else {
+		bw.write(0, 1)
+	}
+}
+
+func (bw *bitWriter) bytes() []byte {
+	if bw.nBits > 0 {
+		bw.buf = append(bw.buf, byte(bw.bits))
+		bw.bits, bw.nBits = 0, 0
+	}
+	return bw.buf
+}
+
+// writePrefixCode writes how c is built: as a simple code when it has at
+// most two symbols that fit in a byte, otherwise by its code lengths, which
+// are run length encoded and written with a code of their own.
+func (bw *bitWriter) writePrefixCode(c prefixCode) {
+	var used []int
+	for symbol, length := range c.lengths {
+		if length > 0 {
+			used = append(used, symbol)
+		}
+	}
+	if len(used) <= 2 && (len(used) == 0 || used[len(used)-1] < 256) {
+		if len(used) == 0 {
+			used = []int{0} // never written, any symbol will do
+		}
+		bw.writeBool(true)
+		bw.write(uint32(len(used)-1), 1)
+		if used[0] < 2 {
+			bw.write(0, 1)
+			bw.write(uint32(used[0]), 1)
+		} else {
+			bw.write(1, 1)
+			bw.write(uint32(used[0]), 8)
+		}
+		if len(used) == 2 {
+			bw.write(uint32(used[1]), 8)
+		}
+		return
+	}
+
+	type token struct {
+		symbol    int
+		extra     uint32
+		extraBits uint
+	}
+	var tokens []token
+	lengths := c.lengths
+	for i := 0; i < len(lengths); {
+		value, run := lengths[i], 1
+		for i+run < len(lengths) && lengths[i+run] == value {
+			run++
+		}
+		i += run
+		if value == 0 {
+			for run >= 11 {
+				n := run
+				if n > 138 {
+					n = 138
+				}
+				tokens = append(tokens, token{18, uint32(n - 11), 7})
+				run -= n
+			}
+			if run >= 3 {
+				tokens = append(tokens, token{17, uint32(run - 3), 3})
+				run = 0
+			}
+		} else {
+			tokens = append(tokens, token{symbol: int(value)})
+			run--
+			for run >= 3 {
+				n := run
+				if n > 6 {
+					n = 6
+				}
+				tokens = append(tokens, token{16, uint32(n - 3), 2})
+				run -= n
+			}
+		}
+		for ; run > 0; run-- {
+			tokens = append(tokens, token{symbol: int(value)})
+		}
+	}
+
+	counts := make([]int, len(webpCodeLengthOrder))
+	for _, t := range tokens {
+		counts[t.symbol]++
+	}
+	lengthCode := newPrefixCode(counts, 7)
+	n := len(webpCodeLengthOrder)
+	for n > 4 && lengthCode.lengths[webpCodeLengthOrder[n-1]] == 0 {
+		n--
+	}
+
+	bw.writeBool(false)
+	bw.write(uint32(n-4), 4)
+	for _, symbol := range webpCodeLengthOrder[:n] {
+		bw.write(uint32(lengthCode.lengths[symbol]), 3)
+	}
+	bw.writeBool(false) // every symbol has a code length
+	for _, t := range tokens {
+		lengthCode.write(bw, t.symbol)
+		bw.write(t.extra, t.extraBits)
+	}
+}
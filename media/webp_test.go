@@ -0,0 +1,115 @@
+package media
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math/rand"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+func TestEncodeWebPRoundTrip(t *testing.T) {
+	noise := rand.New(rand.NewSource(1))
+	tests := []struct {
+		name          string
+		width, height int
+		pixel         func(x, y int) color.NRGBA
+	}{
+		{"single pixel", 1, 1, func(x, y int) color.NRGBA { return color.NRGBA{10, 20, 30, 255} }},
+		{"flat", 40, 30, func(x, y int) color.NRGBA { return color.NRGBA{200, 100, 50, 255} }},
+		{"gradient", 67, 45, func(x, y int) color.NRGBA { return color.NRGBA{uint8(x * 3), uint8(y * 5), uint8(x + y), 255} }},
+		{"transparent", 33, 17, func(x, y int) color.NRGBA { return color.NRGBA{uint8(x), 0, uint8(y), uint8(x * y)} }},
+		{"noise", 50, 50, func(x, y int) color.NRGBA {
+			return color.NRGBA{uint8(noise.Intn(256)), uint8(noise.Intn(256)), uint8(noise.Intn(256)), uint8(noise.Intn(256))}
+		}},
+		{"stripes", 100, 20, func(x, y int) color.NRGBA {
+			if x%7 < 3 {
+				return color.NRGBA{255, 255, 255, 255}
+			}
+			return color.NRGBA{0, 0, 0, 255}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := image.NewNRGBA(image.Rect(0, 0, tt.width, tt.height))
+			for y := 0; y < tt.height; y++ {
+				for x := 0; x < tt.width; x++ {
+					src.SetNRGBA(x, y, tt.pixel(x, y))
+				}
+			}
+
+			var buf bytes.Buffer
+			if err := encodeWebP(&buf, src); err != nil {
+				t.Fatal(err)
+			}
+			decoded, err := webp.Decode(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if decoded.Bounds() != src.Bounds() {
+				t.Fatalf("bounds = %v, want %v", decoded.Bounds(), src.Bounds())
+			}
+			for y := 0; y < tt.height; y++ {
+				for x := 0; x < tt.width; x++ {
+					got := color.NRGBAModel.Convert(decoded.At(x, y)).(color.NRGBA)
+					if want := src.NRGBAAt(x, y); got != want {
+						t.Fatalf("pixel %d,%d = %v, want %v", x, y, got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestRenderWebP(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 120, 80))
+	for y := 0; y < 80; y++ {
+		for x := 0; x < 120; x++ {
+			src.Set(x, y, color.RGBA{uint8(x), uint8(y), 128, 255})
+		}
+	}
+	var data bytes.Buffer
+	if err := png.Encode(&data, src); err != nil {
+		t.Fatal(err)
+	}
+
+	transform := Transform{Width: 60, Fit: FitContain, Format: FormatWebP}
+	if err := transform.Validate(4096); err != nil {
+		t.Fatal(err)
+	}
+	rendered, err := render(data.Bytes(), transform, ImageOptions{MaxDimension: 4096, MaxPixels: 1 << 20, Quality: 85})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rendered.ContentType != "image/webp" || rendered.Width != 60 || rendered.Height != 40 {
+		t.Fatalf("rendered %s %dx%d", rendered.ContentType, rendered.Width, rendered.Height)
+	}
+	config, format, err := image.DecodeConfig(bytes.NewReader(rendered.Data))
+	if err != nil || format != "webp" || config.Width != 60 || config.Height != 40 {
+		t.Fatalf("decoded %s %dx%d: %v", format, config.Width, config.Height, err)
+	}
+}
+
+func TestHuffmanLengthsAreLimited(t *testing.T) {
+	// Fibonacci counts make the deepest possible tree
+	counts := make([]int, 30)
+	a, b := 1, 1
+	for i := range counts {
+		counts[i] = a
+		a, b = b, a+b
+	}
+	lengths := huffmanLengths(counts, 15)
+	kraft := 0.0
+	for _, length := range lengths {
+		if length == 0 || length > 15 {
+			t.Fatalf("length %d out of range", length)
+		}
+		kraft += 1 / float64(uint(1)<<length)
+	}
+	if kraft != 1 {
+		t.Fatalf("code is not complete, kraft sum %v", kraft)
+	}
+}
@@ -1,34 +1,130 @@
 package middleware
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 
+	"golang_cms/apitoken"
 	"golang_cms/helper"
+	"golang_cms/session"
 
 	"github.com/gin-gonic/gin"
 )
 
-func Authentication(c *gin.Context) {
-	clientToken := c.Request.Header.Get("token")
-	if clientToken == "" {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("No Authorization header provided")})
-		c.Abort()
-		return
+// realm names the protection space in WWW-Authenticate challenges.
+const realm = "golang_cms"
+
+// Authentication accepts requests with a valid access token that has not
+// been revoked, on its own or with its session, or with a personal API
+// token. The token is sent as "Authorization: Bearer <token>"; the older
+// "token" header is still read when there is no Authorization header.
+//
+// Requests made with an API token also get its id under "api_token" and
+// its permissions under "scopes", which RequirePermission applies on top of
+// the role of the user.
+func Authentication(sessions *session.Manager, apiTokens *apitoken.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clientToken, ok := bearerToken(c.Request)
+		if !ok {
+			unauthorized(c, "invalid_request", "Authorization header must use the Bearer scheme")
+			return
+		}
+		if clientToken == "" {
+			unauthorized(c, "", "No Authorization header provided")
+			return
+		}
+
+		if apitoken.IsApiToken(clientToken) {
+			token, user, err := apiTokens.Authenticate(c, clientToken)
+			if errors.Is(err, apitoken.ErrInvalidToken) {
+				unauthorized(c, "invalid_token", err.Error())
+				return
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				c.Abort()
+				return
+			}
+
+			c.Set("email", deref(user.Email))
+			c.Set("first_name", deref(user.First_name))
+			c.Set("last_name", deref(user.Last_name))
+			c.Set("uid", user.User_id)
+			c.Set("user_type", deref(user.User_type))
+			c.Set("api_token", token.Id.Hex())
+			c.Set("scopes", token.Permissions)
+
+			c.Next()
+			return
+		}
+
+		claims, err := helper.ValidateToken(clientToken)
+		if err != "" {
+			unauthorized(c, "invalid_token", err)
+			return
+		}
+
+		if err := sessions.Check(c, claims); errors.Is(err, session.ErrTokenRevoked) {
+			unauthorized(c, "invalid_token", err.Error())
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+
+		c.Set("email", claims.Email)
+		c.Set("first_name", claims.First_name)
+		c.Set("last_name", claims.Last_name)
+		c.Set("uid", claims.Uid)
+		c.Set("user_type", claims.User_type)
+		c.Set("claims", claims)
+
+		c.Next()
+	}
+}
+
+// bearerToken returns the token of the request, which is empty when none
+// was sent. ok is false for an Authorization header of another scheme.
+func bearerToken(r *http.Request) (token string, ok bool) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return strings.TrimSpace(r.Header.Get("token")), true
+	}
+	scheme, token, _ := strings.Cut(strings.TrimSpace(header), " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return "", false
 	}
+	return strings.TrimSpace(token), true
+}
 
-	claims, err := helper.ValidateToken(clientToken)
-	if err != "" {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err})
-		c.Abort()
-		return
+// unauthorized rejects the request with 401 and a Bearer challenge. code is
+// the RFC 6750 error code, left out when no credentials were sent.
+func unauthorized(c *gin.Context, code string, message string) {
+	challenge := fmt.Sprintf("Bearer realm=%q", realm)
+	if code != "" {
+		challenge += fmt.Sprintf(", error=%q, error_description=%q", code, quotable(message))
 	}
+	c.Header("WWW-Authenticate", challenge)
+	c.JSON(http.StatusUnauthorized, gin.H{"error": message})
+	c.Abort()
+}
 
-	c.Set("email", claims.Email)
-	c.Set("first_name", claims.First_name)
-	c.Set("last_name", claims.Last_name)
-	c.Set("uid", claims.Uid)
-	c.Set("user_type", claims.User_type)
+// quotable drops the characters a quoted challenge parameter cannot hold.
+func quotable(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '"' || r == '\\' || r < 0x20 || r > 0x7e {
+			return -1
+		}
+		return r
+	}, s)
+}
 
-	c.Next()
-}
\ No newline at end of file
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
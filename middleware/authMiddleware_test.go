@@ -0,0 +1,181 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang_cms/apitoken"
+	"golang_cms/helper"
+	"golang_cms/model"
+	"golang_cms/rbac"
+	"golang_cms/repository"
+	"golang_cms/session"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type authFixture struct {
+	router    *gin.Engine
+	repos     repository.Repositories
+	sessions  *session.Manager
+	apiTokens *apitoken.Service
+	user      model.User
+}
+
+// newAuthFixture serves GET /read, which needs banner:read, POST /write,
+// which needs banner:write, and PUT /users/:user_id, which needs user:write
+// unless it is the caller's own record, to an author.
+func newAuthFixture(t *testing.T) authFixture {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	helper.SetSecretKey("middleware-test-key")
+	repos := repository.NewMemoryRepositories()
+	email, userType := "ada@example.com", "USER"
+	user, err := repos.User.Create(context.Background(), model.User{ID: primitive.NewObjectID(), User_id: "ada", Email: &email, Phone: &email, User_type: &userType, Role: rbac.RoleAuthor})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authz := rbac.NewAuthorizer(repos.User, repos.Role)
+	f := authFixture{
+		repos:     repos,
+		sessions:  session.NewManager(repos.Refresh, repos.Revoked, repos.User, time.Hour, 0),
+		apiTokens: apitoken.NewService(repos.ApiToken, repos.User, authz),
+		user:      user,
+	}
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	f.router = gin.New()
+	authenticate := Authentication(f.sessions, f.apiTokens)
+	f.router.GET("/read", authenticate, RequirePermission(authz, "banner:read"), ok)
+	f.router.POST("/write", authenticate, RequirePermission(authz, "banner:write"), ok)
+	f.router.PUT("/users/:user_id", authenticate, RequirePermissionOrSelf(authz, "user_id", "user:write"), ok)
+	return f
+}
+
+func (f authFixture) serve(method, path string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	f.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuthenticationChallenges(t *testing.T) {
+	f := newAuthFixture(t)
+	tests := []struct {
+		name   string
+		header []string
+		code   string
+	}{
+		{"no credentials", nil, ""},
+		{"other scheme", []string{"Authorization", "Basic YTpi"}, "invalid_request"},
+		{"malformed token", []string{"Authorization", "Bearer nonsense"}, "invalid_token"},
+		{"unknown API token", []string{"Authorization", "Bearer " + apitoken.Prefix + "nonsense"}, "invalid_token"},
+	}
+	for _, tt := range tests {
+		w := f.serve("GET", "/read", tt.header...)
+		challenge := w.Header().Get("WWW-Authenticate")
+		if w.Code != http.StatusUnauthorized || !strings.HasPrefix(challenge, `Bearer realm="golang_cms"`) {
+			t.Errorf("%s = %d, challenge %q", tt.name, w.Code, challenge)
+		}
+		if hasCode := strings.Contains(challenge, "error="); hasCode != (tt.code != "") || !strings.Contains(challenge, tt.code) {
+			t.Errorf("%s: challenge %q, want error %q", tt.name, challenge, tt.code)
+		}
+	}
+}
+
+func TestAuthenticationAccessToken(t *testing.T) {
+	f := newAuthFixture(t)
+	tokens, err := f.sessions.Start(context.Background(), f.user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if w := f.serve("POST", "/write", "Authorization", "Bearer "+tokens.AccessToken); w.Code != http.StatusOK {
+		t.Errorf("bearer access token = %d %s", w.Code, w.Body)
+	}
+	if w := f.serve("GET", "/read", "token", tokens.AccessToken); w.Code != http.StatusOK {
+		t.Errorf("access token in the token header = %d %s", w.Code, w.Body)
+	}
+
+	claims, _ := helper.ValidateToken(tokens.AccessToken)
+	if err := f.sessions.Logout(context.Background(), claims, false); err != nil {
+		t.Fatal(err)
+	}
+	if w := f.serve("GET", "/read", "Authorization", "Bearer "+tokens.AccessToken); w.Code != http.StatusUnauthorized {
+		t.Errorf("logged out access token = %d", w.Code)
+	}
+}
+
+func TestAuthenticationApiToken(t *testing.T) {
+	ctx := context.Background()
+	f := newAuthFixture(t)
+	_, readOnly, err := f.apiTokens.Create(ctx, "ada", "read", []string{"banner:read"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, writer, err := f.apiTokens.Create(ctx, "ada", "write", []string{"banner:read", "banner:write"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if w := f.serve("GET", "/read", "Authorization", "Bearer "+readOnly); w.Code != http.StatusOK {
+		t.Errorf("read with a read token = %d %s", w.Code, w.Body)
+	}
+	w := f.serve("POST", "/write", "Authorization", "Bearer "+readOnly)
+	if w.Code != http.StatusForbidden || !strings.Contains(w.Header().Get("WWW-Authenticate"), "insufficient_scope") {
+		t.Errorf("write with a read token = %d, challenge %q", w.Code, w.Header().Get("WWW-Authenticate"))
+	}
+	if w := f.serve("POST", "/write", "Authorization", "Bearer "+writer); w.Code != http.StatusOK {
+		t.Errorf("write with a write token = %d %s", w.Code, w.Body)
+	}
+
+	// a token never does more than the current role of its user allows
+	f.user.Role = rbac.RoleViewer
+	if _, err := f.repos.User.Update(ctx, f.user); err != nil {
+		t.Fatal(err)
+	}
+	if w := f.serve("POST", "/write", "Authorization", "Bearer "+writer); w.Code != http.StatusForbidden {
+		t.Errorf("write after the role lost it = %d", w.Code)
+	}
+	if w := f.serve("GET", "/read", "Authorization", "Bearer "+writer); w.Code != http.StatusOK {
+		t.Errorf("read after the role change = %d", w.Code)
+	}
+}
+
+func TestRequirePermissionOrSelf(t *testing.T) {
+	ctx := context.Background()
+	f := newAuthFixture(t)
+	tokens, err := f.sessions.Start(ctx, f.user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, scoped, err := f.apiTokens.Create(ctx, "ada", "read", []string{"banner:read"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		path  string
+		token string
+		want  int
+	}{
+		{"own record", "/users/ada", tokens.AccessToken, http.StatusOK},
+		{"own record by email", "/users/ada@example.com", tokens.AccessToken, http.StatusOK},
+		{"someone else's record", "/users/bob", tokens.AccessToken, http.StatusForbidden},
+		{"own record with a scoped API token", "/users/ada", scoped, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		if w := f.serve("PUT", tt.path, "Authorization", "Bearer "+tt.token); w.Code != tt.want {
+			t.Errorf("%s = %d, want %d", tt.name, w.Code, tt.want)
+		}
+	}
+}
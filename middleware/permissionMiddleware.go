@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"golang_cms/rbac"
+	"golang_cms/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequirePermission only lets users through whose role grants every one of
+// permissions. It must run after Authentication. The permissions of the
+// user are kept on the context under "permissions".
+func RequirePermission(authz *rbac.Authorizer, permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		granted, ok := loadPermissions(c, authz)
+		if !ok {
+			return
+		}
+		for _, permission := range permissions {
+			if !rbac.Allows(granted, permission) {
+				forbid(c, permissions)
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// RequirePermissionOrSelf is RequirePermission for routes about one user,
+// named by the param route parameter: the user themselves is let through,
+// whether the parameter holds their user id or their email. Requests made
+// with an API token always need the permissions, so its scopes apply.
+func RequirePermissionOrSelf(authz *rbac.Authorizer, param string, permissions ...string) gin.HandlerFunc {
+	require := RequirePermission(authz, permissions...)
+	return func(c *gin.Context) {
+		if _, apiToken := c.Get("api_token"); apiToken {
+			require(c)
+			return
+		}
+		if self := c.Param(param); self != "" && (self == c.GetString("uid") || self == c.GetString("email")) {
+			c.Next()
+			return
+		}
+		require(c)
+	}
+}
+
+func loadPermissions(c *gin.Context, authz *rbac.Authorizer) ([]string, bool) {
+	if granted, ok := c.Get("permissions"); ok {
+		return granted.([]string), true
+	}
+
+	uid := c.GetString("uid")
+	if uid == "" {
+		unauthorized(c, "", "No Authorization header provided")
+		return nil, false
+	}
+	granted, err := authz.Permissions(c, uid)
+	if errors.Is(err, repository.ErrNotFound) {
+		unauthorized(c, "invalid_token", "User no longer exists!")
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Abort()
+		return nil, false
+	}
+	// an API token can do no more than both its role and its scopes allow
+	if scopes, ok := c.Get("scopes"); ok {
+		granted = rbac.Intersect(granted, scopes.([]string))
+	}
+	c.Set("permissions", granted)
+	return granted, true
+}
+
+// forbid rejects the request with 403, naming the permissions it needed.
+func forbid(c *gin.Context, permissions []string) {
+	c.Header("WWW-Authenticate", fmt.Sprintf("Bearer realm=%q, error=\"insufficient_scope\", scope=%q", realm, strings.Join(permissions, " ")))
+	c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized to access resource!"})
+	c.Abort()
+}
+
+// Guard binds RequirePermission to authz, for registering many routes.
+func Guard(authz *rbac.Authorizer) func(permissions ...string) gin.HandlerFunc {
+	return func(permissions ...string) gin.HandlerFunc {
+		return RequirePermission(authz, permissions...)
+	}
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+
+	"golang_cms/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitByIP rejects requests with 429 once their client IP has made more
+// than limiter allows.
+func RateLimitByIP(limiter *ratelimit.Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if ok, retryAfter := limiter.Allow(c.ClientIP()); !ok {
+			c.Header("Retry-After", fmt.Sprint(int(math.Ceil(retryAfter.Seconds()))))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests, try again later"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
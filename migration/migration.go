@@ -0,0 +1,252 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Migration is one ordered, reversible change to the MongoDB schema.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      func(ctx context.Context, db *mongo.Database) error
+	Down    func(ctx context.Context, db *mongo.Database) error
+}
+
+// Status reports whether a migration has been applied.
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+type record struct {
+	Version   int64     `bson:"_id"`
+	Name      string    `bson:"name"`
+	AppliedAt time.Time `bson:"applied_at"`
+}
+
+// recordStore keeps the records of the applied migrations.
+type recordStore interface {
+	all(ctx context.Context) (map[int64]record, error)
+	add(ctx context.Context, r record) error
+	remove(ctx context.Context, version int64) error
+}
+
+// Migrator applies migrations and records them in the schema_migrations
+// collection.
+type Migrator struct {
+	db         *mongo.Database
+	records    recordStore
+	migrations []Migration
+}
+
+// New returns a migrator for db with the given migrations, or All when none
+// are given.
+func New(db *mongo.Database, migrations ...Migration) *Migrator {
+	return newMigrator(db, collectionRecords{db.Collection("schema_migrations")}, migrations)
+}
+
+func newMigrator(db *mongo.Database, records recordStore, migrations []Migration) *Migrator {
+	if len(migrations) == 0 {
+		migrations = All
+	}
+
+	sorted := append([]Migration(nil), migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+
+	return &Migrator{
+		db:         db,
+		records:    records,
+		migrations: sorted,
+	}
+}
+
+// collectionRecords keeps the records in a collection, one document per
+// migration keyed by its version.
+type collectionRecords struct {
+	collection *mongo.Collection
+}
+
+func (c collectionRecords) all(ctx context.Context) (map[int64]record, error) {
+	cursor, err := c.collection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	applied := map[int64]record{}
+	for cursor.Next(ctx) {
+		var r record
+		if err := cursor.Decode(&r); err != nil {
+			return nil, err
+		}
+		applied[r.Version] = r
+	}
+	return applied, cursor.Err()
+}
+
+func (c collectionRecords) add(ctx context.Context, r record) error {
+	_, err := c.collection.InsertOne(ctx, r)
+	return err
+}
+
+func (c collectionRecords) remove(ctx context.Context, version int64) error {
+	_, err := c.collection.DeleteOne(ctx, bson.M{"_id": version})
+	return err
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[int64]record, error) {
+	return m.records.all(ctx)
+}
+
+// Up applies every pending migration in version order and returns the ones
+// it ran.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var ran []Migration
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+
+		if err := migration.Up(ctx, m.db); err != nil {
+			return ran, fmt.Errorf("migration %d %s: %w", migration.Version, migration.Name, err)
+		}
+
+		r := record{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now().UTC()}
+		if err := m.records.add(ctx, r); err != nil {
+			return ran, err
+		}
+		ran = append(ran, migration)
+	}
+	return ran, nil
+}
+
+// Down reverts the latest steps applied migrations, newest first.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var reverted []Migration
+	for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+
+		if err := migration.Down(ctx, m.db); err != nil {
+			return reverted, fmt.Errorf("revert migration %d %s: %w", migration.Version, migration.Name, err)
+		}
+
+		if err := m.records.remove(ctx, migration.Version); err != nil {
+			return reverted, err
+		}
+		reverted = append(reverted, migration)
+	}
+	return reverted, nil
+}
+
+// Status lists every known migration in version order.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		r, ok := applied[migration.Version]
+		statuses = append(statuses, Status{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			Applied:   ok,
+			AppliedAt: r.AppliedAt,
+		})
+	}
+	return statuses, nil
+}
+
+// Pending reports how many migrations have not been applied yet.
+func (m *Migrator) Pending(ctx context.Context) (int, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	pending := 0
+	for _, status := range statuses {
+		if !status.Applied {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+// createIndexes is a helper for migrations that only add indexes.
+func createIndexes(ctx context.Context, collection *mongo.Collection, models ...mongo.IndexModel) error {
+	_, err := collection.Indexes().CreateMany(ctx, models)
+	return err
+}
+
+// dropIndexes drops indexes by name, ignoring the ones that do not exist.
+func dropIndexes(ctx context.Context, collection *mongo.Collection, names ...string) error {
+	for _, name := range names {
+		if _, err := collection.Indexes().DropOne(ctx, name); err != nil {
+			var cmdErr mongo.CommandError
+			if errors.As(err, &cmdErr) && cmdErr.Name == "IndexNotFound" {
+				continue
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func uniqueIndex(field string) mongo.IndexModel {
+	return mongo.IndexModel{
+		Keys:    bson.D{{Key: field, Value: 1}},
+		Options: options.Index().SetName(field + "_unique").SetUnique(true),
+	}
+}
+
+// textIndex covers fields with a text index that weighs the first field
+// double. The language is "none" so words are neither stemmed nor dropped
+// as English stop words, matching the embedded search index.
+func textIndex(fields ...string) mongo.IndexModel {
+	keys := bson.D{}
+	weights := bson.D{}
+	for i, field := range fields {
+		keys = append(keys, bson.E{Key: field, Value: "text"})
+		weight := 1
+		if i == 0 {
+			weight = 2
+		}
+		weights = append(weights, bson.E{Key: field, Value: weight})
+	}
+	return mongo.IndexModel{
+		Keys:    keys,
+		Options: options.Index().SetName("search_text").SetWeights(weights).SetDefaultLanguage("none"),
+	}
+}
+
+func index(field string) mongo.IndexModel {
+	return mongo.IndexModel{
+		Keys:    bson.D{{Key: field, Value: 1}},
+		Options: options.Index().SetName(field + "_1"),
+	}
+}
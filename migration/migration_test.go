@@ -0,0 +1,206 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// memoryRecords keeps the records of the applied migrations in memory.
+type memoryRecords map[int64]record
+
+func (m memoryRecords) all(ctx context.Context) (map[int64]record, error) {
+	applied := map[int64]record{}
+	for version, r := range m {
+		applied[version] = r
+	}
+	return applied, nil
+}
+
+func (m memoryRecords) add(ctx context.Context, r record) error {
+	m[r.Version] = r
+	return nil
+}
+
+func (m memoryRecords) remove(ctx context.Context, version int64) error {
+	delete(m, version)
+	return nil
+}
+
+// tracked returns migrations with the given versions that append what they
+// do to log, failing the up of fail.
+func tracked(log *[]string, fail int64, versions ...int64) []Migration {
+	var migrations []Migration
+	for _, version := range versions {
+		version := version
+		name := string(rune('a' + version))
+		migrations = append(migrations, Migration{
+			Version: version,
+			Name:    name,
+			Up: func(ctx context.Context, db *mongo.Database) error {
+				if version == fail {
+					return errors.New("boom")
+				}
+				*log = append(*log, "up "+name)
+				return nil
+			},
+			Down: func(ctx context.Context, db *mongo.Database) error {
+				*log = append(*log, "down "+name)
+				return nil
+			},
+		})
+	}
+	return migrations
+}
+
+func versions(migrations []Migration) []int64 {
+	var versions []int64
+	for _, m := range migrations {
+		versions = append(versions, m.Version)
+	}
+	return versions
+}
+
+func TestUpRunsPendingInOrder(t *testing.T) {
+	ctx := context.Background()
+	var log []string
+	records := memoryRecords{}
+	m := newMigrator(nil, records, tracked(&log, 0, 3, 1, 2))
+
+	ran, err := m.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(versions(ran), []int64{1, 2, 3}) || !reflect.DeepEqual(log, []string{"up b", "up c", "up d"}) {
+		t.Errorf("ran %v, log %v", versions(ran), log)
+	}
+	if pending, _ := m.Pending(ctx); pending != 0 {
+		t.Errorf("%d pending after up", pending)
+	}
+
+	ran, err = m.Up(ctx)
+	if err != nil || len(ran) != 0 || len(log) != 3 {
+		t.Errorf("second up ran %v, %v", versions(ran), err)
+	}
+}
+
+func TestUpStopsAtFailure(t *testing.T) {
+	ctx := context.Background()
+	var log []string
+	records := memoryRecords{}
+	m := newMigrator(nil, records, tracked(&log, 2, 1, 2, 3))
+
+	ran, err := m.Up(ctx)
+	if err == nil || !reflect.DeepEqual(versions(ran), []int64{1}) {
+		t.Fatalf("up = %v, %v", versions(ran), err)
+	}
+	if _, ok := records[2]; ok {
+		t.Error("failed migration recorded as applied")
+	}
+	statuses, _ := m.Status(ctx)
+	var applied []bool
+	for _, s := range statuses {
+		applied = append(applied, s.Applied)
+	}
+	if !reflect.DeepEqual(applied, []bool{true, false, false}) {
+		t.Errorf("applied = %v", applied)
+	}
+}
+
+func TestDownRevertsNewestFirst(t *testing.T) {
+	ctx := context.Background()
+	var log []string
+	records := memoryRecords{}
+	m := newMigrator(nil, records, tracked(&log, 0, 1, 2, 3))
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	log = nil
+
+	reverted, err := m.Down(ctx, 2)
+	if err != nil || !reflect.DeepEqual(versions(reverted), []int64{3, 2}) || !reflect.DeepEqual(log, []string{"down d", "down c"}) {
+		t.Fatalf("down 2 = %v, %v, log %v", versions(reverted), err, log)
+	}
+	if pending, _ := m.Pending(ctx); pending != 2 {
+		t.Errorf("%d pending after down 2", pending)
+	}
+
+	reverted, _ = m.Down(ctx, 5)
+	if !reflect.DeepEqual(versions(reverted), []int64{1}) {
+		t.Errorf("down 5 = %v", versions(reverted))
+	}
+	if reverted, _ := m.Down(ctx, 1); len(reverted) != 0 {
+		t.Errorf("down with nothing applied = %v", versions(reverted))
+	}
+}
+
+func TestStatus(t *testing.T) {
+	ctx := context.Background()
+	var log []string
+	appliedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	records := memoryRecords{2: {Version: 2, Name: "c", AppliedAt: appliedAt}}
+	m := newMigrator(nil, records, tracked(&log, 0, 1, 2))
+
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Status{{Version: 1, Name: "b"}, {Version: 2, Name: "c", Applied: true, AppliedAt: appliedAt}}
+	if !reflect.DeepEqual(statuses, want) {
+		t.Errorf("status = %+v, want %+v", statuses, want)
+	}
+}
+
+func TestAllMigrations(t *testing.T) {
+	seen := map[int64]bool{}
+	for i, m := range All {
+		if m.Version <= 0 || seen[m.Version] || m.Name == "" || m.Up == nil || m.Down == nil {
+			t.Errorf("migration %d is incomplete or reuses a version: %+v", i, m)
+		}
+		if i > 0 && m.Version < All[i-1].Version {
+			t.Errorf("migration %d is listed out of order", m.Version)
+		}
+		seen[m.Version] = true
+	}
+}
+
+// TestAllOnMongo applies and reverts every migration against the MongoDB
+// server at MONGO_TEST_URI, in a scratch database.
+func TestAllOnMongo(t *testing.T) {
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI is not set")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Disconnect(ctx)
+	db := client.Database("migration_test_" + time.Now().Format("20060102150405"))
+	defer db.Drop(ctx)
+
+	m := New(db)
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if pending, err := m.Pending(ctx); err != nil || pending != 0 {
+		t.Fatalf("pending after up = %d, %v", pending, err)
+	}
+	if _, err := m.Down(ctx, len(All)); err != nil {
+		t.Fatal(err)
+	}
+	if pending, err := m.Pending(ctx); err != nil || pending != len(All) {
+		t.Fatalf("pending after down = %d, %v", pending, err)
+	}
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatalf("up after down: %v", err)
+	}
+}
@@ -0,0 +1,373 @@
+package migration
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// contentCollections hold documents that used to be keyed by an "id" field
+// next to the "_id" Mongo generated on insert.
+var contentCollections = []string{"Banner", "Meta", "Description", "Main Category"}
+
+// All is every migration of the application, in version order.
+var All = []Migration{
+	{
+		Version: 1,
+		Name:    "create_user_indexes",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndexes(ctx, db.Collection("User"), uniqueIndex("email"), uniqueIndex("phone"), uniqueIndex("user_id"))
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndexes(ctx, db.Collection("User"), "email_unique", "phone_unique", "user_id_unique")
+		},
+	},
+	{
+		Version: 2,
+		Name:    "normalize_content_ids",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			for _, name := range contentCollections {
+				if err := moveIdToUnderscoreId(ctx, db.Collection(name)); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			for _, name := range contentCollections {
+				if err := copyUnderscoreIdToId(ctx, db.Collection(name)); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 3,
+		Name:    "create_child_category_parent_index",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndexes(ctx, db.Collection("Child Category"), index("idmaincategory"))
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndexes(ctx, db.Collection("Child Category"), "idmaincategory_1")
+		},
+	},
+	{
+		Version: 4,
+		Name:    "create_category_tree_indexes",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndexes(ctx, db.Collection("Category"), index("parent_id"), index("path"))
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndexes(ctx, db.Collection("Category"), "parent_id_1", "path_1")
+		},
+	},
+	{
+		Version: 5,
+		Name:    "create_product_indexes",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndexes(ctx, db.Collection("Product"), uniqueIndex("sku"), index("category_ids"))
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndexes(ctx, db.Collection("Product"), "sku_unique", "category_ids_1")
+		},
+	},
+	{
+		Version: 6,
+		Name:    "create_search_text_indexes",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			for name, fields := range searchFields {
+				if err := createIndexes(ctx, db.Collection(name), textIndex(fields...)); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			for name := range searchFields {
+				if err := dropIndexes(ctx, db.Collection(name), "search_text"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 7,
+		Name:    "create_media_indexes",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			if err := createIndexes(ctx, db.Collection("Media"), index("checksum")); err != nil {
+				return err
+			}
+			if err := createIndexes(ctx, db.Collection("Banner"), index("media_id")); err != nil {
+				return err
+			}
+			return createIndexes(ctx, db.Collection("Child Category"), index("media_id"))
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			if err := dropIndexes(ctx, db.Collection("Media"), "checksum_1"); err != nil {
+				return err
+			}
+			if err := dropIndexes(ctx, db.Collection("Banner"), "media_id_1"); err != nil {
+				return err
+			}
+			return dropIndexes(ctx, db.Collection("Child Category"), "media_id_1")
+		},
+	},
+	{
+		Version: 8,
+		Name:    "create_revision_indexes",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndexes(ctx, db.Collection("Revision"), mongo.IndexModel{
+				Keys:    bson.D{{Key: "content_type", Value: 1}, {Key: "document_id", Value: 1}, {Key: "number", Value: 1}},
+				Options: options.Index().SetName("revision_number_unique").SetUnique(true),
+			})
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndexes(ctx, db.Collection("Revision"), "revision_number_unique")
+		},
+	},
+	{
+		// Content written before publishing statuses existed was live, so
+		// it starts out published. Down keeps the statuses, which older
+		// code ignores.
+		Version: 9,
+		Name:    "add_workflow_status",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			for _, name := range workflowCollections {
+				collection := db.Collection(name)
+				if _, err := collection.UpdateMany(ctx, bson.M{"status": bson.M{"$exists": false}}, bson.M{"$set": bson.M{"status": "published"}}); err != nil {
+					return err
+				}
+				if err := createIndexes(ctx, collection, index("status"), index("publish_at"), index("unpublish_at")); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			for _, name := range workflowCollections {
+				if err := dropIndexes(ctx, db.Collection(name), "status_1", "publish_at_1", "unpublish_at_1"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 10,
+		Name:    "create_trash_indexes",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			for _, name := range contentCollections {
+				if err := createIndexes(ctx, db.Collection(name), index("deleted_at")); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			for _, name := range contentCollections {
+				if err := dropIndexes(ctx, db.Collection(name), "deleted_at_1"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 11,
+		Name:    "create_audit_indexes",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndexes(ctx, db.Collection("Audit"),
+				index("actor_uid"),
+				index("actor_email"),
+				index("created_at"),
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "resource_type", Value: 1}, {Key: "resource_id", Value: 1}},
+					Options: options.Index().SetName("audit_resource"),
+				})
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndexes(ctx, db.Collection("Audit"), "actor_uid_1", "actor_email_1", "created_at_1", "audit_resource")
+		},
+	},
+	{
+		// Users keep the access their user type gave them: admins get the
+		// admin role and everyone else the viewer role. Down keeps the
+		// roles, which older code ignores.
+		Version: 12,
+		Name:    "add_user_roles",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			users := db.Collection("User")
+			if _, err := users.UpdateMany(ctx,
+				bson.M{"role": bson.M{"$in": bson.A{nil, ""}}, "user_type": "ADMIN"},
+				bson.M{"$set": bson.M{"role": "admin"}}); err != nil {
+				return err
+			}
+			if _, err := users.UpdateMany(ctx,
+				bson.M{"role": bson.M{"$in": bson.A{nil, ""}}},
+				bson.M{"$set": bson.M{"role": "viewer"}}); err != nil {
+				return err
+			}
+			if err := createIndexes(ctx, users, index("role")); err != nil {
+				return err
+			}
+			return createIndexes(ctx, db.Collection("Role"), mongo.IndexModel{
+				Keys:    bson.D{{Key: "name", Value: 1}},
+				Options: options.Index().SetName("name_unique").SetUnique(true),
+			})
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			if err := dropIndexes(ctx, db.Collection("User"), "role_1"); err != nil {
+				return err
+			}
+			return dropIndexes(ctx, db.Collection("Role"), "name_unique")
+		},
+	},
+	{
+		// Sessions keep their refresh tokens, by hash, in RefreshToken. The
+		// tokens logins used to store in plain text on the user are removed.
+		Version: 13,
+		Name:    "create_refresh_tokens",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			if _, err := db.Collection("User").UpdateMany(ctx,
+				bson.M{},
+				bson.M{"$unset": bson.M{"token": "", "refresh_token": ""}}); err != nil {
+				return err
+			}
+			return createIndexes(ctx, db.Collection("RefreshToken"),
+				uniqueIndex("token_hash"), index("user_id"), index("session_id"), index("expires_at"))
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndexes(ctx, db.Collection("RefreshToken"), "token_hash_unique", "user_id_1", "session_id_1", "expires_at_1")
+		},
+	},
+	{
+		// Revoked access tokens are looked up by jti on every request, and
+		// MongoDB removes them by itself once the token has expired.
+		Version: 14,
+		Name:    "create_revoked_tokens",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndexes(ctx, db.Collection("RevokedToken"),
+				uniqueIndex("jti"),
+				index("user_id"),
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "expires_at", Value: 1}},
+					Options: options.Index().SetName("expires_at_ttl").SetExpireAfterSeconds(0),
+				})
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndexes(ctx, db.Collection("RevokedToken"), "jti_unique", "user_id_1", "expires_at_ttl")
+		},
+	},
+	{
+		Version: 15,
+		Name:    "create_api_token_indexes",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndexes(ctx, db.Collection("ApiToken"), uniqueIndex("token_hash"), index("user_id"))
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndexes(ctx, db.Collection("ApiToken"), "token_hash_unique", "user_id_1")
+		},
+	},
+	{
+		// Password reset tokens are removed by MongoDB once they expire.
+		Version: 16,
+		Name:    "create_password_reset_indexes",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndexes(ctx, db.Collection("PasswordReset"),
+				uniqueIndex("token_hash"),
+				index("user_id"),
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "expires_at", Value: 1}},
+					Options: options.Index().SetName("expires_at_ttl").SetExpireAfterSeconds(0),
+				})
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndexes(ctx, db.Collection("PasswordReset"), "token_hash_unique", "user_id_1", "expires_at_ttl")
+		},
+	},
+}
+
+// workflowCollections hold content with a publishing status.
+var workflowCollections = []string{"Banner", "Meta", "Description", "Main Category", "Child Category", "Category"}
+
+// searchFields are the fields of each collection covered by its text index,
+// the first one being the title.
+var searchFields = map[string][]string{
+	"Banner":         {"alt", "banner", "link"},
+	"Meta":           {"meta_title", "meta_desc", "meta_url"},
+	"Description":    {"title", "desc"},
+	"Main Category":  {"kategori_produk", "nama_produk"},
+	"Child Category": {"nama_produk"},
+	"Category":       {"name", "slug"},
+}
+
+// moveIdToUnderscoreId re-keys every document whose "id" differs from its
+// "_id". Since "_id" is immutable the document is inserted again under the
+// old "id" and the original is removed.
+func moveIdToUnderscoreId(ctx context.Context, collection *mongo.Collection) error {
+	cursor, err := collection.Find(ctx, bson.M{"id": bson.M{"$exists": true}})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var doc bson.M
+		if err := cursor.Decode(&doc); err != nil {
+			return err
+		}
+
+		oldId := doc["_id"]
+		newId, ok := doc["id"].(primitive.ObjectID)
+		if !ok || newId.IsZero() {
+			if _, err := collection.UpdateOne(ctx, bson.M{"_id": oldId}, bson.M{"$unset": bson.M{"id": ""}}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		delete(doc, "id")
+		if oldId == newId {
+			if _, err := collection.ReplaceOne(ctx, bson.M{"_id": oldId}, doc); err != nil {
+				return err
+			}
+			continue
+		}
+
+		doc["_id"] = newId
+		if _, err := collection.InsertOne(ctx, doc); err != nil && !mongo.IsDuplicateKeyError(err) {
+			return err
+		}
+		if _, err := collection.DeleteOne(ctx, bson.M{"_id": oldId}); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+// copyUnderscoreIdToId restores the "id" field the old code filtered on.
+func copyUnderscoreIdToId(ctx context.Context, collection *mongo.Collection) error {
+	cursor, err := collection.Find(ctx, bson.M{"id": bson.M{"$exists": false}})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var doc struct {
+			Id interface{} `bson:"_id"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return err
+		}
+		if _, err := collection.UpdateOne(ctx, bson.M{"_id": doc.Id}, bson.M{"$set": bson.M{"id": doc.Id}}); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
@@ -1,39 +1,216 @@
 package model
 
 import (
+	"time"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+const (
+	StatusDraft     = "draft"
+	StatusInReview  = "in_review"
+	StatusPublished = "published"
+	StatusArchived  = "archived"
+)
+
+// status tayang sebuah konten. Hanya konten dengan Status published yang
+// tampil di endpoint public. PublishAt menjadwalkan konten draft atau
+// in_review untuk dipublish, UnpublishAt menjadwalkan konten published untuk
+// diarsipkan; scheduler mengosongkan jadwal itu setelah dijalankan.
+// Data lama yang belum punya status dianggap published.
+type Workflow struct {
+	Status      string     `json:"status" validate:"omitempty,oneof=draft in_review published archived" gorm:"size:16;index;default:published"`
+	PublishAt   *time.Time `bson:"publish_at" json:"publish_at" gorm:"index"`
+	UnpublishAt *time.Time `bson:"unpublish_at" json:"unpublish_at" gorm:"index"`
+}
+
+// WorkflowState memberi akses ke status tayang konten yang menyematkan
+// Workflow.
+func (w *Workflow) WorkflowState() *Workflow {
+	return w
+}
+
+// Publishable adalah konten yang punya status tayang.
+type Publishable interface {
+	WorkflowState() *Workflow
+}
+
+// penanda konten yang sudah dihapus ke tong sampah. Konten di tong sampah
+// tidak tampil di mana pun selain /trash, bisa dikembalikan, dan dihapus
+// permanen setelah masa simpannya habis. DeletedBy berisi uid penghapus.
+type Trash struct {
+	DeletedAt *time.Time `bson:"deleted_at" json:"deleted_at,omitempty" gorm:"index"`
+	DeletedBy string     `bson:"deleted_by" json:"deleted_by,omitempty"`
+}
+
+// TrashState memberi akses ke penanda tong sampah konten yang menyematkan
+// Trash.
+func (t *Trash) TrashState() *Trash {
+	return t
+}
+
+// Trashable adalah konten yang dihapus ke tong sampah dulu sebelum dihapus
+// permanen.
+type Trashable interface {
+	TrashState() *Trash
+}
+
+// Banner berisi url gambar, atau MediaId yang menunjuk ke Media yang sudah
+// diupload; kalau MediaId diisi, Banner diisi otomatis dengan url media itu.
 type Banner struct {
-	Id     primitive.ObjectID `json:"id,omitempty"`
-	Banner string             `json:"banner,omitempty" validate:"required"`
-	Alt    string             `json:"alt,omitempty" validate:"required"`
-	Link   string             `json:"link,omitempty" validate:"required"`
+	Id      primitive.ObjectID  `bson:"_id" json:"id,omitempty" gorm:"primaryKey;serializer:objectid;size:24"`
+	Banner  string              `json:"banner,omitempty" validate:"required_without=MediaId"`
+	MediaId *primitive.ObjectID `bson:"media_id" json:"media_id,omitempty" gorm:"serializer:objectid;size:24;index"`
+	Alt     string              `json:"alt,omitempty" validate:"required"`
+	Link    string              `json:"link,omitempty" validate:"required"`
+	Workflow `bson:",inline"`
+	Trash    `bson:",inline"`
 }
 
 // meta
 type Meta struct {
-	Id              primitive.ObjectID `json:"id,omitempty"`
+	Id              primitive.ObjectID `bson:"_id" json:"id,omitempty" gorm:"primaryKey;serializer:objectid;size:24"`
 	Meta_title      string             `json:"meta_title,omitempty" validate:"required"`
 	Meta_url        string             `json:"meta_url,omitempty" validate:"required"`
 	Meta_desc 		string             `json:"meta_desc,omitempty" validate:"required"`
+	Workflow `bson:",inline"`
+	Trash    `bson:",inline"`
 }
 
 type Desc struct {
-	Id              primitive.ObjectID `json:"id,omitempty"`
+	Id              primitive.ObjectID `bson:"_id" json:"id,omitempty" gorm:"primaryKey;serializer:objectid;size:24"`
 	Title 			string             `json:"title,omitempty" validate:"required"`
 	Desc 			string             `json:"desc,omitempty" validate:"required"`	
+	Workflow `bson:",inline"`
+	Trash    `bson:",inline"`
 }
 
 type MainCategory struct {
-	Id              primitive.ObjectID `json:"id,omitempty"`
+	Id              primitive.ObjectID `bson:"_id" json:"id,omitempty" gorm:"primaryKey;serializer:objectid;size:24"`
 	Kategori_Produk string             `json:"kategori_produk,omitempty" validate:"required"`
 	Nama_produk     string             `json:"nama_produk" validate:"required"`
+	Workflow `bson:",inline"`
+	Trash    `bson:",inline"`
 }
 
+// sub kategori, IdMainCategory menunjuk ke MainCategory induknya. Sama
+// seperti Banner, Image diisi otomatis dari MediaId kalau MediaId diisi.
 type ChildCategory struct {
-	Id             primitive.ObjectID `bson:"_id,omitempty"`
-	IdMainCategory primitive.ObjectID `json:"idmaincategory"`
-	Nama_produk    string             `json:"nama_produk,omitempty" validate:"required"`
-	Image          string             `json:"image" validate:"required"`
-}
\ No newline at end of file
+	Id             primitive.ObjectID  `bson:"_id,omitempty" json:"id,omitempty" gorm:"primaryKey;serializer:objectid;size:24"`
+	IdMainCategory primitive.ObjectID  `json:"idmaincategory" gorm:"serializer:objectid;size:24;index"`
+	Nama_produk    string              `json:"nama_produk,omitempty" validate:"required"`
+	Image          string              `json:"image" validate:"required_without=MediaId"`
+	MediaId        *primitive.ObjectID `bson:"media_id" json:"media_id,omitempty" gorm:"serializer:objectid;size:24;index"`
+	Workflow `bson:",inline"`
+}
+
+// kategori utama beserta semua sub kategorinya, hanya untuk response
+type MainCategoryWithChildren struct {
+	MainCategory
+	Children []ChildCategory `json:"children"`
+}
+
+// kategori bertingkat tanpa batas kedalaman. Path berisi id semua leluhur
+// dan id kategori itu sendiri, misalnya "/<id root>/<id induk>/<id ini>/",
+// sehingga semua turunan sebuah kategori punya Path dengan awalan yang sama.
+type Category struct {
+	Id       primitive.ObjectID  `bson:"_id" json:"id" gorm:"primaryKey;serializer:objectid;size:24"`
+	Name     string              `json:"name" validate:"required"`
+	Slug     string              `json:"slug"`
+	ParentId *primitive.ObjectID `bson:"parent_id" json:"parent_id" gorm:"serializer:objectid;size:24;index"`
+	Path     string              `json:"path" gorm:"index"`
+	Depth    int                 `json:"depth"`
+	Order    int                 `json:"order"`
+	Workflow `bson:",inline"`
+}
+
+// satu node pohon kategori beserta anak-anaknya, hanya untuk response
+type CategoryNode struct {
+	Category
+	Children []*CategoryNode `json:"children"`
+}
+
+// produk katalog. Price disimpan dalam satuan terkecil mata uangnya (misalnya
+// sen untuk USD), CategoryIds menunjuk ke satu atau lebih Category.
+type Product struct {
+	Id          primitive.ObjectID   `bson:"_id" json:"id" gorm:"primaryKey;serializer:objectid;size:24"`
+	Sku         string               `json:"sku" validate:"required" gorm:"uniqueIndex;size:64"`
+	Name        string               `json:"name" validate:"required"`
+	Description string               `json:"description"`
+	Price       int64                `json:"price" validate:"gte=0"`
+	Currency    string               `json:"currency" validate:"required,iso4217"`
+	Stock       int64                `json:"stock" validate:"gte=0"`
+	Images      []string             `json:"images" validate:"dive,required" gorm:"serializer:json"`
+	CategoryIds []primitive.ObjectID `bson:"category_ids" json:"category_ids" validate:"required,min=1" gorm:"serializer:json"`
+	CreatedAt   time.Time            `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time            `bson:"updated_at" json:"updated_at"`
+}
+
+// file yang diupload ke media library. File disimpan di storage dengan
+// StorageKey, Checksum adalah sha256 isi file dalam hex. Width dan Height
+// hanya diisi untuk gambar.
+type Media struct {
+	Id          primitive.ObjectID `bson:"_id" json:"id" gorm:"primaryKey;serializer:objectid;size:24"`
+	Filename    string             `json:"filename"`
+	ContentType string             `bson:"content_type" json:"content_type"`
+	Size        int64              `json:"size"`
+	Width       int                `json:"width"`
+	Height      int                `json:"height"`
+	Checksum    string             `json:"checksum" gorm:"index;size:64"`
+	StorageKey  string             `bson:"storage_key" json:"-"`
+	UploadedBy  string             `bson:"uploaded_by" json:"uploaded_by"`
+	Variants    []MediaVariant     `json:"variants" gorm:"serializer:json"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// satu ukuran turunan sebuah Media, diambil lewat Url. Filenya disimpan
+// di storage dengan key "<StorageKey media>_<Name>".
+type MediaVariant struct {
+	Name        string `json:"name"`
+	Url         string `json:"url"`
+	ContentType string `bson:"content_type" json:"content_type"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	Size        int64  `json:"size"`
+}
+
+// satu versi sebuah dokumen, disimpan setiap kali dokumen dibuat, diedit
+// atau dikembalikan ke versi lama. Revision tidak pernah diubah atau
+// dihapus. Snapshot berisi seluruh dokumen dalam bentuk JSON-nya.
+type Revision struct {
+	Id           primitive.ObjectID     `bson:"_id" json:"id" gorm:"primaryKey;serializer:objectid;size:24"`
+	ContentType  string                 `bson:"content_type" json:"content_type" gorm:"uniqueIndex:revision_number;size:32"`
+	DocumentId   primitive.ObjectID     `bson:"document_id" json:"document_id" gorm:"uniqueIndex:revision_number;serializer:objectid;size:24"`
+	Number       int                    `json:"number" gorm:"uniqueIndex:revision_number"`
+	Action       string                 `json:"action"`
+	RestoredFrom *int                   `bson:"restored_from" json:"restored_from,omitempty"`
+	Snapshot     map[string]interface{} `json:"snapshot" gorm:"serializer:json"`
+	Author       string                 `json:"author"`
+	AuthorEmail  string                 `bson:"author_email" json:"author_email"`
+	CreatedAt    time.Time              `bson:"created_at" json:"created_at"`
+}
+
+// satu field yang berbeda antara dua versi sebuah dokumen. From atau To
+// nil kalau field itu tidak ada di versi tersebut.
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// satu catatan audit, ditulis setiap kali data user atau konten dibuat,
+// diubah atau dihapus. Catatan audit tidak pernah diubah atau dihapus.
+// Actor kosong untuk perubahan yang dibuat sistem tanpa request, misalnya
+// scheduler, yang namanya ada di ActorUid dengan awalan "system:".
+type AuditEntry struct {
+	Id           primitive.ObjectID `bson:"_id" json:"id" gorm:"primaryKey;serializer:objectid;size:24"`
+	Action       string             `json:"action" gorm:"size:16"`
+	ResourceType string             `bson:"resource_type" json:"resource_type" gorm:"index:audit_resource;size:32"`
+	ResourceId   string             `bson:"resource_id" json:"resource_id" gorm:"index:audit_resource;size:64"`
+	ActorUid     string             `bson:"actor_uid" json:"actor_uid" gorm:"index;size:64"`
+	ActorEmail   string             `bson:"actor_email" json:"actor_email" gorm:"index"`
+	Ip           string             `bson:"ip" json:"ip"`
+	UserAgent    string             `bson:"user_agent" json:"user_agent"`
+	Changes      []FieldChange      `json:"changes" gorm:"serializer:json"`
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at" gorm:"index"`
+}
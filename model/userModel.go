@@ -7,16 +7,83 @@ import (
 )
 
 type User struct {
-	ID primitive.ObjectID `bson:"_id"`
+	ID primitive.ObjectID `bson:"_id" gorm:"primaryKey;serializer:objectid;size:24"`
 	First_name    *string            `json:"first_name" validate:"required,min=2"`
 	Last_name     *string            `json:"last_name" validate:"required,min=2"`
-	Password      *string            `json:"password" validate:"required,min=8""`
-	Email         *string            `json:"email" validate:"email,required"`
-	Phone         *string            `json:"phone" validate:"required"`
-	User_type     *string            `json:"user_type" validate:"required,eq=ADMIN|eq=USER""`
+	Password      *string            `json:"password" validate:"required,min=8"`
+	Email         *string            `json:"email" validate:"email,required" gorm:"uniqueIndex"`
+	Phone         *string            `json:"phone" validate:"required" gorm:"uniqueIndex"`
+	User_type     *string            `json:"user_type" validate:"required,eq=ADMIN|eq=USER"`
+	Role          string             `json:"role" gorm:"index;size:32"`
 	Token         *string            `json:"token"`
 	Refresh_token *string            `json:"refresh_token"`
 	Created_at    time.Time          `json:"created_at"`
 	Updated_at    time.Time          `json:"updated_at"`
-	User_id       string             `json:"user_id"`
-}
\ No newline at end of file
+	User_id       string             `json:"user_id" gorm:"uniqueIndex;size:24"`
+}
+
+// role yang bisa diberikan ke user, dengan daftar permission seperti
+// "banner:write" atau "user:read". Role bawaan (admin, editor, author,
+// viewer) tidak disimpan di database; Builtin menandainya saat ditampilkan.
+type Role struct {
+	Id          primitive.ObjectID `bson:"_id" json:"id" gorm:"primaryKey;serializer:objectid;size:24"`
+	Name        string             `json:"name" validate:"required,max=32" gorm:"uniqueIndex;size:32"`
+	Description string             `json:"description"`
+	Permissions []string           `json:"permissions" validate:"required,min=1" gorm:"serializer:json"`
+	Builtin     bool               `bson:"-" json:"builtin" gorm:"-"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// refresh token dari satu sesi login. Token diganti setiap kali dipakai;
+// semua token dari satu sesi punya SessionId yang sama, sehingga token lama
+// yang dipakai lagi bisa mencabut seluruh sesi. Token hanya disimpan sebagai
+// hash.
+type RefreshToken struct {
+	Id        primitive.ObjectID `bson:"_id" json:"id" gorm:"primaryKey;serializer:objectid;size:24"`
+	UserId    string             `bson:"user_id" json:"user_id" gorm:"index;size:24"`
+	SessionId string             `bson:"session_id" json:"session_id" gorm:"index;size:24"`
+	TokenHash string             `bson:"token_hash" json:"-" gorm:"uniqueIndex;size:64"`
+	ExpiresAt time.Time          `bson:"expires_at" json:"expires_at" gorm:"index"`
+	UsedAt    *time.Time         `bson:"used_at" json:"used_at"`
+	RevokedAt *time.Time         `bson:"revoked_at" json:"revoked_at"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// token akses yang sudah dicabut sebelum waktunya, misalnya saat logout.
+// Catatan ini hanya perlu disimpan sampai token itu sendiri kedaluwarsa.
+type RevokedToken struct {
+	Id        primitive.ObjectID `bson:"_id" json:"id" gorm:"primaryKey;serializer:objectid;size:24"`
+	Jti       string             `bson:"jti" json:"jti" gorm:"uniqueIndex;size:64"`
+	UserId    string             `bson:"user_id" json:"user_id" gorm:"index;size:24"`
+	ExpiresAt time.Time          `bson:"expires_at" json:"expires_at" gorm:"index"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// token API pribadi milik seorang user, untuk program seperti pipeline build
+// atau storefront yang memanggil API tanpa password. Token hanya disimpan
+// sebagai hash, Prefix adalah awal token untuk mengenalinya. Permissions
+// membatasi apa yang boleh dilakukan token, di dalam batas role pemiliknya;
+// ExpiresAt kosong berarti token tidak kedaluwarsa.
+type ApiToken struct {
+	Id          primitive.ObjectID `bson:"_id" json:"id" gorm:"primaryKey;serializer:objectid;size:24"`
+	UserId      string             `bson:"user_id" json:"user_id" gorm:"index;size:24"`
+	Name        string             `json:"name" validate:"required,max=64" gorm:"size:64"`
+	Prefix      string             `json:"prefix" gorm:"size:16"`
+	TokenHash   string             `bson:"token_hash" json:"-" gorm:"uniqueIndex;size:64"`
+	Permissions []string           `json:"permissions" validate:"required,min=1" gorm:"serializer:json"`
+	ExpiresAt   *time.Time         `bson:"expires_at" json:"expires_at"`
+	LastUsedAt  *time.Time         `bson:"last_used_at" json:"last_used_at"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// permintaan reset password. Token yang dikirim lewat email hanya disimpan
+// sebagai hash dan hanya bisa dipakai sekali sebelum ExpiresAt.
+type PasswordReset struct {
+	Id        primitive.ObjectID `bson:"_id" json:"id" gorm:"primaryKey;serializer:objectid;size:24"`
+	UserId    string             `bson:"user_id" json:"user_id" gorm:"index;size:24"`
+	TokenHash string             `bson:"token_hash" json:"-" gorm:"uniqueIndex;size:64"`
+	ExpiresAt time.Time          `bson:"expires_at" json:"expires_at" gorm:"index"`
+	UsedAt    *time.Time         `bson:"used_at" json:"used_at"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
@@ -0,0 +1,224 @@
+// Package passwordreset lets users who forgot their password choose a new
+// one. A reset token is emailed to the user; it is stored as a hash, works
+// once and expires after a while.
+package passwordreset
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang_cms/helper"
+	"golang_cms/mailer"
+	"golang_cms/model"
+	"golang_cms/ratelimit"
+	"golang_cms/repository"
+	"golang_cms/scheduler"
+	"golang_cms/session"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrInvalidToken is returned for a reset token that is unknown, used or
+// expired.
+var ErrInvalidToken = errors.New("invalid or expired reset token")
+
+// ErrTooManyRequests is returned when an email was asked for more resets
+// than RequestsPerEmail allows.
+var ErrTooManyRequests = errors.New("too many password reset requests for this email")
+
+const (
+	// RequestsPerEmail resets can be asked for an email every hour, so that
+	// nobody can flood an inbox with reset emails.
+	RequestsPerEmail = 3
+	// concurrentSends limits the reset emails being sent at once.
+	concurrentSends = 4
+	// sendTimeout bounds sending one reset email, which outlives the
+	// request that asked for it.
+	sendTimeout = 30 * time.Second
+)
+
+// Service sends reset tokens and changes passwords with them.
+type Service struct {
+	resets   repository.PasswordResetRepository
+	users    repository.UserRepository
+	sessions *session.Manager
+	mail     mailer.Mailer
+	ttl      time.Duration
+	link     string
+
+	perEmail *ratelimit.Limiter
+	sending  chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewService sends reset tokens through mail that are valid for ttl. link is
+// the page where users choose their new password, which gets the token as
+// its "token" query parameter; without it the email holds the bare token.
+func NewService(repos repository.Repositories, sessions *session.Manager, mail mailer.Mailer, ttl time.Duration, link string) *Service {
+	return &Service{
+		resets:   repos.Reset,
+		users:    repos.User,
+		sessions: sessions,
+		mail:     mail,
+		ttl:      ttl,
+		link:     link,
+		perEmail: ratelimit.New(RequestsPerEmail, time.Hour),
+		sending:  make(chan struct{}, concurrentSends),
+	}
+}
+
+// RequestAsync is Request without waiting for it: the email is sent in the
+// background, so that registered and unknown emails are answered alike and
+// equally fast. It only fails with ErrTooManyRequests, which unknown emails
+// get just as registered ones do.
+func (s *Service) RequestAsync(email string) error {
+	if ok, _ := s.perEmail.Allow(strings.ToLower(strings.TrimSpace(email))); !ok {
+		return ErrTooManyRequests
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.sending <- struct{}{}
+		defer func() { <-s.sending }()
+
+		ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+		defer cancel()
+		if err := s.Request(ctx, email); err != nil {
+			log.Printf("password reset for %s: %v", email, err)
+		}
+	}()
+	return nil
+}
+
+// Wait blocks until the emails of RequestAsync have been sent or ctx ends.
+func (s *Service) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Request emails a reset token to the user with email, replacing any token
+// sent before. An unknown email is not an error, so that callers cannot
+// tell which emails are registered.
+func (s *Service) Request(ctx context.Context, email string) error {
+	user, err := s.users.FindByEmail(ctx, email)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	token, err := helper.RandomToken(32)
+	if err != nil {
+		return err
+	}
+	if _, err := s.resets.DeleteByUser(ctx, user.User_id); err != nil {
+		return err
+	}
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	if _, err := s.resets.Create(ctx, model.PasswordReset{
+		Id:        primitive.NewObjectID(),
+		UserId:    user.User_id,
+		TokenHash: helper.HashToken(token),
+		ExpiresAt: now.Add(s.ttl),
+		CreatedAt: now,
+	}); err != nil {
+		return err
+	}
+
+	data := mailer.PasswordReset{Token: token, ExpiresMinutes: int(s.ttl / time.Minute)}
+	if user.First_name != nil {
+		data.Name = *user.First_name
+	}
+	if s.link != "" {
+		link, err := url.Parse(s.link)
+		if err != nil {
+			return err
+		}
+		query := link.Query()
+		query.Set("token", token)
+		link.RawQuery = query.Encode()
+		data.Link = link.String()
+	}
+	msg, err := mailer.Render(mailer.TemplatePasswordReset, email, data)
+	if err != nil {
+		return err
+	}
+	msg.Secrets = []string{token}
+	return s.mail.Send(ctx, msg)
+}
+
+// Reset gives the user of token the password hashedPassword, already
+// hashed by the caller. Every session of the user ends, so whoever knew the
+// old password is logged out.
+func (s *Service) Reset(ctx context.Context, token string, hashedPassword string) error {
+	reset, err := s.resets.FindByHash(ctx, helper.HashToken(token))
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrInvalidToken
+	}
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	if reset.UsedAt != nil || !now.Before(reset.ExpiresAt) {
+		return ErrInvalidToken
+	}
+	// another request may have used the token since it was read
+	err = s.resets.MarkUsed(ctx, reset, now)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrInvalidToken
+	}
+	if err != nil {
+		return err
+	}
+
+	user, err := s.users.FindByUserID(ctx, reset.UserId)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrInvalidToken
+	}
+	if err != nil {
+		return err
+	}
+	user.Password = &hashedPassword
+	user.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	if _, err := s.users.Update(ctx, user); err != nil {
+		return err
+	}
+
+	if err := s.sessions.EndAll(ctx, user.User_id); err != nil {
+		return err
+	}
+	_, err = s.resets.DeleteByUser(ctx, user.User_id)
+	return err
+}
+
+// Job is the scheduler job that deletes expired reset tokens.
+func Job(repos repository.Repositories, interval time.Duration) scheduler.Job {
+	return scheduler.Job{
+		Name:     "password resets",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			deleted, err := repos.Reset.DeleteExpired(ctx, time.Now().UTC())
+			if deleted > 0 {
+				log.Printf("password resets: deleted %d expired reset tokens", deleted)
+			}
+			return err
+		},
+	}
+}
@@ -0,0 +1,178 @@
+package passwordreset
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang_cms/helper"
+	"golang_cms/mailer"
+	"golang_cms/model"
+	"golang_cms/repository"
+	"golang_cms/session"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// captureMailer keeps the messages it is given.
+type captureMailer struct {
+	mu   sync.Mutex
+	sent []mailer.Message
+}
+
+func (m *captureMailer) Send(ctx context.Context, msg mailer.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func (m *captureMailer) messages() []mailer.Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]mailer.Message(nil), m.sent...)
+}
+
+func newService(t *testing.T, ttl time.Duration) (*Service, *captureMailer, repository.Repositories, *session.Manager) {
+	t.Helper()
+	helper.SetSecretKey("test-secret")
+	repos := repository.NewMemoryRepositories()
+	sessions := session.NewManager(repos.Refresh, repos.Revoked, repos.User, time.Hour, time.Minute)
+	mail := &captureMailer{}
+
+	email, name, password := "ada@example.com", "Ada", "old-hash"
+	_, err := repos.User.Create(context.Background(), model.User{ID: primitive.NewObjectID(), User_id: "ada", Email: &email, Phone: &email, First_name: &name, Password: &password})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewService(repos, sessions, mail, ttl, "https://cms.example/reset?from=mail"), mail, repos, sessions
+}
+
+// tokenOf reads the reset token from the link of msg.
+func tokenOf(t *testing.T, msg mailer.Message) string {
+	t.Helper()
+	if len(msg.Secrets) != 1 {
+		t.Fatalf("secrets = %v", msg.Secrets)
+	}
+	token := msg.Secrets[0]
+	link := url.URL{Scheme: "https", Host: "cms.example", Path: "/reset", RawQuery: url.Values{"from": {"mail"}, "token": {token}}.Encode()}
+	if !strings.Contains(msg.Text, link.String()) {
+		t.Fatalf("email does not link to %s:\n%s", link.String(), msg.Text)
+	}
+	return token
+}
+
+func TestRequestAndReset(t *testing.T) {
+	ctx := context.Background()
+	s, mail, repos, sessions := newService(t, time.Hour)
+	user, _ := repos.User.FindByUserID(ctx, "ada")
+	tokens, err := sessions.Start(ctx, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Request(ctx, "ada@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	sent := mail.messages()
+	if len(sent) != 1 || sent[0].To[0] != "ada@example.com" {
+		t.Fatalf("sent = %+v", sent)
+	}
+	token := tokenOf(t, sent[0])
+
+	if err := s.Reset(ctx, token, "new-hash"); err != nil {
+		t.Fatal(err)
+	}
+	user, _ = repos.User.FindByUserID(ctx, "ada")
+	if *user.Password != "new-hash" {
+		t.Errorf("password = %q", *user.Password)
+	}
+	if _, err := sessions.Refresh(ctx, tokens.RefreshToken); !errors.Is(err, session.ErrInvalidRefreshToken) {
+		t.Errorf("refreshing a session from before the reset = %v", err)
+	}
+
+	if err := s.Reset(ctx, token, "other-hash"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("using a token twice = %v", err)
+	}
+	if err := s.Reset(ctx, "made-up", "other-hash"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("using an unknown token = %v", err)
+	}
+}
+
+func TestRequestReplacesEarlierTokens(t *testing.T) {
+	ctx := context.Background()
+	s, mail, _, _ := newService(t, time.Hour)
+	s.Request(ctx, "ada@example.com")
+	s.Request(ctx, "ada@example.com")
+	sent := mail.messages()
+	first, second := tokenOf(t, sent[0]), tokenOf(t, sent[1])
+
+	if err := s.Reset(ctx, first, "new-hash"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("using a replaced token = %v", err)
+	}
+	if err := s.Reset(ctx, second, "new-hash"); err != nil {
+		t.Errorf("using the latest token = %v", err)
+	}
+}
+
+func TestExpiredToken(t *testing.T) {
+	ctx := context.Background()
+	s, mail, _, _ := newService(t, -time.Minute)
+	s.Request(ctx, "ada@example.com")
+	if err := s.Reset(ctx, tokenOf(t, mail.messages()[0]), "new-hash"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("using an expired token = %v", err)
+	}
+}
+
+func TestRequestUnknownEmail(t *testing.T) {
+	s, mail, _, _ := newService(t, time.Hour)
+	if err := s.Request(context.Background(), "nobody@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if sent := mail.messages(); len(sent) != 0 {
+		t.Errorf("sent %d emails to an unknown address", len(sent))
+	}
+}
+
+func TestRequestAsync(t *testing.T) {
+	s, mail, _, _ := newService(t, time.Hour)
+	for i := 0; i < RequestsPerEmail; i++ {
+		if err := s.RequestAsync("ada@example.com"); err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+	}
+	if err := s.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if sent := mail.messages(); len(sent) != RequestsPerEmail {
+		t.Errorf("sent %d emails, want %d", len(sent), RequestsPerEmail)
+	}
+
+	// the limit ignores case and applies to unknown emails alike
+	if err := s.RequestAsync("ADA@example.com"); !errors.Is(err, ErrTooManyRequests) {
+		t.Errorf("request over the limit = %v", err)
+	}
+	for i := 0; i < RequestsPerEmail; i++ {
+		s.RequestAsync("nobody@example.com")
+	}
+	if err := s.RequestAsync("nobody@example.com"); !errors.Is(err, ErrTooManyRequests) {
+		t.Errorf("unknown email over the limit = %v", err)
+	}
+	s.Wait(context.Background())
+}
+
+func TestJobDeletesExpiredTokens(t *testing.T) {
+	ctx := context.Background()
+	s, _, repos, _ := newService(t, -time.Minute)
+	s.Request(ctx, "ada@example.com")
+	if err := Job(repos, time.Minute).Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if deleted, _ := repos.Reset.DeleteByUser(ctx, "ada"); deleted != 0 {
+		t.Errorf("%d expired tokens left", deleted)
+	}
+}
@@ -0,0 +1,64 @@
+// Package ratelimit limits how often something may happen per key, such as
+// password reset requests per client IP or per email.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter allows a number of events per key in every window of time,
+// counted from the first event of the window. It only counts within this
+// process, so every instance of the application has limits of its own.
+type Limiter struct {
+	limit  int
+	window time.Duration
+	now    func() time.Time
+
+	mu        sync.Mutex
+	windows   map[string]*window
+	nextSweep time.Time
+}
+
+type window struct {
+	start time.Time
+	count int
+}
+
+// New allows limit events per key in every window of per.
+func New(limit int, per time.Duration) *Limiter {
+	return &Limiter{limit: limit, window: per, now: time.Now, windows: map[string]*window{}}
+}
+
+// Allow counts an event for key and reports whether it is within the limit.
+// When it is not, retryAfter is how long until the window of key ends.
+func (l *Limiter) Allow(key string) (ok bool, retryAfter time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+	w := l.windows[key]
+	if w == nil || now.Sub(w.start) >= l.window {
+		w = &window{start: now}
+		l.windows[key] = w
+	}
+	if w.count >= l.limit {
+		return false, w.start.Add(l.window).Sub(now)
+	}
+	w.count++
+	return true, 0
+}
+
+// sweep drops the windows that have ended, at most once per window.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Before(l.nextSweep) {
+		return
+	}
+	for key, w := range l.windows {
+		if now.Sub(w.start) >= l.window {
+			delete(l.windows, key)
+		}
+	}
+	l.nextSweep = now.Add(l.window)
+}
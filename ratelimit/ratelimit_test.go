@@ -0,0 +1,45 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := New(2, time.Minute)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.Allow("a"); !ok {
+			t.Fatalf("event %d refused", i)
+		}
+	}
+	now = now.Add(20 * time.Second)
+	ok, retryAfter := l.Allow("a")
+	if ok || retryAfter != 40*time.Second {
+		t.Fatalf("event over the limit = %v, %v", ok, retryAfter)
+	}
+	if ok, _ := l.Allow("b"); !ok {
+		t.Fatal("other key refused")
+	}
+
+	now = now.Add(40 * time.Second)
+	if ok, _ := l.Allow("a"); !ok {
+		t.Fatal("event of a new window refused")
+	}
+}
+
+func TestLimiterForgetsEndedWindows(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := New(1, time.Minute)
+	l.now = func() time.Time { return now }
+	l.Allow("a")
+	l.Allow("b")
+
+	now = now.Add(2 * time.Minute)
+	l.Allow("c")
+	if len(l.windows) != 1 {
+		t.Errorf("%d windows kept, want 1", len(l.windows))
+	}
+}
@@ -0,0 +1,57 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"golang_cms/model"
+	"golang_cms/repository"
+)
+
+// Authorizer looks up the permissions of users through their role.
+type Authorizer struct {
+	users repository.UserRepository
+	roles repository.RoleRepository
+}
+
+func NewAuthorizer(users repository.UserRepository, roles repository.RoleRepository) *Authorizer {
+	return &Authorizer{users: users, roles: roles}
+}
+
+// RoleOf returns the role of user. Users created before roles existed have
+// none and keep the access their user type gave them.
+func RoleOf(user model.User) string {
+	if user.Role != "" {
+		return user.Role
+	}
+	if user.User_type != nil && *user.User_type == "ADMIN" {
+		return RoleAdmin
+	}
+	return RoleViewer
+}
+
+// Role returns the built-in or custom role called name.
+func (a *Authorizer) Role(ctx context.Context, name string) (model.Role, error) {
+	if permissions, ok := Builtin[name]; ok {
+		return model.Role{Name: name, Permissions: permissions, Builtin: true}, nil
+	}
+	role, err := a.roles.FindByName(ctx, name)
+	if errors.Is(err, repository.ErrNotFound) {
+		return role, fmt.Errorf("%w %q", ErrUnknownRole, name)
+	}
+	return role, err
+}
+
+// Permissions returns the permissions the role of user uid grants.
+func (a *Authorizer) Permissions(ctx context.Context, uid string) ([]string, error) {
+	user, err := a.users.FindByUserID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	role, err := a.Role(ctx, RoleOf(user))
+	if errors.Is(err, ErrUnknownRole) {
+		return nil, nil
+	}
+	return role.Permissions, err
+}
@@ -0,0 +1,153 @@
+// Package rbac decides what a user may do. Every user has one role, and a
+// role grants permissions of the form "<resource>:<action>", such as
+// "banner:write" or "user:read".
+package rbac
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	RoleAdmin  = "admin"
+	RoleEditor = "editor"
+	RoleAuthor = "author"
+	RoleViewer = "viewer"
+)
+
+const (
+	ActionRead    = "read"
+	ActionWrite   = "write"
+	ActionPublish = "publish"
+)
+
+// Wildcard grants every action on a resource in place of one, or every
+// permission on its own.
+const Wildcard = "*"
+
+// ErrUnknownPermission is returned for a permission that names a resource
+// or action that does not exist.
+var ErrUnknownPermission = errors.New("unknown permission")
+
+// ErrUnknownRole is returned when a user is assigned a role that does not
+// exist.
+var ErrUnknownRole = errors.New("unknown role")
+
+// ErrBuiltinRole is returned when a built-in role is created, changed or
+// deleted.
+var ErrBuiltinRole = errors.New("built-in roles cannot be changed")
+
+// content are the resources editors and authors work on. Those with a
+// publishing status can also be published.
+var (
+	content     = []string{"banner", "meta", "desc", "kategori", "category", "product", "media"}
+	publishable = map[string]bool{"banner": true, "meta": true, "desc": true, "kategori": true, "category": true}
+)
+
+// resources maps every resource to the actions it has.
+var resources = func() map[string][]string {
+	resources := map[string][]string{
+		"user":  {ActionRead, ActionWrite},
+		"role":  {ActionRead, ActionWrite},
+		"audit": {ActionRead},
+	}
+	for _, resource := range content {
+		resources[resource] = []string{ActionRead, ActionWrite}
+		if publishable[resource] {
+			resources[resource] = append(resources[resource], ActionPublish)
+		}
+	}
+	return resources
+}()
+
+// Permission is the permission to apply action to resource.
+func Permission(resource string, action string) string {
+	return resource + ":" + action
+}
+
+// Permissions lists every permission there is, sorted.
+func Permissions() []string {
+	permissions := []string{}
+	for resource, actions := range resources {
+		for _, action := range actions {
+			permissions = append(permissions, Permission(resource, action))
+		}
+	}
+	sort.Strings(permissions)
+	return permissions
+}
+
+// Validate checks that permission is one of Permissions, "<resource>:*" or
+// "*".
+func Validate(permission string) error {
+	if permission == Wildcard {
+		return nil
+	}
+	resource, action, _ := strings.Cut(permission, ":")
+	actions, ok := resources[resource]
+	if !ok {
+		return fmt.Errorf("%w %q", ErrUnknownPermission, permission)
+	}
+	if action == Wildcard {
+		return nil
+	}
+	for _, known := range actions {
+		if action == known {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w %q", ErrUnknownPermission, permission)
+}
+
+// Allows reports whether granted includes permission, directly or through
+// a wildcard.
+func Allows(granted []string, permission string) bool {
+	resource, _, _ := strings.Cut(permission, ":")
+	for _, grant := range granted {
+		if grant == permission || grant == Wildcard || grant == Permission(resource, Wildcard) {
+			return true
+		}
+	}
+	return false
+}
+
+// Intersect spells out the permissions that both a and b grant, such as
+// those of an API token limited to a subset of its owner's role.
+func Intersect(a []string, b []string) []string {
+	permissions := []string{}
+	for _, permission := range Permissions() {
+		if Allows(a, permission) && Allows(b, permission) {
+			permissions = append(permissions, permission)
+		}
+	}
+	return permissions
+}
+
+// Covers reports whether granted includes every permission that requested
+// grants, so that whoever holds granted may hand requested out.
+func Covers(granted []string, requested []string) bool {
+	return len(Intersect(granted, requested)) == len(Intersect(requested, []string{Wildcard}))
+}
+
+// Builtin holds the permissions of the built-in roles: admins may do
+// anything, editors manage and publish content, authors write content
+// without publishing it and viewers only read it.
+var Builtin = func() map[string][]string {
+	builtin := map[string][]string{
+		RoleAdmin: {Wildcard},
+	}
+	for _, resource := range content {
+		builtin[RoleEditor] = append(builtin[RoleEditor], Permission(resource, Wildcard))
+		builtin[RoleAuthor] = append(builtin[RoleAuthor], Permission(resource, ActionRead), Permission(resource, ActionWrite))
+		builtin[RoleViewer] = append(builtin[RoleViewer], Permission(resource, ActionRead))
+	}
+	return builtin
+}()
+
+// IsBuiltin reports whether name is one of the built-in roles.
+func IsBuiltin(name string) bool {
+	_, ok := Builtin[name]
+	return ok
+}
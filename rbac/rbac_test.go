@@ -0,0 +1,118 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"golang_cms/model"
+	"golang_cms/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestValidate(t *testing.T) {
+	for _, permission := range []string{"*", "banner:*", "banner:read", "banner:publish", "user:write", "audit:read"} {
+		if err := Validate(permission); err != nil {
+			t.Errorf("Validate(%q) = %v", permission, err)
+		}
+	}
+	for _, permission := range []string{"", "banner", "banner:delete", "product:publish", "audit:write", "nope:read"} {
+		if err := Validate(permission); !errors.Is(err, ErrUnknownPermission) {
+			t.Errorf("Validate(%q) = %v, want ErrUnknownPermission", permission, err)
+		}
+	}
+}
+
+func TestAllows(t *testing.T) {
+	tests := []struct {
+		granted    []string
+		permission string
+		want       bool
+	}{
+		{[]string{"*"}, "user:write", true},
+		{[]string{"banner:*"}, "banner:publish", true},
+		{[]string{"banner:*"}, "meta:read", false},
+		{[]string{"banner:read"}, "banner:read", true},
+		{[]string{"banner:read"}, "banner:write", false},
+		{nil, "banner:read", false},
+	}
+	for _, tt := range tests {
+		if got := Allows(tt.granted, tt.permission); got != tt.want {
+			t.Errorf("Allows(%v, %q) = %v, want %v", tt.granted, tt.permission, got, tt.want)
+		}
+	}
+}
+
+func TestBuiltinRoles(t *testing.T) {
+	if !Allows(Builtin[RoleEditor], "banner:publish") || Allows(Builtin[RoleEditor], "user:read") {
+		t.Errorf("editor = %v", Builtin[RoleEditor])
+	}
+	if Allows(Builtin[RoleAuthor], "banner:publish") || !Allows(Builtin[RoleAuthor], "banner:write") {
+		t.Errorf("author = %v", Builtin[RoleAuthor])
+	}
+	if Allows(Builtin[RoleViewer], "banner:write") || !Allows(Builtin[RoleViewer], "banner:read") {
+		t.Errorf("viewer = %v", Builtin[RoleViewer])
+	}
+}
+
+func TestCovers(t *testing.T) {
+	tests := []struct {
+		granted   []string
+		requested []string
+		want      bool
+	}{
+		{[]string{"*"}, []string{"*"}, true},
+		{[]string{"banner:*"}, []string{"banner:read", "banner:publish"}, true},
+		{[]string{"banner:read"}, []string{"banner:*"}, false},
+		{[]string{"banner:*", "meta:read"}, []string{"meta:write"}, false},
+		{Builtin[RoleEditor], []string{"*"}, false},
+		{Builtin[RoleEditor], Builtin[RoleAuthor], true},
+		{Builtin[RoleAuthor], Builtin[RoleEditor], false},
+	}
+	for _, tt := range tests {
+		if got := Covers(tt.granted, tt.requested); got != tt.want {
+			t.Errorf("Covers(%v, %v) = %v, want %v", tt.granted, tt.requested, got, tt.want)
+		}
+	}
+}
+
+func TestAuthorizerPermissions(t *testing.T) {
+	ctx := context.Background()
+	repos := repository.NewMemoryRepositories()
+	authz := NewAuthorizer(repos.User, repos.Role)
+
+	if _, err := repos.Role.Create(ctx, model.Role{Id: primitive.NewObjectID(), Name: "seo", Permissions: []string{"meta:*"}}); err != nil {
+		t.Fatal(err)
+	}
+	admin := "ADMIN"
+	users := []model.User{
+		{ID: primitive.NewObjectID(), User_id: "custom", Role: "seo"},
+		{ID: primitive.NewObjectID(), User_id: "legacy-admin", User_type: &admin},
+		{ID: primitive.NewObjectID(), User_id: "legacy"},
+		{ID: primitive.NewObjectID(), User_id: "deleted-role", Role: "gone"},
+	}
+	for _, user := range users {
+		email := user.User_id + "@example.com"
+		user.Email, user.Phone = &email, &email
+		if _, err := repos.User.Create(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := map[string][]string{
+		"custom":       {"meta:*"},
+		"legacy-admin": Builtin[RoleAdmin],
+		"legacy":       Builtin[RoleViewer],
+		"deleted-role": nil,
+	}
+	for uid, want := range tests {
+		got, err := authz.Permissions(ctx, uid)
+		if err != nil || len(got) != len(want) || (len(want) > 0 && got[0] != want[0]) {
+			t.Errorf("Permissions(%s) = %v, %v; want %v", uid, got, err, want)
+		}
+	}
+	if _, err := authz.Permissions(ctx, "missing"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Permissions(missing) = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"golang_cms/model"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type apiTokenRepository struct {
+	*crudRepository[model.ApiToken]
+}
+
+func newApiTokenRepository(s store[model.ApiToken]) *apiTokenRepository {
+	return &apiTokenRepository{newCrudRepository[model.ApiToken](s, "_id", apiTokenID)}
+}
+
+func (r *apiTokenRepository) FindByHash(ctx context.Context, hash string) (model.ApiToken, error) {
+	return r.store.findOne(ctx, Filter{"token_hash": hash})
+}
+
+func (r *apiTokenRepository) MarkUsed(ctx context.Context, token model.ApiToken, at time.Time) error {
+	token.LastUsedAt = &at
+	return r.store.replace(ctx, Filter{"_id": token.Id}, token)
+}
+
+func apiTokenID(token model.ApiToken) primitive.ObjectID { return token.Id }
@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"context"
+
+	"golang_cms/model"
+)
+
+// auditRepository only ever inserts: the audit log is append-only.
+type auditRepository struct {
+	store store[model.AuditEntry]
+}
+
+func newAuditRepository(s store[model.AuditEntry]) *auditRepository {
+	return &auditRepository{store: s}
+}
+
+func (r *auditRepository) Create(ctx context.Context, entry model.AuditEntry) (model.AuditEntry, error) {
+	if err := r.store.insert(ctx, entry); err != nil {
+		return model.AuditEntry{}, err
+	}
+	return entry, nil
+}
+
+func (r *auditRepository) List(ctx context.Context, q Query) ([]model.AuditEntry, int64, error) {
+	return list(ctx, r.store, q)
+}
@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"context"
+
+	"golang_cms/model"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type childCategoryRepository struct {
+	*crudRepository[model.ChildCategory]
+}
+
+func newChildCategoryRepository(s store[model.ChildCategory]) *childCategoryRepository {
+	return &childCategoryRepository{newCrudRepository[model.ChildCategory](s, "_id", childCategoryID)}
+}
+
+func (r *childCategoryRepository) FindByParent(ctx context.Context, parentId primitive.ObjectID) ([]model.ChildCategory, error) {
+	return r.store.find(ctx, Query{Filter: Filter{"idmaincategory": parentId}})
+}
+
+func (r *childCategoryRepository) CountByParent(ctx context.Context, parentId primitive.ObjectID) (int64, error) {
+	return r.store.count(ctx, Filter{"idmaincategory": parentId})
+}
+
+func childCategoryID(k model.ChildCategory) primitive.ObjectID { return k.Id }
@@ -0,0 +1,258 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+
+	"golang_cms/model"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrInvalidMove is returned when a category would be moved under itself or
+// one of its own descendants.
+var ErrInvalidMove = errors.New("category cannot be moved under itself or its descendants")
+
+// ErrInvalidOrder is returned when a reorder request does not list exactly
+// the children of the parent being reordered.
+var ErrInvalidOrder = errors.New("order must list every sibling exactly once")
+
+// categoryTreeRepository keeps the materialized path, depth and sibling
+// order of every category consistent. Moves touch the whole subtree one
+// document at a time, since not every backend offers multi-document
+// transactions through the store.
+type categoryTreeRepository struct {
+	*crudRepository[model.Category]
+}
+
+func newCategoryTreeRepository(s store[model.Category]) *categoryTreeRepository {
+	return &categoryTreeRepository{newCrudRepository[model.Category](s, "_id", categoryTreeID)}
+}
+
+// Create places doc under its ParentId, or at the root when it has none,
+// after the siblings that are already there.
+func (r *categoryTreeRepository) Create(ctx context.Context, doc model.Category) (model.Category, error) {
+	parent, err := r.parentOf(ctx, doc.ParentId)
+	if err != nil {
+		return model.Category{}, err
+	}
+
+	siblings, err := r.FindChildren(ctx, doc.ParentId)
+	if err != nil {
+		return model.Category{}, err
+	}
+
+	place(&doc, parent)
+	doc.Order = len(siblings)
+	return r.crudRepository.Create(ctx, doc)
+}
+
+func (r *categoryTreeRepository) FindChildren(ctx context.Context, parentId *primitive.ObjectID) ([]model.Category, error) {
+	children, err := r.store.find(ctx, Query{Filter: Filter{"parent_id": parentId}})
+	if err != nil {
+		return nil, err
+	}
+	SortCategories(children)
+	return children, nil
+}
+
+func (r *categoryTreeRepository) CountChildren(ctx context.Context, id primitive.ObjectID) (int64, error) {
+	return r.store.count(ctx, Filter{"parent_id": id})
+}
+
+// FindDescendants returns every category below root, at any depth: those
+// whose path starts with the path of root.
+func (r *categoryTreeRepository) FindDescendants(ctx context.Context, root model.Category) ([]model.Category, error) {
+	descendants, err := r.store.find(ctx, Query{Filter: Filter{"path": Prefix(root.Path), "_id": Ne(root.Id)}})
+	if err != nil {
+		return nil, err
+	}
+	SortCategories(descendants)
+	return descendants, nil
+}
+
+// FindAncestors returns the chain from the root down to the parent of
+// category, read from its path.
+func (r *categoryTreeRepository) FindAncestors(ctx context.Context, category model.Category) ([]model.Category, error) {
+	var ancestors []model.Category
+	for _, hex := range strings.Split(strings.Trim(category.Path, "/"), "/") {
+		id, err := primitive.ObjectIDFromHex(hex)
+		if err != nil {
+			return nil, err
+		}
+		if id == category.Id {
+			break
+		}
+
+		ancestor, err := r.FindByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		ancestors = append(ancestors, ancestor)
+	}
+	return ancestors, nil
+}
+
+// Move puts the category under parentId (the root when nil) at position
+// among its new siblings, rewriting the path of its whole subtree. A
+// negative or too large position appends it at the end.
+func (r *categoryTreeRepository) Move(ctx context.Context, id primitive.ObjectID, parentId *primitive.ObjectID, position int) (model.Category, error) {
+	category, err := r.FindByID(ctx, id)
+	if err != nil {
+		return model.Category{}, err
+	}
+
+	parent, err := r.parentOf(ctx, parentId)
+	if err != nil {
+		return model.Category{}, err
+	}
+	if parent != nil && strings.HasPrefix(parent.Path, category.Path) {
+		return model.Category{}, ErrInvalidMove
+	}
+
+	descendants, err := r.FindDescendants(ctx, category)
+	if err != nil {
+		return model.Category{}, err
+	}
+
+	oldParentId := category.ParentId
+	oldPath, oldDepth := category.Path, category.Depth
+	place(&category, parent)
+	if category, err = r.Update(ctx, category); err != nil {
+		return model.Category{}, err
+	}
+	for _, descendant := range descendants {
+		descendant.Path = category.Path + strings.TrimPrefix(descendant.Path, oldPath)
+		descendant.Depth += category.Depth - oldDepth
+		if _, err := r.Update(ctx, descendant); err != nil {
+			return model.Category{}, err
+		}
+	}
+
+	siblings, err := r.FindChildren(ctx, parentId)
+	if err != nil {
+		return model.Category{}, err
+	}
+	var ordered []model.Category
+	for _, sibling := range siblings {
+		if sibling.Id != category.Id {
+			ordered = append(ordered, sibling)
+		}
+	}
+	if position < 0 || position > len(ordered) {
+		position = len(ordered)
+	}
+	ordered = append(ordered[:position], append([]model.Category{category}, ordered[position:]...)...)
+	if err := r.renumber(ctx, ordered); err != nil {
+		return model.Category{}, err
+	}
+
+	if !sameParent(oldParentId, parentId) {
+		previous, err := r.FindChildren(ctx, oldParentId)
+		if err != nil {
+			return model.Category{}, err
+		}
+		if err := r.renumber(ctx, previous); err != nil {
+			return model.Category{}, err
+		}
+	}
+
+	return r.FindByID(ctx, id)
+}
+
+// Reorder sets the order of the children of parentId (the root categories
+// when nil) to the order of ids, which must name each of them once.
+func (r *categoryTreeRepository) Reorder(ctx context.Context, parentId *primitive.ObjectID, ids []primitive.ObjectID) ([]model.Category, error) {
+	siblings, err := r.FindChildren(ctx, parentId)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) != len(siblings) {
+		return nil, ErrInvalidOrder
+	}
+
+	byId := make(map[primitive.ObjectID]model.Category, len(siblings))
+	for _, sibling := range siblings {
+		byId[sibling.Id] = sibling
+	}
+	ordered := make([]model.Category, 0, len(ids))
+	for _, id := range ids {
+		sibling, ok := byId[id]
+		if !ok {
+			return nil, ErrInvalidOrder
+		}
+		delete(byId, id)
+		ordered = append(ordered, sibling)
+	}
+
+	if err := r.renumber(ctx, ordered); err != nil {
+		return nil, err
+	}
+	return r.FindChildren(ctx, parentId)
+}
+
+// renumber stores 0..n-1 as the order of siblings, skipping unchanged ones.
+func (r *categoryTreeRepository) renumber(ctx context.Context, siblings []model.Category) error {
+	for i, sibling := range siblings {
+		if sibling.Order == i {
+			continue
+		}
+		sibling.Order = i
+		if _, err := r.Update(ctx, sibling); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parentOf loads the category named by parentId, or nil for the root.
+func (r *categoryTreeRepository) parentOf(ctx context.Context, parentId *primitive.ObjectID) (*model.Category, error) {
+	if parentId == nil {
+		return nil, nil
+	}
+	parent, err := r.FindByID(ctx, *parentId)
+	if err != nil {
+		return nil, err
+	}
+	return &parent, nil
+}
+
+// place sets the parent, path and depth of category for a position under
+// parent, or at the root when parent is nil.
+func place(category *model.Category, parent *model.Category) {
+	category.ParentId = nil
+	category.Path = "/" + category.Id.Hex() + "/"
+	category.Depth = 0
+	if parent != nil {
+		parentId := parent.Id
+		category.ParentId = &parentId
+		category.Path = parent.Path + category.Id.Hex() + "/"
+		category.Depth = parent.Depth + 1
+	}
+}
+
+func sameParent(a *primitive.ObjectID, b *primitive.ObjectID) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// SortCategories orders categories by depth, then sibling order, then name,
+// which is the order trees are built and displayed in.
+func SortCategories(categories []model.Category) {
+	sort.SliceStable(categories, func(i, j int) bool {
+		a, b := categories[i], categories[j]
+		if a.Depth != b.Depth {
+			return a.Depth < b.Depth
+		}
+		if a.Order != b.Order {
+			return a.Order < b.Order
+		}
+		return a.Name < b.Name
+	})
+}
+
+func categoryTreeID(c model.Category) primitive.ObjectID { return c.Id }
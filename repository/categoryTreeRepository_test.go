@@ -0,0 +1,207 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"golang_cms/model"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// testTree holds the categories created by newTestTree:
+//
+//	a
+//	  a1
+//	    a11
+//	  a2
+//	b
+type testTree map[string]model.Category
+
+func newTestTree(t *testing.T, repo CategoryTreeRepository) testTree {
+	t.Helper()
+	tree := testTree{}
+	for _, node := range []struct{ name, parent string }{
+		{"a", ""}, {"b", ""}, {"a1", "a"}, {"a2", "a"}, {"a11", "a1"},
+	} {
+		category := model.Category{Id: primitive.NewObjectID(), Name: node.name}
+		if node.parent != "" {
+			parentId := tree[node.parent].Id
+			category.ParentId = &parentId
+		}
+		created, err := repo.Create(context.Background(), category)
+		if err != nil {
+			t.Fatalf("create %s: %v", node.name, err)
+		}
+		tree[node.name] = created
+	}
+	return tree
+}
+
+// reload reads every category of the tree again.
+func (tree testTree) reload(t *testing.T, repo CategoryTreeRepository) {
+	t.Helper()
+	for name, category := range tree {
+		stored, err := repo.FindByID(context.Background(), category.Id)
+		if err != nil {
+			t.Fatalf("reload %s: %v", name, err)
+		}
+		tree[name] = stored
+	}
+}
+
+func (tree testTree) names(categories []model.Category) []string {
+	names := []string{}
+	for _, category := range categories {
+		for name, node := range tree {
+			if node.Id == category.Id {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// checkPlace fails unless the named category sits under parent ("" for the
+// root) at the given sibling order, with a path and depth to match.
+func (tree testTree) checkPlace(t *testing.T, name, parent string, order int) {
+	t.Helper()
+	category := tree[name]
+	wantPath, wantDepth := "/"+category.Id.Hex()+"/", 0
+	var wantParent *primitive.ObjectID
+	if parent != "" {
+		parentId := tree[parent].Id
+		wantParent = &parentId
+		wantPath = tree[parent].Path + category.Id.Hex() + "/"
+		wantDepth = tree[parent].Depth + 1
+	}
+	if !sameParent(category.ParentId, wantParent) || category.Path != wantPath || category.Depth != wantDepth || category.Order != order {
+		t.Errorf("%s = parent %v, path %s, depth %d, order %d; want parent %s, path %s, depth %d, order %d",
+			name, category.ParentId, category.Path, category.Depth, category.Order, parent, wantPath, wantDepth, order)
+	}
+}
+
+func TestCategoryTreeCreate(t *testing.T) {
+	eachBackend(t, func(t *testing.T, repos Repositories) {
+		tree := newTestTree(t, repos.Tree)
+		tree.reload(t, repos.Tree)
+		tree.checkPlace(t, "a", "", 0)
+		tree.checkPlace(t, "b", "", 1)
+		tree.checkPlace(t, "a1", "a", 0)
+		tree.checkPlace(t, "a2", "a", 1)
+		tree.checkPlace(t, "a11", "a1", 0)
+
+		missing := primitive.NewObjectID()
+		if _, err := repos.Tree.Create(context.Background(), model.Category{Id: primitive.NewObjectID(), Name: "x", ParentId: &missing}); !errors.Is(err, ErrNotFound) {
+			t.Errorf("create under a missing parent: %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestCategoryTreeReads(t *testing.T) {
+	eachBackend(t, func(t *testing.T, repos Repositories) {
+		ctx := context.Background()
+		tree := newTestTree(t, repos.Tree)
+
+		roots, err := repos.Tree.FindChildren(ctx, nil)
+		if got := tree.names(roots); err != nil || !reflect.DeepEqual(got, []string{"a", "b"}) {
+			t.Errorf("roots = %v, %v", got, err)
+		}
+		descendants, err := repos.Tree.FindDescendants(ctx, tree["a"])
+		if got := tree.names(descendants); err != nil || !reflect.DeepEqual(got, []string{"a1", "a2", "a11"}) {
+			t.Errorf("descendants of a = %v, %v", got, err)
+		}
+		descendants, err = repos.Tree.FindDescendants(ctx, tree["a1"])
+		if got := tree.names(descendants); err != nil || !reflect.DeepEqual(got, []string{"a11"}) {
+			t.Errorf("descendants of a1 = %v, %v", got, err)
+		}
+		descendants, err = repos.Tree.FindDescendants(ctx, tree["b"])
+		if err != nil || len(descendants) != 0 {
+			t.Errorf("descendants of the leaf b = %v, %v", tree.names(descendants), err)
+		}
+		ancestors, err := repos.Tree.FindAncestors(ctx, tree["a11"])
+		if got := tree.names(ancestors); err != nil || !reflect.DeepEqual(got, []string{"a", "a1"}) {
+			t.Errorf("ancestors of a11 = %v, %v", got, err)
+		}
+		if count, err := repos.Tree.CountChildren(ctx, tree["a"].Id); err != nil || count != 2 {
+			t.Errorf("children of a = %d, %v", count, err)
+		}
+	})
+}
+
+func TestCategoryTreeMove(t *testing.T) {
+	eachBackend(t, func(t *testing.T, repos Repositories) {
+		ctx := context.Background()
+		tree := newTestTree(t, repos.Tree)
+
+		bId := tree["b"].Id
+		if _, err := repos.Tree.Move(ctx, tree["a1"].Id, &bId, 0); err != nil {
+			t.Fatalf("move a1 under b: %v", err)
+		}
+		tree.reload(t, repos.Tree)
+		tree.checkPlace(t, "a1", "b", 0)
+		tree.checkPlace(t, "a11", "a1", 0)
+		tree.checkPlace(t, "a2", "a", 0)
+
+		if _, err := repos.Tree.Move(ctx, tree["a11"].Id, nil, 0); err != nil {
+			t.Fatalf("move a11 to the root: %v", err)
+		}
+		tree.reload(t, repos.Tree)
+		tree.checkPlace(t, "a11", "", 0)
+		tree.checkPlace(t, "a", "", 1)
+		tree.checkPlace(t, "b", "", 2)
+
+		// a position past the end appends
+		aId := tree["a"].Id
+		if _, err := repos.Tree.Move(ctx, tree["a11"].Id, &aId, 99); err != nil {
+			t.Fatalf("move a11 under a: %v", err)
+		}
+		tree.reload(t, repos.Tree)
+		tree.checkPlace(t, "a11", "a", 1)
+		tree.checkPlace(t, "a", "", 0)
+	})
+}
+
+func TestCategoryTreeMoveUnderItself(t *testing.T) {
+	eachBackend(t, func(t *testing.T, repos Repositories) {
+		ctx := context.Background()
+		tree := newTestTree(t, repos.Tree)
+
+		for _, target := range []string{"a", "a1", "a11"} {
+			parentId := tree[target].Id
+			if _, err := repos.Tree.Move(ctx, tree["a"].Id, &parentId, 0); !errors.Is(err, ErrInvalidMove) {
+				t.Errorf("move a under %s: %v, want ErrInvalidMove", target, err)
+			}
+		}
+		tree.reload(t, repos.Tree)
+		tree.checkPlace(t, "a", "", 0)
+	})
+}
+
+func TestCategoryTreeReorder(t *testing.T) {
+	eachBackend(t, func(t *testing.T, repos Repositories) {
+		ctx := context.Background()
+		tree := newTestTree(t, repos.Tree)
+		aId := tree["a"].Id
+
+		children, err := repos.Tree.Reorder(ctx, &aId, []primitive.ObjectID{tree["a2"].Id, tree["a1"].Id})
+		if got := tree.names(children); err != nil || !reflect.DeepEqual(got, []string{"a2", "a1"}) {
+			t.Fatalf("reorder = %v, %v", got, err)
+		}
+
+		for name, ids := range map[string][]primitive.ObjectID{
+			"missing a sibling": {tree["a2"].Id},
+			"a sibling twice":   {tree["a2"].Id, tree["a2"].Id},
+			"a stranger":        {tree["a2"].Id, tree["b"].Id},
+		} {
+			if _, err := repos.Tree.Reorder(ctx, &aId, ids); !errors.Is(err, ErrInvalidOrder) {
+				t.Errorf("reorder with %s: %v, want ErrInvalidOrder", name, err)
+			}
+		}
+		tree.reload(t, repos.Tree)
+		tree.checkPlace(t, "a2", "a", 0)
+		tree.checkPlace(t, "a1", "a", 1)
+	})
+}
@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+
+	"golang_cms/model"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type mediaRepository struct {
+	*crudRepository[model.Media]
+}
+
+func newMediaRepository(s store[model.Media]) *mediaRepository {
+	return &mediaRepository{newCrudRepository[model.Media](s, "_id", mediaID)}
+}
+
+func (r *mediaRepository) FindByChecksum(ctx context.Context, checksum string) (model.Media, error) {
+	return r.store.findOne(ctx, Filter{"checksum": checksum})
+}
+
+func mediaID(m model.Media) primitive.ObjectID { return m.Id }
@@ -0,0 +1,427 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memoryStore keeps documents as encoded bson so that reads hand out fresh
+// copies and filters see the same field names and value types Mongo would.
+type memoryStore[T any] struct {
+	mu     sync.RWMutex
+	docs   []bson.Raw
+	unique []string
+}
+
+// newMemoryStore returns an empty store enforcing uniqueness of the given
+// fields, the way unique indexes do in the other backends.
+func newMemoryStore[T any](unique ...string) *memoryStore[T] {
+	return &memoryStore[T]{unique: unique}
+}
+
+func (s *memoryStore[T]) insert(ctx context.Context, doc T) error {
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conflicts(raw, -1) {
+		return ErrDuplicate
+	}
+	s.docs = append(s.docs, raw)
+	return nil
+}
+
+// conflicts reports whether raw shares a unique field value with any stored
+// document other than the one at index skip.
+func (s *memoryStore[T]) conflicts(raw bson.Raw, skip int) bool {
+	for _, field := range s.unique {
+		value, err := raw.LookupErr(field)
+		if err != nil || value.Type == bsontype.Null {
+			continue
+		}
+		for i, doc := range s.docs {
+			if i != skip && doc.Lookup(field).Equal(value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s *memoryStore[T]) findOne(ctx context.Context, filter Filter) (T, error) {
+	var doc T
+	match, err := newMatcher(filter)
+	if err != nil {
+		return doc, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, raw := range s.docs {
+		if match(raw) {
+			err := bson.Unmarshal(raw, &doc)
+			return doc, err
+		}
+	}
+	return doc, ErrNotFound
+}
+
+func (s *memoryStore[T]) find(ctx context.Context, q Query) ([]T, error) {
+	match, err := newMatcher(q.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := withIdOrder(q.Sort)
+	if q.After != nil {
+		after, err := normalize(q.After)
+		if err != nil {
+			return nil, err
+		}
+		filter := match
+		match = func(doc bson.Raw) bool {
+			return filter(doc) && sortsAfter(doc, fields, after.(bson.A))
+		}
+	}
+
+	s.mu.RLock()
+	var matched []bson.Raw
+	for _, raw := range s.docs {
+		if match(raw) {
+			matched = append(matched, raw)
+		}
+	}
+	s.mu.RUnlock()
+
+	if err := sortDocs(matched, fields); err != nil {
+		return nil, err
+	}
+	if q.Skip >= int64(len(matched)) {
+		return nil, nil
+	}
+	matched = matched[q.Skip:]
+	if q.Limit > 0 && q.Limit < int64(len(matched)) {
+		matched = matched[:q.Limit]
+	}
+
+	var docs []T
+	for _, raw := range matched {
+		var doc T
+		if err := bson.Unmarshal(raw, &doc); err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+// sortDocs orders docs by the given fields, missing values first, as Mongo
+// sorts them.
+func sortDocs(docs []bson.Raw, fields []Sort) error {
+	type decodedDoc struct {
+		raw    bson.Raw
+		values bson.M
+	}
+
+	decoded := make([]decodedDoc, len(docs))
+	for i, raw := range docs {
+		decoded[i].raw = raw
+		if err := bson.Unmarshal(raw, &decoded[i].values); err != nil {
+			return err
+		}
+	}
+
+	sort.SliceStable(decoded, func(i, j int) bool {
+		for _, field := range fields {
+			c, _ := compareValues(decoded[i].values[field.Field], decoded[j].values[field.Field])
+			if c == 0 {
+				continue
+			}
+			if field.Desc {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+
+	for i := range decoded {
+		docs[i] = decoded[i].raw
+	}
+	return nil
+}
+
+func (s *memoryStore[T]) count(ctx context.Context, filter Filter) (int64, error) {
+	match, err := newMatcher(filter)
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var total int64
+	for _, raw := range s.docs {
+		if match(raw) {
+			total++
+		}
+	}
+	return total, nil
+}
+
+func (s *memoryStore[T]) replace(ctx context.Context, filter Filter, doc T) error {
+	match, err := newMatcher(filter)
+	if err != nil {
+		return err
+	}
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.docs {
+		if match(s.docs[i]) {
+			if s.conflicts(raw, i) {
+				return ErrDuplicate
+			}
+			s.docs[i] = raw
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (s *memoryStore[T]) delete(ctx context.Context, filter Filter) error {
+	match, err := newMatcher(filter)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.docs {
+		if match(s.docs[i]) {
+			s.docs = append(s.docs[:i], s.docs[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+// sortsAfter reports whether doc is ordered after the sort values in after.
+func sortsAfter(doc bson.Raw, fields []Sort, after bson.A) bool {
+	var have bson.M
+	if err := bson.Unmarshal(doc, &have); err != nil {
+		return false
+	}
+	for i, field := range fields {
+		c, _ := compareValues(have[field.Field], after[i])
+		if c == 0 {
+			continue
+		}
+		if field.Desc {
+			return c < 0
+		}
+		return c > 0
+	}
+	return false
+}
+
+// newMatcher round-trips the filter values through bson so they are
+// compared in the same representation as the stored documents.
+func newMatcher(filter Filter) (func(bson.Raw) bool, error) {
+	plain := bson.M{}
+	conds := map[string][]Cond{}
+	for field, value := range filter {
+		fieldConds, ok := condsOf(value)
+		if !ok {
+			plain[field] = value
+			continue
+		}
+		for _, cond := range fieldConds {
+			values, err := normalize(cond.Values)
+			if err != nil {
+				return nil, err
+			}
+			cond.Values, _ = values.(bson.A)
+			conds[field] = append(conds[field], cond)
+		}
+	}
+	raw, err := bson.Marshal(plain)
+	if err != nil {
+		return nil, err
+	}
+	var want bson.M
+	if err := bson.Unmarshal(raw, &want); err != nil {
+		return nil, err
+	}
+
+	return func(doc bson.Raw) bool {
+		var have bson.M
+		if err := bson.Unmarshal(doc, &have); err != nil {
+			return false
+		}
+		for field, value := range want {
+			if !valuesEqual(have[field], value) {
+				return false
+			}
+		}
+		for field, fieldConds := range conds {
+			for _, cond := range fieldConds {
+				if !condMatches(have[field], cond) {
+					return false
+				}
+			}
+		}
+		return true
+	}, nil
+}
+
+// normalize returns value as bson would decode it into an interface{}.
+func normalize(value interface{}) (interface{}, error) {
+	raw, err := bson.Marshal(bson.M{"v": value})
+	if err != nil {
+		return nil, err
+	}
+	var doc bson.M
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+	return doc["v"], nil
+}
+
+// condMatches applies cond to a document value. Array values match when
+// any of their elements does, except for OpNe which needs all of them to
+// differ, as in Mongo.
+func condMatches(have interface{}, cond Cond) bool {
+	if elements, ok := have.(bson.A); ok {
+		for _, element := range elements {
+			matches := condMatches(element, cond)
+			if cond.Op == OpNe && !matches {
+				return false
+			}
+			if cond.Op != OpNe && matches {
+				return true
+			}
+		}
+		return cond.Op == OpNe
+	}
+
+	switch cond.Op {
+	case OpEq:
+		return valuesEqual(have, cond.value())
+	case OpIn:
+		for _, value := range cond.Values {
+			if valuesEqual(have, value) {
+				return true
+			}
+		}
+	case OpNe:
+		return !valuesEqual(have, cond.value())
+	case OpContains:
+		text, ok := have.(string)
+		want, _ := cond.value().(string)
+		return ok && strings.Contains(strings.ToLower(text), strings.ToLower(want))
+	case OpPrefix:
+		text, ok := have.(string)
+		want, _ := cond.value().(string)
+		return ok && strings.HasPrefix(text, want)
+	case OpGte:
+		c, ok := compareValues(have, cond.value())
+		return ok && c >= 0
+	case OpLte:
+		c, ok := compareValues(have, cond.value())
+		return ok && c <= 0
+	}
+	return false
+}
+
+func valuesEqual(a interface{}, b interface{}) bool {
+	if x, ok := toFloat(a); ok {
+		if y, ok := toFloat(b); ok {
+			return x == y
+		}
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// compareValues orders two decoded bson values of the same kind. Nil sorts
+// before everything; values of different kinds are not comparable.
+func compareValues(a interface{}, b interface{}) (int, bool) {
+	if a == nil || b == nil {
+		switch {
+		case a == nil && b == nil:
+			return 0, true
+		case a == nil:
+			return -1, true
+		}
+		return 1, true
+	}
+
+	if x, ok := toFloat(a); ok {
+		if y, ok := toFloat(b); ok {
+			return compareOrdered(x, y), true
+		}
+		return 0, false
+	}
+
+	switch x := a.(type) {
+	case string:
+		if y, ok := b.(string); ok {
+			return strings.Compare(x, y), true
+		}
+	case primitive.DateTime:
+		if y, ok := b.(primitive.DateTime); ok {
+			return compareOrdered(x, y), true
+		}
+	case primitive.ObjectID:
+		if y, ok := b.(primitive.ObjectID); ok {
+			return bytes.Compare(x[:], y[:]), true
+		}
+	case bool:
+		if y, ok := b.(bool); ok && x != y {
+			if x {
+				return 1, true
+			}
+			return -1, true
+		} else if ok {
+			return 0, true
+		}
+	}
+	return 0, false
+}
+
+func compareOrdered[N float64 | primitive.DateTime](a N, b N) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"regexp"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoStore[T any] struct {
+	collection *mongo.Collection
+}
+
+func newMongoStore[T any](collection *mongo.Collection) *mongoStore[T] {
+	return &mongoStore[T]{collection: collection}
+}
+
+func (s *mongoStore[T]) insert(ctx context.Context, doc T) error {
+	_, err := s.collection.InsertOne(ctx, doc)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+func (s *mongoStore[T]) findOne(ctx context.Context, filter Filter) (T, error) {
+	var doc T
+	err := s.collection.FindOne(ctx, mongoFilter(filter)).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return doc, ErrNotFound
+	}
+	return doc, err
+}
+
+func (s *mongoStore[T]) find(ctx context.Context, q Query) ([]T, error) {
+	fields := withIdOrder(q.Sort)
+	filter := mongoFilter(q.Filter)
+	if q.After != nil {
+		filter = bson.M{"$and": bson.A{filter, mongoSeek(fields, q.After)}}
+	}
+
+	var sort bson.D
+	for _, field := range fields {
+		direction := 1
+		if field.Desc {
+			direction = -1
+		}
+		sort = append(sort, bson.E{Key: field.Field, Value: direction})
+	}
+
+	opts := options.Find().SetSort(sort)
+	if q.Skip > 0 {
+		opts.SetSkip(q.Skip)
+	}
+	if q.Limit > 0 {
+		opts.SetLimit(q.Limit)
+	}
+
+	cursor, err := s.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var docs []T
+	for cursor.Next(ctx) {
+		var doc T
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+	return docs, cursor.Err()
+}
+
+func (s *mongoStore[T]) count(ctx context.Context, filter Filter) (int64, error) {
+	return s.collection.CountDocuments(ctx, mongoFilter(filter))
+}
+
+func (s *mongoStore[T]) replace(ctx context.Context, filter Filter, doc T) error {
+	result, err := s.collection.ReplaceOne(ctx, mongoFilter(filter), doc)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicate
+	}
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *mongoStore[T]) delete(ctx context.Context, filter Filter) error {
+	result, err := s.collection.DeleteOne(ctx, mongoFilter(filter))
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// mongoFilter translates a Filter into a Mongo query document. Conditions
+// go into an $and so several of them can apply to the same field.
+func mongoFilter(filter Filter) bson.M {
+	query := bson.M{}
+	var and bson.A
+	for field, value := range filter {
+		conds, ok := condsOf(value)
+		if !ok {
+			query[field] = value
+			continue
+		}
+		for _, cond := range conds {
+			and = append(and, bson.M{field: mongoCond(cond)})
+		}
+	}
+	if len(and) > 0 {
+		query["$and"] = and
+	}
+	return query
+}
+
+func mongoCond(cond Cond) bson.M {
+	switch cond.Op {
+	case OpEq:
+		return bson.M{"$eq": cond.value()}
+	case OpIn:
+		return bson.M{"$in": cond.Values}
+	case OpNe:
+		return bson.M{"$ne": cond.value()}
+	case OpContains:
+		text, _ := cond.value().(string)
+		return bson.M{"$regex": regexp.QuoteMeta(text), "$options": "i"}
+	case OpPrefix:
+		// an anchored regex without options can use an index on the field
+		text, _ := cond.value().(string)
+		return bson.M{"$regex": "^" + regexp.QuoteMeta(text)}
+	case OpGte:
+		return bson.M{"$gte": cond.value()}
+	case OpLte:
+		return bson.M{"$lte": cond.value()}
+	}
+	return bson.M{"$in": bson.A{}}
+}
+
+// mongoSeek selects the documents ordered after values by sort: those
+// equal on the first i fields and past the value on field i, for any i.
+func mongoSeek(sort []Sort, values []interface{}) bson.M {
+	var or bson.A
+	for i, field := range sort {
+		clause := bson.M{}
+		for j := 0; j < i; j++ {
+			clause[sort[j].Field] = values[j]
+		}
+		op := "$gt"
+		if field.Desc {
+			op = "$lt"
+		}
+		clause[field.Field] = bson.M{op: values[i]}
+		or = append(or, clause)
+	}
+	return bson.M{"$or": or}
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"golang_cms/model"
+)
+
+type passwordResetRepository struct {
+	store store[model.PasswordReset]
+}
+
+func newPasswordResetRepository(s store[model.PasswordReset]) *passwordResetRepository {
+	return &passwordResetRepository{store: s}
+}
+
+func (r *passwordResetRepository) Create(ctx context.Context, reset model.PasswordReset) (model.PasswordReset, error) {
+	if err := r.store.insert(ctx, reset); err != nil {
+		return model.PasswordReset{}, err
+	}
+	return reset, nil
+}
+
+func (r *passwordResetRepository) FindByHash(ctx context.Context, hash string) (model.PasswordReset, error) {
+	return r.store.findOne(ctx, Filter{"token_hash": hash})
+}
+
+// MarkUsed only replaces the reset while it is unused, so a token cannot be
+// used twice even by requests racing each other.
+func (r *passwordResetRepository) MarkUsed(ctx context.Context, reset model.PasswordReset, at time.Time) error {
+	filter := Filter{"_id": reset.Id, "used_at": nil}
+	reset.UsedAt = &at
+	return r.store.replace(ctx, filter, reset)
+}
+
+func (r *passwordResetRepository) DeleteByUser(ctx context.Context, userId string) (int, error) {
+	return r.deleteAll(ctx, Filter{"user_id": userId})
+}
+
+func (r *passwordResetRepository) DeleteExpired(ctx context.Context, before time.Time) (int, error) {
+	return r.deleteAll(ctx, Filter{"expires_at": Lte(before)})
+}
+
+func (r *passwordResetRepository) deleteAll(ctx context.Context, filter Filter) (int, error) {
+	resets, err := r.store.find(ctx, Query{Filter: filter})
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, reset := range resets {
+		err := r.store.delete(ctx, Filter{"_id": reset.Id})
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
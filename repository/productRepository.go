@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+
+	"golang_cms/model"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type productRepository struct {
+	*crudRepository[model.Product]
+}
+
+func newProductRepository(s store[model.Product]) *productRepository {
+	return &productRepository{newCrudRepository[model.Product](s, "_id", productID)}
+}
+
+// ListByCategories is List restricted to the products filed under any of
+// categoryIds.
+func (r *productRepository) ListByCategories(ctx context.Context, categoryIds []primitive.ObjectID, q Query) ([]model.Product, int64, error) {
+	filter := Filter{}
+	for field, value := range q.Filter {
+		filter[field] = value
+	}
+	filter["category_ids"] = In(objectIDs(categoryIds)...)
+	q.Filter = filter
+	return r.List(ctx, q)
+}
+
+func (r *productRepository) CountByCategory(ctx context.Context, categoryId primitive.ObjectID) (int64, error) {
+	return r.store.count(ctx, Filter{"category_ids": In(categoryId)})
+}
+
+func objectIDs(ids []primitive.ObjectID) []interface{} {
+	values := make([]interface{}, len(ids))
+	for i, id := range ids {
+		values[i] = id
+	}
+	return values
+}
+
+func productID(p model.Product) primitive.ObjectID { return p.Id }
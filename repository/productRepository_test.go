@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"golang_cms/model"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestProductsByCategory(t *testing.T) {
+	eachBackend(t, func(t *testing.T, repos Repositories) {
+		ctx := context.Background()
+		shoes, boots, hats := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+		for _, p := range []struct {
+			sku        string
+			categories []primitive.ObjectID
+		}{
+			{"sneaker", []primitive.ObjectID{shoes}},
+			{"chelsea", []primitive.ObjectID{shoes, boots}},
+			{"beanie", []primitive.ObjectID{hats}},
+		} {
+			product := model.Product{Id: primitive.NewObjectID(), Sku: p.sku, Name: p.sku, Currency: "EUR", CategoryIds: p.categories}
+			if _, err := repos.Product.Create(ctx, product); err != nil {
+				t.Fatalf("create %s: %v", p.sku, err)
+			}
+		}
+
+		tests := []struct {
+			categories []primitive.ObjectID
+			want       string
+		}{
+			{[]primitive.ObjectID{shoes}, "chelsea sneaker"},
+			{[]primitive.ObjectID{boots}, "chelsea"},
+			{[]primitive.ObjectID{boots, hats}, "beanie chelsea"},
+			{[]primitive.ObjectID{primitive.NewObjectID()}, ""},
+			{nil, ""},
+		}
+		for _, tt := range tests {
+			products, total, err := repos.Product.ListByCategories(ctx, tt.categories, Query{})
+			if err != nil || total != int64(len(products)) {
+				t.Fatalf("list by %v: %d, %v", tt.categories, total, err)
+			}
+			var skus []string
+			for _, product := range products {
+				skus = append(skus, product.Sku)
+			}
+			sort.Strings(skus)
+			if got := strings.Join(skus, " "); got != tt.want {
+				t.Errorf("products in %v = %q, want %q", tt.categories, got, tt.want)
+			}
+		}
+
+		for category, want := range map[primitive.ObjectID]int64{shoes: 2, boots: 1, hats: 1} {
+			if got, err := repos.Product.CountByCategory(ctx, category); err != nil || got != want {
+				t.Errorf("count by category = %d, %v; want %d", got, err, want)
+			}
+		}
+	})
+}
+
+func TestProductSkuIsUnique(t *testing.T) {
+	eachBackend(t, func(t *testing.T, repos Repositories) {
+		ctx := context.Background()
+		product := model.Product{Id: primitive.NewObjectID(), Sku: "sku-1", Currency: "EUR"}
+		if _, err := repos.Product.Create(ctx, product); err != nil {
+			t.Fatal(err)
+		}
+		product.Id = primitive.NewObjectID()
+		if _, err := repos.Product.Create(ctx, product); !errors.Is(err, ErrDuplicate) {
+			t.Errorf("second product with the same sku: %v, want ErrDuplicate", err)
+		}
+	})
+}
@@ -0,0 +1,126 @@
+package repository
+
+import "go.mongodb.org/mongo-driver/bson"
+
+// Filter selects documents by field, keyed by the bson field name. A plain
+// value matches by equality; a Cond, or a []Cond that must all hold,
+// applies operators instead.
+type Filter map[string]interface{}
+
+// Op names the operator of a Cond.
+type Op string
+
+const (
+	// OpEq matches a field equal to the value, like a plain filter value.
+	OpEq Op = "eq"
+	// OpIn matches a field equal to any of the values, or an array field
+	// holding any of them.
+	OpIn Op = "in"
+	// OpNe matches a field that differs from the value.
+	OpNe Op = "ne"
+	// OpContains matches a string field containing the value, ignoring case.
+	OpContains Op = "contains"
+	// OpPrefix matches a string field starting with the value.
+	OpPrefix Op = "prefix"
+	// OpGte and OpLte match a field ordered at or after, or at or before,
+	// the value.
+	OpGte Op = "gte"
+	OpLte Op = "lte"
+)
+
+// Cond is a filter value compared with an operator other than equality.
+// Every operator but OpIn takes exactly one value.
+type Cond struct {
+	Op     Op
+	Values []interface{}
+}
+
+// In builds an OpIn condition.
+func In(values ...interface{}) Cond {
+	if values == nil {
+		values = []interface{}{}
+	}
+	return Cond{Op: OpIn, Values: values}
+}
+
+// Eq, Ne, Contains, Prefix, Gte and Lte build single-value conditions.
+func Eq(value interface{}) Cond  { return Cond{Op: OpEq, Values: []interface{}{value}} }
+func Ne(value interface{}) Cond  { return Cond{Op: OpNe, Values: []interface{}{value}} }
+func Contains(value string) Cond { return Cond{Op: OpContains, Values: []interface{}{value}} }
+func Prefix(value string) Cond   { return Cond{Op: OpPrefix, Values: []interface{}{value}} }
+func Gte(value interface{}) Cond { return Cond{Op: OpGte, Values: []interface{}{value}} }
+func Lte(value interface{}) Cond { return Cond{Op: OpLte, Values: []interface{}{value}} }
+
+func (c Cond) value() interface{} { return c.Values[0] }
+
+// condsOf returns the conditions held by a filter value, if it has any.
+func condsOf(value interface{}) ([]Cond, bool) {
+	switch v := value.(type) {
+	case Cond:
+		return []Cond{v}, true
+	case []Cond:
+		return v, true
+	}
+	return nil, false
+}
+
+// Sort orders results by a bson field.
+type Sort struct {
+	Field string
+	Desc  bool
+}
+
+// Query selects a page of documents. Results are always ordered by _id
+// after the requested sort fields so pages do not overlap. A zero Limit
+// returns every document after Skip.
+//
+// After and Before page by key instead of by offset: they hold the sort
+// values of a document, one per Sort field followed by its _id (see
+// SortValues), and select the documents ordered after or before it. At
+// most one of them may be set.
+type Query struct {
+	Filter Filter
+	Sort   []Sort
+	Skip   int64
+	Limit  int64
+	After  []interface{}
+	Before []interface{}
+}
+
+// withIdOrder returns sort with _id appended when it is not already there.
+func withIdOrder(sort []Sort) []Sort {
+	for _, s := range sort {
+		if s.Field == "_id" {
+			return sort
+		}
+	}
+	return append(append([]Sort{}, sort...), Sort{Field: "_id"})
+}
+
+// reversed returns sort with every direction flipped.
+func reversed(sort []Sort) []Sort {
+	flipped := make([]Sort, len(sort))
+	for i, field := range sort {
+		flipped[i] = Sort{Field: field.Field, Desc: !field.Desc}
+	}
+	return flipped
+}
+
+// SortValues returns the values of doc that After and Before expect for
+// the given sort, read by bson field name.
+func SortValues[T any](doc T, sort []Sort) ([]interface{}, error) {
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var fields bson.M
+	if err := bson.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+
+	var values []interface{}
+	for _, field := range withIdOrder(sort) {
+		values = append(values, fields[field.Field])
+	}
+	return values, nil
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"golang_cms/model"
+)
+
+type refreshTokenRepository struct {
+	store store[model.RefreshToken]
+}
+
+func newRefreshTokenRepository(s store[model.RefreshToken]) *refreshTokenRepository {
+	return &refreshTokenRepository{store: s}
+}
+
+func (r *refreshTokenRepository) Create(ctx context.Context, token model.RefreshToken) (model.RefreshToken, error) {
+	if err := r.store.insert(ctx, token); err != nil {
+		return model.RefreshToken{}, err
+	}
+	return token, nil
+}
+
+func (r *refreshTokenRepository) FindByHash(ctx context.Context, hash string) (model.RefreshToken, error) {
+	return r.store.findOne(ctx, Filter{"token_hash": hash})
+}
+
+// MarkUsed only replaces the token while it is still unused and not
+// revoked, so of two requests racing with the same token only one wins.
+func (r *refreshTokenRepository) MarkUsed(ctx context.Context, token model.RefreshToken, at time.Time) error {
+	filter := Filter{"_id": token.Id, "used_at": nil, "revoked_at": nil}
+	token.UsedAt = &at
+	return r.store.replace(ctx, filter, token)
+}
+
+func (r *refreshTokenRepository) SessionRevoked(ctx context.Context, sessionId string) (bool, error) {
+	count, err := r.store.count(ctx, Filter{"session_id": sessionId, "revoked_at": Ne(nil)})
+	return count > 0, err
+}
+
+func (r *refreshTokenRepository) RevokeSession(ctx context.Context, sessionId string, at time.Time) (int, error) {
+	return r.revoke(ctx, Filter{"session_id": sessionId, "revoked_at": nil}, at)
+}
+
+func (r *refreshTokenRepository) RevokeUser(ctx context.Context, userId string, keepSession string, at time.Time) (int, error) {
+	filter := Filter{"user_id": userId, "revoked_at": nil}
+	if keepSession != "" {
+		filter["session_id"] = Ne(keepSession)
+	}
+	return r.revoke(ctx, filter, at)
+}
+
+func (r *refreshTokenRepository) revoke(ctx context.Context, filter Filter, at time.Time) (int, error) {
+	tokens, err := r.store.find(ctx, Query{Filter: filter})
+	if err != nil {
+		return 0, err
+	}
+
+	revoked := 0
+	for _, token := range tokens {
+		token.RevokedAt = &at
+		err := r.store.replace(ctx, Filter{"_id": token.Id, "revoked_at": nil}, token)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return revoked, err
+		}
+		revoked++
+	}
+	return revoked, nil
+}
+
+func (r *refreshTokenRepository) DeleteExpired(ctx context.Context, before time.Time) (int, error) {
+	tokens, err := r.store.find(ctx, Query{Filter: Filter{"expires_at": Lte(before)}})
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, token := range tokens {
+		err := r.store.delete(ctx, Filter{"_id": token.Id})
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
@@ -0,0 +1,313 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"golang_cms/model"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"gorm.io/gorm"
+)
+
+// ErrNotFound is returned when no document matches the requested filter.
+var ErrNotFound = errors.New("data not found")
+
+// ErrDuplicate is returned when a write violates a unique index.
+var ErrDuplicate = errors.New("data already exists")
+
+// CrudRepository is the set of operations shared by every content type.
+type CrudRepository[T any] interface {
+	Create(ctx context.Context, doc T) (T, error)
+	FindByID(ctx context.Context, id primitive.ObjectID) (T, error)
+	FindAll(ctx context.Context) ([]T, error)
+	List(ctx context.Context, q Query) ([]T, int64, error)
+	Update(ctx context.Context, doc T) (T, error)
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+type BannerRepository interface {
+	CrudRepository[model.Banner]
+}
+
+type MetaRepository interface {
+	CrudRepository[model.Meta]
+}
+
+type DescRepository interface {
+	CrudRepository[model.Desc]
+}
+
+type CategoryRepository interface {
+	CrudRepository[model.MainCategory]
+}
+
+type ChildCategoryRepository interface {
+	CrudRepository[model.ChildCategory]
+	FindByParent(ctx context.Context, parentId primitive.ObjectID) ([]model.ChildCategory, error)
+	CountByParent(ctx context.Context, parentId primitive.ObjectID) (int64, error)
+}
+
+// CategoryTreeRepository stores categories nested to any depth. Create,
+// Move and Reorder maintain the path, depth and sibling order; Update only
+// stores the fields it is given and must not be used to re-parent.
+type CategoryTreeRepository interface {
+	CrudRepository[model.Category]
+	FindChildren(ctx context.Context, parentId *primitive.ObjectID) ([]model.Category, error)
+	CountChildren(ctx context.Context, id primitive.ObjectID) (int64, error)
+	FindDescendants(ctx context.Context, root model.Category) ([]model.Category, error)
+	FindAncestors(ctx context.Context, category model.Category) ([]model.Category, error)
+	Move(ctx context.Context, id primitive.ObjectID, parentId *primitive.ObjectID, position int) (model.Category, error)
+	Reorder(ctx context.Context, parentId *primitive.ObjectID, ids []primitive.ObjectID) ([]model.Category, error)
+}
+
+type ProductRepository interface {
+	CrudRepository[model.Product]
+	ListByCategories(ctx context.Context, categoryIds []primitive.ObjectID, q Query) ([]model.Product, int64, error)
+	CountByCategory(ctx context.Context, categoryId primitive.ObjectID) (int64, error)
+}
+
+// MediaRepository stores the metadata of uploaded files; the files
+// themselves live in a media.Storage.
+type MediaRepository interface {
+	CrudRepository[model.Media]
+	FindByChecksum(ctx context.Context, checksum string) (model.Media, error)
+}
+
+// RevisionRepository keeps the history of content documents. Revisions are
+// numbered per document from 1 and can only be added, never changed.
+type RevisionRepository interface {
+	Create(ctx context.Context, revision model.Revision) (model.Revision, error)
+	List(ctx context.Context, q Query) ([]model.Revision, int64, error)
+	FindByNumber(ctx context.Context, contentType string, documentId primitive.ObjectID, number int) (model.Revision, error)
+	Latest(ctx context.Context, contentType string, documentId primitive.ObjectID) (model.Revision, error)
+	NextNumber(ctx context.Context, contentType string, documentId primitive.ObjectID) (int, error)
+}
+
+// RoleRepository stores the custom roles; the built-in roles are defined in
+// code.
+type RoleRepository interface {
+	CrudRepository[model.Role]
+	FindByName(ctx context.Context, name string) (model.Role, error)
+}
+
+// AuditRepository stores the audit log. Entries can only be added and
+// listed, never changed or removed.
+type AuditRepository interface {
+	Create(ctx context.Context, entry model.AuditEntry) (model.AuditEntry, error)
+	List(ctx context.Context, q Query) ([]model.AuditEntry, int64, error)
+}
+
+// RefreshTokenRepository stores the refresh tokens of every session, by
+// hash. Tokens are marked used rather than deleted, so a replayed token can
+// still be recognised until it expires.
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, token model.RefreshToken) (model.RefreshToken, error)
+	FindByHash(ctx context.Context, hash string) (model.RefreshToken, error)
+	MarkUsed(ctx context.Context, token model.RefreshToken, at time.Time) error
+	SessionRevoked(ctx context.Context, sessionId string) (bool, error)
+	RevokeSession(ctx context.Context, sessionId string, at time.Time) (int, error)
+	RevokeUser(ctx context.Context, userId string, keepSession string, at time.Time) (int, error)
+	DeleteExpired(ctx context.Context, before time.Time) (int, error)
+}
+
+// RevokedTokenRepository stores the IDs (jti) of access tokens revoked
+// before they expired. Entries are only needed until then.
+type RevokedTokenRepository interface {
+	Create(ctx context.Context, token model.RevokedToken) error
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+	DeleteExpired(ctx context.Context, before time.Time) (int, error)
+}
+
+// ApiTokenRepository stores the personal API tokens of users, by hash.
+// MarkUsed only records when a token was last used.
+type ApiTokenRepository interface {
+	CrudRepository[model.ApiToken]
+	FindByHash(ctx context.Context, hash string) (model.ApiToken, error)
+	MarkUsed(ctx context.Context, token model.ApiToken, at time.Time) error
+}
+
+// PasswordResetRepository stores the password reset tokens sent to users,
+// by hash.
+type PasswordResetRepository interface {
+	Create(ctx context.Context, reset model.PasswordReset) (model.PasswordReset, error)
+	FindByHash(ctx context.Context, hash string) (model.PasswordReset, error)
+	MarkUsed(ctx context.Context, reset model.PasswordReset, at time.Time) error
+	DeleteByUser(ctx context.Context, userId string) (int, error)
+	DeleteExpired(ctx context.Context, before time.Time) (int, error)
+}
+
+type UserRepository interface {
+	Create(ctx context.Context, user model.User) (model.User, error)
+	FindByUserID(ctx context.Context, userId string) (model.User, error)
+	FindByEmail(ctx context.Context, email string) (model.User, error)
+	CountByEmail(ctx context.Context, email string) (int64, error)
+	CountByPhone(ctx context.Context, phone string) (int64, error)
+	CountByRole(ctx context.Context, role string) (int64, error)
+	List(ctx context.Context, q Query) ([]model.User, int64, error)
+	Update(ctx context.Context, user model.User) (model.User, error)
+}
+
+// Repositories groups every repository the controllers depend on.
+type Repositories struct {
+	Banner   BannerRepository
+	Meta     MetaRepository
+	Desc     DescRepository
+	Category CategoryRepository
+	Child    ChildCategoryRepository
+	Tree     CategoryTreeRepository
+	Product  ProductRepository
+	Media    MediaRepository
+	Revision RevisionRepository
+	Audit    AuditRepository
+	Role     RoleRepository
+	User     UserRepository
+	Refresh  RefreshTokenRepository
+	Revoked  RevokedTokenRepository
+	ApiToken ApiTokenRepository
+	Reset    PasswordResetRepository
+}
+
+// NewMongoRepositories builds repositories backed by the collections of db.
+func NewMongoRepositories(db *mongo.Database) Repositories {
+	return Repositories{
+		Banner:   newCrudRepository[model.Banner](newMongoStore[model.Banner](db.Collection("Banner")), "_id", bannerID),
+		Meta:     newCrudRepository[model.Meta](newMongoStore[model.Meta](db.Collection("Meta")), "_id", metaID),
+		Desc:     newCrudRepository[model.Desc](newMongoStore[model.Desc](db.Collection("Description")), "_id", descID),
+		Category: newCrudRepository[model.MainCategory](newMongoStore[model.MainCategory](db.Collection("Main Category")), "_id", categoryID),
+		Child:    newChildCategoryRepository(newMongoStore[model.ChildCategory](db.Collection("Child Category"))),
+		Tree:     newCategoryTreeRepository(newMongoStore[model.Category](db.Collection("Category"))),
+		Product:  newProductRepository(newMongoStore[model.Product](db.Collection("Product"))),
+		Media:    newMediaRepository(newMongoStore[model.Media](db.Collection("Media"))),
+		Revision: newRevisionRepository(newMongoStore[model.Revision](db.Collection("Revision"))),
+		Audit:    newAuditRepository(newMongoStore[model.AuditEntry](db.Collection("Audit"))),
+		Role:     newRoleRepository(newMongoStore[model.Role](db.Collection("Role"))),
+		User:     newUserRepository(newMongoStore[model.User](db.Collection("User"))),
+		Refresh:  newRefreshTokenRepository(newMongoStore[model.RefreshToken](db.Collection("RefreshToken"))),
+		Revoked:  newRevokedTokenRepository(newMongoStore[model.RevokedToken](db.Collection("RevokedToken"))),
+		ApiToken: newApiTokenRepository(newMongoStore[model.ApiToken](db.Collection("ApiToken"))),
+		Reset:    newPasswordResetRepository(newMongoStore[model.PasswordReset](db.Collection("PasswordReset"))),
+	}
+}
+
+// NewMemoryRepositories builds repositories that keep everything in process
+// memory, for tests and local runs without a database.
+func NewMemoryRepositories() Repositories {
+	return Repositories{
+		Banner:   newCrudRepository[model.Banner](newMemoryStore[model.Banner]("_id"), "_id", bannerID),
+		Meta:     newCrudRepository[model.Meta](newMemoryStore[model.Meta]("_id"), "_id", metaID),
+		Desc:     newCrudRepository[model.Desc](newMemoryStore[model.Desc]("_id"), "_id", descID),
+		Category: newCrudRepository[model.MainCategory](newMemoryStore[model.MainCategory]("_id"), "_id", categoryID),
+		Child:    newChildCategoryRepository(newMemoryStore[model.ChildCategory]("_id")),
+		Tree:     newCategoryTreeRepository(newMemoryStore[model.Category]("_id")),
+		Product:  newProductRepository(newMemoryStore[model.Product]("_id", "sku")),
+		Media:    newMediaRepository(newMemoryStore[model.Media]("_id")),
+		Revision: newRevisionRepository(newMemoryStore[model.Revision]("_id")),
+		Audit:    newAuditRepository(newMemoryStore[model.AuditEntry]("_id")),
+		Role:     newRoleRepository(newMemoryStore[model.Role]("_id", "name")),
+		User:     newUserRepository(newMemoryStore[model.User]("_id", "email", "phone", "user_id")),
+		Refresh:  newRefreshTokenRepository(newMemoryStore[model.RefreshToken]("_id", "token_hash")),
+		Revoked:  newRevokedTokenRepository(newMemoryStore[model.RevokedToken]("_id", "jti")),
+		ApiToken: newApiTokenRepository(newMemoryStore[model.ApiToken]("_id", "token_hash")),
+		Reset:    newPasswordResetRepository(newMemoryStore[model.PasswordReset]("_id", "token_hash")),
+	}
+}
+
+// NewSQLRepositories builds repositories backed by gorm tables, creating or
+// migrating the tables of every model first.
+func NewSQLRepositories(db *gorm.DB) (Repositories, error) {
+	if err := db.AutoMigrate(&model.Banner{}, &model.Meta{}, &model.Desc{}, &model.MainCategory{}, &model.ChildCategory{}, &model.Category{}, &model.Product{}, &model.Media{}, &model.Revision{}, &model.AuditEntry{}, &model.Role{}, &model.User{}, &model.RefreshToken{}, &model.RevokedToken{}, &model.ApiToken{}, &model.PasswordReset{}); err != nil {
+		return Repositories{}, err
+	}
+
+	banners, err := newSQLStore[model.Banner](db)
+	if err != nil {
+		return Repositories{}, err
+	}
+	metas, err := newSQLStore[model.Meta](db)
+	if err != nil {
+		return Repositories{}, err
+	}
+	descs, err := newSQLStore[model.Desc](db)
+	if err != nil {
+		return Repositories{}, err
+	}
+	categories, err := newSQLStore[model.MainCategory](db)
+	if err != nil {
+		return Repositories{}, err
+	}
+	children, err := newSQLStore[model.ChildCategory](db)
+	if err != nil {
+		return Repositories{}, err
+	}
+	tree, err := newSQLStore[model.Category](db)
+	if err != nil {
+		return Repositories{}, err
+	}
+	products, err := newSQLStore[model.Product](db)
+	if err != nil {
+		return Repositories{}, err
+	}
+	media, err := newSQLStore[model.Media](db)
+	if err != nil {
+		return Repositories{}, err
+	}
+	revisions, err := newSQLStore[model.Revision](db)
+	if err != nil {
+		return Repositories{}, err
+	}
+	audit, err := newSQLStore[model.AuditEntry](db)
+	if err != nil {
+		return Repositories{}, err
+	}
+	roles, err := newSQLStore[model.Role](db)
+	if err != nil {
+		return Repositories{}, err
+	}
+	users, err := newSQLStore[model.User](db)
+	if err != nil {
+		return Repositories{}, err
+	}
+	refresh, err := newSQLStore[model.RefreshToken](db)
+	if err != nil {
+		return Repositories{}, err
+	}
+	revoked, err := newSQLStore[model.RevokedToken](db)
+	if err != nil {
+		return Repositories{}, err
+	}
+	apiTokens, err := newSQLStore[model.ApiToken](db)
+	if err != nil {
+		return Repositories{}, err
+	}
+	resets, err := newSQLStore[model.PasswordReset](db)
+	if err != nil {
+		return Repositories{}, err
+	}
+
+	return Repositories{
+		Banner:   newCrudRepository[model.Banner](banners, "_id", bannerID),
+		Meta:     newCrudRepository[model.Meta](metas, "_id", metaID),
+		Desc:     newCrudRepository[model.Desc](descs, "_id", descID),
+		Category: newCrudRepository[model.MainCategory](categories, "_id", categoryID),
+		Child:    newChildCategoryRepository(children),
+		Tree:     newCategoryTreeRepository(tree),
+		Product:  newProductRepository(products),
+		Media:    newMediaRepository(media),
+		Revision: newRevisionRepository(revisions),
+		Audit:    newAuditRepository(audit),
+		Role:     newRoleRepository(roles),
+		User:     newUserRepository(users),
+		Refresh:  newRefreshTokenRepository(refresh),
+		Revoked:  newRevokedTokenRepository(revoked),
+		ApiToken: newApiTokenRepository(apiTokens),
+		Reset:    newPasswordResetRepository(resets),
+	}, nil
+}
+
+func bannerID(b model.Banner) primitive.ObjectID         { return b.Id }
+func metaID(m model.Meta) primitive.ObjectID             { return m.Id }
+func descID(d model.Desc) primitive.ObjectID             { return d.Id }
+func categoryID(k model.MainCategory) primitive.ObjectID { return k.Id }
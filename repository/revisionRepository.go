@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"golang_cms/model"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// revisionRepository only ever inserts: revisions are immutable.
+type revisionRepository struct {
+	store store[model.Revision]
+}
+
+func newRevisionRepository(s store[model.Revision]) *revisionRepository {
+	return &revisionRepository{store: s}
+}
+
+func (r *revisionRepository) Create(ctx context.Context, revision model.Revision) (model.Revision, error) {
+	if err := r.store.insert(ctx, revision); err != nil {
+		return model.Revision{}, err
+	}
+	return revision, nil
+}
+
+func (r *revisionRepository) List(ctx context.Context, q Query) ([]model.Revision, int64, error) {
+	return list(ctx, r.store, q)
+}
+
+func (r *revisionRepository) FindByNumber(ctx context.Context, contentType string, documentId primitive.ObjectID, number int) (model.Revision, error) {
+	return r.store.findOne(ctx, Filter{"content_type": contentType, "document_id": documentId, "number": number})
+}
+
+// Latest returns the newest revision of a document.
+func (r *revisionRepository) Latest(ctx context.Context, contentType string, documentId primitive.ObjectID) (model.Revision, error) {
+	revisions, err := r.store.find(ctx, Query{
+		Filter: Filter{"content_type": contentType, "document_id": documentId},
+		Sort:   []Sort{{Field: "number", Desc: true}},
+		Limit:  1,
+	})
+	if err != nil {
+		return model.Revision{}, err
+	}
+	if len(revisions) == 0 {
+		return model.Revision{}, ErrNotFound
+	}
+	return revisions[0], nil
+}
+
+// NextNumber returns the number the next revision of a document gets,
+// starting at 1.
+func (r *revisionRepository) NextNumber(ctx context.Context, contentType string, documentId primitive.ObjectID) (int, error) {
+	latest, err := r.Latest(ctx, contentType, documentId)
+	if errors.Is(err, ErrNotFound) {
+		return 1, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return latest.Number + 1, nil
+}
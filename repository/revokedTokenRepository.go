@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"golang_cms/model"
+)
+
+type revokedTokenRepository struct {
+	store store[model.RevokedToken]
+}
+
+func newRevokedTokenRepository(s store[model.RevokedToken]) *revokedTokenRepository {
+	return &revokedTokenRepository{store: s}
+}
+
+// Create records a revocation; revoking a token twice is not an error.
+func (r *revokedTokenRepository) Create(ctx context.Context, token model.RevokedToken) error {
+	err := r.store.insert(ctx, token)
+	if errors.Is(err, ErrDuplicate) {
+		return nil
+	}
+	return err
+}
+
+func (r *revokedTokenRepository) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	count, err := r.store.count(ctx, Filter{"jti": jti})
+	return count > 0, err
+}
+
+func (r *revokedTokenRepository) DeleteExpired(ctx context.Context, before time.Time) (int, error) {
+	tokens, err := r.store.find(ctx, Query{Filter: Filter{"expires_at": Lte(before)}})
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, token := range tokens {
+		err := r.store.delete(ctx, Filter{"_id": token.Id})
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
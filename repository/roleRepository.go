@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+
+	"golang_cms/model"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type roleRepository struct {
+	*crudRepository[model.Role]
+}
+
+func newRoleRepository(s store[model.Role]) *roleRepository {
+	return &roleRepository{newCrudRepository[model.Role](s, "_id", roleID)}
+}
+
+func (r *roleRepository) FindByName(ctx context.Context, name string) (model.Role, error) {
+	return r.store.findOne(ctx, Filter{"name": name})
+}
+
+func roleID(role model.Role) primitive.ObjectID { return role.Id }
@@ -0,0 +1,334 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgconn"
+	"github.com/mattn/go-sqlite3"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+func init() {
+	schema.RegisterSerializer("objectid", objectIDSerializer{})
+}
+
+// sqlStore keeps documents in a relational table managed by gorm. Filters
+// use the bson field names so repositories do not care which backend they
+// run on; columns are resolved from the parsed gorm schema.
+type sqlStore[T any] struct {
+	db      *gorm.DB
+	columns map[string]string
+	arrays  map[string]bool
+	primary string
+}
+
+func newSQLStore[T any](db *gorm.DB) (*sqlStore[T], error) {
+	var doc T
+	parsed, err := schema.Parse(&doc, &sync.Map{}, db.NamingStrategy)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &sqlStore[T]{db: db, columns: map[string]string{}, arrays: map[string]bool{}}
+	for _, field := range parsed.Fields {
+		if field.DBName == "" {
+			continue
+		}
+		s.columns[bsonName(field.StructField)] = field.DBName
+		if field.FieldType.Kind() == reflect.Slice && field.Serializer != nil {
+			s.arrays[field.DBName] = true
+		}
+	}
+	if parsed.PrioritizedPrimaryField != nil {
+		s.primary = parsed.PrioritizedPrimaryField.DBName
+	}
+	return s, nil
+}
+
+func (s *sqlStore[T]) insert(ctx context.Context, doc T) error {
+	return translateSQLError(s.db.WithContext(ctx).Create(&doc).Error)
+}
+
+func (s *sqlStore[T]) findOne(ctx context.Context, filter Filter) (T, error) {
+	var doc T
+	tx, err := s.where(ctx, filter)
+	if err != nil {
+		return doc, err
+	}
+
+	err = tx.Take(&doc).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return doc, ErrNotFound
+	}
+	return doc, err
+}
+
+func (s *sqlStore[T]) find(ctx context.Context, q Query) ([]T, error) {
+	tx, err := s.where(ctx, q.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := withIdOrder(q.Sort)
+	columns := make([]clause.Column, len(fields))
+	for i, field := range fields {
+		column, ok := s.columns[field.Field]
+		if !ok {
+			return nil, fmt.Errorf("unknown field %q", field.Field)
+		}
+		columns[i] = clause.Column{Name: column}
+		tx = tx.Order(clause.OrderByColumn{Column: columns[i], Desc: field.Desc})
+	}
+	if q.After != nil {
+		tx = tx.Where(sqlSeek(fields, columns, q.After))
+	}
+	if q.Skip > 0 {
+		tx = tx.Offset(int(q.Skip))
+	}
+	if q.Limit > 0 {
+		tx = tx.Limit(int(q.Limit))
+	}
+
+	var docs []T
+	err = tx.Find(&docs).Error
+	return docs, err
+}
+
+func (s *sqlStore[T]) count(ctx context.Context, filter Filter) (int64, error) {
+	tx, err := s.where(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	err = tx.Model(new(T)).Count(&total).Error
+	return total, err
+}
+
+func (s *sqlStore[T]) replace(ctx context.Context, filter Filter, doc T) error {
+	tx, err := s.where(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	result := tx.Model(new(T)).Select("*").Updates(&doc)
+	if result.Error != nil {
+		return translateSQLError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *sqlStore[T]) delete(ctx context.Context, filter Filter) error {
+	tx, err := s.where(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	result := tx.Delete(new(T))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *sqlStore[T]) where(ctx context.Context, filter Filter) (*gorm.DB, error) {
+	tx := s.db.WithContext(ctx)
+	for field, value := range filter {
+		column, ok := s.columns[field]
+		if !ok {
+			return nil, fmt.Errorf("unknown field %q", field)
+		}
+		if conds, ok := condsOf(value); ok {
+			for _, cond := range conds {
+				tx = tx.Where(s.condition(column, cond))
+			}
+			continue
+		}
+		tx = tx.Where(clause.Eq{Column: clause.Column{Name: column}, Value: sqlValue(value)})
+	}
+	return tx, nil
+}
+
+// condition builds the SQL expression of cond. Array fields are stored as
+// JSON text, so membership is tested by matching the encoded element.
+func (s *sqlStore[T]) condition(column string, cond Cond) clause.Expression {
+	col := clause.Column{Name: column}
+	switch cond.Op {
+	case OpEq:
+		if s.arrays[column] {
+			return s.condition(column, In(cond.value()))
+		}
+		return clause.Eq{Column: col, Value: sqlValue(cond.value())}
+	case OpIn:
+		if len(cond.Values) == 0 {
+			return clause.Expr{SQL: "1 = 0"}
+		}
+		if !s.arrays[column] {
+			values := make([]interface{}, len(cond.Values))
+			for i, value := range cond.Values {
+				values[i] = sqlValue(value)
+			}
+			return clause.IN{Column: col, Values: values}
+		}
+
+		var matches []clause.Expression
+		for _, value := range cond.Values {
+			encoded, _ := json.Marshal(sqlValue(value))
+			matches = append(matches, likeExpr(col, string(encoded), false))
+		}
+		// gorm joins a single-element Or to the previous condition with OR
+		if len(matches) == 1 {
+			return matches[0]
+		}
+		return clause.Or(matches...)
+	case OpNe:
+		return clause.Neq{Column: col, Value: sqlValue(cond.value())}
+	case OpContains:
+		text, _ := cond.value().(string)
+		return likeExpr(col, strings.ToLower(text), true)
+	case OpPrefix:
+		text, _ := cond.value().(string)
+		return clause.Expr{SQL: `? LIKE ? ESCAPE '\'`, Vars: []interface{}{col, likeEscaper.Replace(text) + "%"}}
+	case OpGte:
+		return clause.Gte{Column: col, Value: sqlValue(cond.value())}
+	case OpLte:
+		return clause.Lte{Column: col, Value: sqlValue(cond.value())}
+	}
+	return clause.Expr{SQL: "1 = 0"}
+}
+
+// sqlSeek selects the rows ordered after values by sort: those equal on
+// the first i columns and past the value on column i, for any i.
+func sqlSeek(sort []Sort, columns []clause.Column, values []interface{}) clause.Expression {
+	var or []clause.Expression
+	for i, field := range sort {
+		var and []clause.Expression
+		for j := 0; j < i; j++ {
+			and = append(and, clause.Eq{Column: columns[j], Value: sqlValue(values[j])})
+		}
+		if field.Desc {
+			and = append(and, clause.Lt{Column: columns[i], Value: sqlValue(values[i])})
+		} else {
+			and = append(and, clause.Gt{Column: columns[i], Value: sqlValue(values[i])})
+		}
+		or = append(or, clause.And(and...))
+	}
+	if len(or) == 1 {
+		return or[0]
+	}
+	return clause.Or(or...)
+}
+
+// likeExpr matches col containing text, escaping LIKE wildcards in text.
+func likeExpr(col clause.Column, text string, lower bool) clause.Expression {
+	pattern := "%" + likeEscaper.Replace(text) + "%"
+	if lower {
+		return clause.Expr{SQL: `LOWER(?) LIKE ? ESCAPE '\'`, Vars: []interface{}{col, pattern}}
+	}
+	return clause.Expr{SQL: `? LIKE ? ESCAPE '\'`, Vars: []interface{}{col, pattern}}
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// translateSQLError maps unique constraint violations onto ErrDuplicate.
+func translateSQLError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrDuplicate
+	}
+
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && (sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique || sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+// sqlValue converts filter values to the representation stored in the table.
+func sqlValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case primitive.ObjectID:
+		return v.Hex()
+	case *primitive.ObjectID:
+		if v == nil {
+			return nil
+		}
+		return v.Hex()
+	case primitive.DateTime:
+		return v.Time().UTC()
+	}
+	return value
+}
+
+// bsonName returns the key the mongo driver uses for a struct field.
+func bsonName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("bson"), ",")[0]
+	if name == "" {
+		name = strings.ToLower(field.Name)
+	}
+	return name
+}
+
+// objectIDSerializer stores primitive.ObjectID values, or pointers to them,
+// as hex strings. A nil pointer is stored as NULL.
+type objectIDSerializer struct{}
+
+func (objectIDSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var hex string
+	switch v := dbValue.(type) {
+	case nil:
+	case string:
+		hex = v
+	case []byte:
+		hex = string(v)
+	default:
+		return fmt.Errorf("failed to scan object id: %#v", dbValue)
+	}
+
+	var id primitive.ObjectID
+	if hex != "" {
+		var err error
+		if id, err = primitive.ObjectIDFromHex(hex); err != nil {
+			return err
+		}
+	}
+
+	value := reflect.ValueOf(id)
+	if field.FieldType.Kind() == reflect.Ptr {
+		if hex == "" {
+			value = reflect.Zero(field.FieldType)
+		} else {
+			value = reflect.ValueOf(&id)
+		}
+	}
+	field.ReflectValueOf(ctx, dst).Set(value)
+	return nil
+}
+
+func (objectIDSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	switch id := fieldValue.(type) {
+	case primitive.ObjectID:
+		return id.Hex(), nil
+	case *primitive.ObjectID:
+		if id == nil {
+			return nil, nil
+		}
+		return id.Hex(), nil
+	}
+	return nil, fmt.Errorf("invalid object id: %#v", fieldValue)
+}
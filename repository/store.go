@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// store is the storage primitive every repository is built on. Each backend
+// implements it once and the typed repositories stay backend agnostic.
+type store[T any] interface {
+	insert(ctx context.Context, doc T) error
+	findOne(ctx context.Context, filter Filter) (T, error)
+	find(ctx context.Context, q Query) ([]T, error)
+	count(ctx context.Context, filter Filter) (int64, error)
+	replace(ctx context.Context, filter Filter, doc T) error
+	delete(ctx context.Context, filter Filter) error
+}
+
+type crudRepository[T any] struct {
+	store   store[T]
+	idField string
+	idOf    func(T) primitive.ObjectID
+}
+
+func newCrudRepository[T any](s store[T], idField string, idOf func(T) primitive.ObjectID) *crudRepository[T] {
+	return &crudRepository[T]{store: s, idField: idField, idOf: idOf}
+}
+
+func (r *crudRepository[T]) Create(ctx context.Context, doc T) (T, error) {
+	if err := r.store.insert(ctx, doc); err != nil {
+		var zero T
+		return zero, err
+	}
+	return doc, nil
+}
+
+func (r *crudRepository[T]) FindByID(ctx context.Context, id primitive.ObjectID) (T, error) {
+	return r.store.findOne(ctx, Filter{r.idField: id})
+}
+
+func (r *crudRepository[T]) FindAll(ctx context.Context) ([]T, error) {
+	return r.store.find(ctx, Query{})
+}
+
+// List returns the page of documents selected by q and the number of
+// documents matching its filter across all pages.
+func (r *crudRepository[T]) List(ctx context.Context, q Query) ([]T, int64, error) {
+	return list(ctx, r.store, q)
+}
+
+func (r *crudRepository[T]) Update(ctx context.Context, doc T) (T, error) {
+	filter := Filter{r.idField: r.idOf(doc)}
+	if err := r.store.replace(ctx, filter, doc); err != nil {
+		var zero T
+		return zero, err
+	}
+	return r.store.findOne(ctx, filter)
+}
+
+func (r *crudRepository[T]) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return r.store.delete(ctx, Filter{r.idField: id})
+}
+
+// list serves List for every repository. Stores only page forward with
+// After, so Before runs as After over the reversed sort and the page is
+// turned back around.
+func list[T any](ctx context.Context, s store[T], q Query) ([]T, int64, error) {
+	total, err := s.count(ctx, q.Filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if q.Before == nil {
+		docs, err := s.find(ctx, q)
+		return docs, total, err
+	}
+
+	q.Sort, q.After, q.Before = reversed(withIdOrder(q.Sort)), q.Before, nil
+	docs, err := s.find(ctx, q)
+	for i, j := 0, len(docs)-1; i < j; i, j = i+1, j-1 {
+		docs[i], docs[j] = docs[j], docs[i]
+	}
+	return docs, total, err
+}
@@ -0,0 +1,334 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type testDoc struct {
+	Id   primitive.ObjectID `bson:"_id" gorm:"primaryKey;serializer:objectid;size:24"`
+	Name string             `bson:"name"`
+	Rank int                `bson:"rank"`
+	Tags []string           `bson:"tags" gorm:"serializer:json"`
+}
+
+// eachStore runs test against an empty store of every backend that works
+// without a server.
+func eachStore(t *testing.T, test func(t *testing.T, s store[testDoc])) {
+	backends := []struct {
+		name string
+		open func(t *testing.T) store[testDoc]
+	}{
+		{"memory", func(t *testing.T) store[testDoc] { return newMemoryStore[testDoc]("_id") }},
+		{"sqlite", openSQLiteStore},
+	}
+	for _, backend := range backends {
+		backend := backend
+		t.Run(backend.name, func(t *testing.T) {
+			test(t, backend.open(t))
+		})
+	}
+}
+
+// eachBackend runs test against empty repositories of every backend that
+// works without a server.
+func eachBackend(t *testing.T, test func(t *testing.T, repos Repositories)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, NewMemoryRepositories())
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		repos, err := NewSQLRepositories(openSQLite(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		test(t, repos)
+	})
+}
+
+func openSQLite(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func openSQLiteStore(t *testing.T) store[testDoc] {
+	t.Helper()
+	db := openSQLite(t)
+	if err := db.AutoMigrate(&testDoc{}); err != nil {
+		t.Fatal(err)
+	}
+	s, err := newSQLStore[testDoc](db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// insertDocs stores one document per name, ranked in the order given and
+// tagged with the letters of its name.
+func insertDocs(t *testing.T, s store[testDoc], names ...string) []testDoc {
+	t.Helper()
+	var docs []testDoc
+	for i, name := range names {
+		doc := testDoc{Id: primitive.NewObjectID(), Name: name, Rank: i + 1, Tags: strings.Split(name, "")}
+		if err := s.insert(context.Background(), doc); err != nil {
+			t.Fatalf("insert %s: %v", name, err)
+		}
+		docs = append(docs, doc)
+	}
+	return docs
+}
+
+func namesOf(docs []testDoc) []string {
+	names := []string{}
+	for _, doc := range docs {
+		names = append(names, doc.Name)
+	}
+	return names
+}
+
+func TestStoreFindOne(t *testing.T) {
+	eachStore(t, func(t *testing.T, s store[testDoc]) {
+		ctx := context.Background()
+		docs := insertDocs(t, s, "a", "b", "c")
+
+		got, err := s.findOne(ctx, Filter{"_id": docs[1].Id})
+		if err != nil || !reflect.DeepEqual(got, docs[1]) {
+			t.Errorf("findOne by id = %+v, %v; want %+v", got, err, docs[1])
+		}
+		got, err = s.findOne(ctx, Filter{"name": "c", "rank": int64(3)})
+		if err != nil || !reflect.DeepEqual(got, docs[2]) {
+			t.Errorf("findOne by name and rank = %+v, %v; want %+v", got, err, docs[2])
+		}
+		if _, err := s.findOne(ctx, Filter{"name": "d"}); !errors.Is(err, ErrNotFound) {
+			t.Errorf("findOne of a missing document: %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestStoreFind(t *testing.T) {
+	tests := []struct {
+		name string
+		q    Query
+		want []string
+	}{
+		{"everything", Query{}, []string{"a", "b", "c", "d"}},
+		{"limit", Query{Limit: 2}, []string{"a", "b"}},
+		{"skip", Query{Skip: 3}, []string{"d"}},
+		{"skip and limit", Query{Skip: 1, Limit: 2}, []string{"b", "c"}},
+		{"skip past the end", Query{Skip: 10}, []string{}},
+		{"filter", Query{Filter: Filter{"rank": 2}}, []string{"b"}},
+		{"no match", Query{Filter: Filter{"name": "x"}}, []string{}},
+		{"sort", Query{Sort: []Sort{{Field: "rank", Desc: true}}}, []string{"d", "c", "b", "a"}},
+		{"sort then page", Query{Sort: []Sort{{Field: "name", Desc: true}}, Skip: 1, Limit: 2}, []string{"c", "b"}},
+	}
+	eachStore(t, func(t *testing.T, s store[testDoc]) {
+		insertDocs(t, s, "a", "b", "c", "d")
+		for _, tt := range tests {
+			docs, err := s.find(context.Background(), tt.q)
+			if err != nil {
+				t.Errorf("%s: %v", tt.name, err)
+				continue
+			}
+			if got := namesOf(docs); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s = %v, want %v", tt.name, got, tt.want)
+			}
+		}
+	})
+}
+
+func TestStoreSortTiesFollowId(t *testing.T) {
+	eachStore(t, func(t *testing.T, s store[testDoc]) {
+		docs := insertDocs(t, s, "x", "y", "z")
+		for _, doc := range docs {
+			doc.Rank = 1
+			if err := s.replace(context.Background(), Filter{"_id": doc.Id}, doc); err != nil {
+				t.Fatal(err)
+			}
+		}
+		found, err := s.find(context.Background(), Query{Sort: []Sort{{Field: "rank"}}})
+		if got := namesOf(found); err != nil || !reflect.DeepEqual(got, []string{"x", "y", "z"}) {
+			t.Errorf("equal ranks = %v, %v; want them in _id order", got, err)
+		}
+	})
+}
+
+func TestStoreOperators(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{"eq", Filter{"name": Eq("ab")}, []string{"ab"}},
+		{"ne", Filter{"name": Ne("ab")}, []string{"a", "abc", "b", "b_c"}},
+		{"in", Filter{"rank": In(1, 3)}, []string{"a", "abc"}},
+		{"in nothing", Filter{"rank": In()}, []string{}},
+		{"contains ignores case", Filter{"name": Contains("BC")}, []string{"abc"}},
+		{"contains escapes wildcards", Filter{"name": Contains("_")}, []string{"b_c"}},
+		{"prefix", Filter{"name": Prefix("ab")}, []string{"ab", "abc"}},
+		{"prefix escapes wildcards", Filter{"name": Prefix("b_")}, []string{"b_c"}},
+		{"prefix anchors at the start", Filter{"name": Prefix("c")}, []string{}},
+		{"gte", Filter{"rank": Gte(4)}, []string{"b", "b_c"}},
+		{"lte", Filter{"rank": Lte(2)}, []string{"a", "ab"}},
+		{"range", Filter{"rank": []Cond{Gte(2), Lte(3)}}, []string{"ab", "abc"}},
+		{"eq on an array", Filter{"tags": Eq("c")}, []string{"abc", "b_c"}},
+		{"in on an array", Filter{"tags": In("a", "_")}, []string{"a", "ab", "abc", "b_c"}},
+		{"in on an array with one value", Filter{"tags": In("c"), "rank": Lte(3)}, []string{"abc"}},
+		{"in on an array and a plain field", Filter{"tags": In("b"), "name": "ab"}, []string{"ab"}},
+	}
+	eachStore(t, func(t *testing.T, s store[testDoc]) {
+		insertDocs(t, s, "a", "ab", "abc", "b", "b_c")
+		for _, tt := range tests {
+			docs, err := s.find(context.Background(), Query{Filter: tt.filter})
+			if err != nil {
+				t.Errorf("%s: %v", tt.name, err)
+				continue
+			}
+			if got := namesOf(docs); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s = %v, want %v", tt.name, got, tt.want)
+			}
+			count, err := s.count(context.Background(), tt.filter)
+			if err != nil || count != int64(len(tt.want)) {
+				t.Errorf("%s: count = %d, %v; want %d", tt.name, count, err, len(tt.want))
+			}
+		}
+	})
+}
+
+func TestStoreCount(t *testing.T) {
+	eachStore(t, func(t *testing.T, s store[testDoc]) {
+		ctx := context.Background()
+		insertDocs(t, s, "a", "b", "a")
+
+		for filter, want := range map[string]int64{"a": 2, "b": 1, "c": 0} {
+			if got, err := s.count(ctx, Filter{"name": filter}); err != nil || got != want {
+				t.Errorf("count %s = %d, %v; want %d", filter, got, err, want)
+			}
+		}
+		if got, err := s.count(ctx, Filter{}); err != nil || got != 3 {
+			t.Errorf("count all = %d, %v; want 3", got, err)
+		}
+	})
+}
+
+func TestStoreReplace(t *testing.T) {
+	eachStore(t, func(t *testing.T, s store[testDoc]) {
+		ctx := context.Background()
+		docs := insertDocs(t, s, "a", "b")
+
+		changed := docs[0]
+		changed.Name, changed.Rank = "z", 9
+		if err := s.replace(ctx, Filter{"_id": changed.Id}, changed); err != nil {
+			t.Fatalf("replace: %v", err)
+		}
+		if got, err := s.findOne(ctx, Filter{"_id": changed.Id}); err != nil || !reflect.DeepEqual(got, changed) {
+			t.Errorf("after replace = %+v, %v; want %+v", got, err, changed)
+		}
+		if got, err := s.findOne(ctx, Filter{"_id": docs[1].Id}); err != nil || !reflect.DeepEqual(got, docs[1]) {
+			t.Errorf("replace changed another document: %+v, %v", got, err)
+		}
+
+		missing := testDoc{Id: primitive.NewObjectID(), Name: "m"}
+		if err := s.replace(ctx, Filter{"_id": missing.Id}, missing); !errors.Is(err, ErrNotFound) {
+			t.Errorf("replace of a missing document: %v, want ErrNotFound", err)
+		}
+		if total, _ := s.count(ctx, Filter{}); total != 2 {
+			t.Errorf("replace of a missing document left %d documents, want 2", total)
+		}
+	})
+}
+
+func TestStoreDelete(t *testing.T) {
+	eachStore(t, func(t *testing.T, s store[testDoc]) {
+		ctx := context.Background()
+		docs := insertDocs(t, s, "a", "b")
+
+		if err := s.delete(ctx, Filter{"_id": docs[0].Id}); err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+		if err := s.delete(ctx, Filter{"_id": docs[0].Id}); !errors.Is(err, ErrNotFound) {
+			t.Errorf("second delete: %v, want ErrNotFound", err)
+		}
+		left, err := s.find(ctx, Query{})
+		if err != nil || !reflect.DeepEqual(namesOf(left), []string{"b"}) {
+			t.Errorf("left after delete = %v, %v; want [b]", namesOf(left), err)
+		}
+	})
+}
+
+func TestStoreDuplicate(t *testing.T) {
+	eachStore(t, func(t *testing.T, s store[testDoc]) {
+		ctx := context.Background()
+		docs := insertDocs(t, s, "a", "b")
+
+		if err := s.insert(ctx, testDoc{Id: docs[0].Id, Name: "c"}); !errors.Is(err, ErrDuplicate) {
+			t.Errorf("insert of a taken id: %v, want ErrDuplicate", err)
+		}
+		moved := docs[1]
+		moved.Id = docs[0].Id
+		if err := s.replace(ctx, Filter{"_id": docs[1].Id}, moved); !errors.Is(err, ErrDuplicate) {
+			t.Errorf("replace onto a taken id: %v, want ErrDuplicate", err)
+		}
+		if err := s.replace(ctx, Filter{"_id": docs[0].Id}, docs[0]); err != nil {
+			t.Errorf("replace keeping its own id: %v", err)
+		}
+	})
+}
+
+func TestStoreSeek(t *testing.T) {
+	eachStore(t, func(t *testing.T, s store[testDoc]) {
+		ctx := context.Background()
+		docs := insertDocs(t, s, "a", "b", "c", "d", "e")
+		// b and c tie on rank, so the seek has to fall through to _id
+		docs[2].Rank = docs[1].Rank
+		if err := s.replace(ctx, Filter{"_id": docs[2].Id}, docs[2]); err != nil {
+			t.Fatal(err)
+		}
+
+		sorts := map[string][]Sort{
+			"ascending":  {{Field: "rank"}},
+			"descending": {{Field: "rank", Desc: true}},
+		}
+		for name, sort := range sorts {
+			all, _, err := list(ctx, s, Query{Sort: sort})
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			for i, doc := range all {
+				key, err := SortValues(doc, sort)
+				if err != nil {
+					t.Fatal(err)
+				}
+				after, total, err := list(ctx, s, Query{Sort: sort, After: key, Limit: 2})
+				end := i + 3
+				if end > len(all) {
+					end = len(all)
+				}
+				if err != nil || total != 5 || !reflect.DeepEqual(namesOf(after), namesOf(all[i+1:end])) {
+					t.Errorf("%s: after %s = %v, %d, %v; want %v", name, doc.Name, namesOf(after), total, err, namesOf(all[i+1:end]))
+				}
+
+				before, _, err := list(ctx, s, Query{Sort: sort, Before: key, Limit: 2})
+				start := i - 2
+				if start < 0 {
+					start = 0
+				}
+				if err != nil || !reflect.DeepEqual(namesOf(before), namesOf(all[start:i])) {
+					t.Errorf("%s: before %s = %v, %v; want %v", name, doc.Name, namesOf(before), err, namesOf(all[start:i]))
+				}
+			}
+		}
+	})
+}
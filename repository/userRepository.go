@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+
+	"golang_cms/model"
+)
+
+type userRepository struct {
+	store store[model.User]
+}
+
+func newUserRepository(s store[model.User]) *userRepository {
+	return &userRepository{store: s}
+}
+
+func (r *userRepository) Create(ctx context.Context, user model.User) (model.User, error) {
+	if err := r.store.insert(ctx, user); err != nil {
+		return model.User{}, err
+	}
+	return user, nil
+}
+
+func (r *userRepository) FindByUserID(ctx context.Context, userId string) (model.User, error) {
+	return r.store.findOne(ctx, Filter{"user_id": userId})
+}
+
+func (r *userRepository) FindByEmail(ctx context.Context, email string) (model.User, error) {
+	return r.store.findOne(ctx, Filter{"email": email})
+}
+
+func (r *userRepository) CountByEmail(ctx context.Context, email string) (int64, error) {
+	return r.store.count(ctx, Filter{"email": email})
+}
+
+func (r *userRepository) CountByPhone(ctx context.Context, phone string) (int64, error) {
+	return r.store.count(ctx, Filter{"phone": phone})
+}
+
+func (r *userRepository) CountByRole(ctx context.Context, role string) (int64, error) {
+	return r.store.count(ctx, Filter{"role": role})
+}
+
+func (r *userRepository) List(ctx context.Context, q Query) ([]model.User, int64, error) {
+	return list(ctx, r.store, q)
+}
+
+func (r *userRepository) Update(ctx context.Context, user model.User) (model.User, error) {
+	filter := Filter{"user_id": user.User_id}
+	if err := r.store.replace(ctx, filter, user); err != nil {
+		return model.User{}, err
+	}
+	return r.store.findOne(ctx, filter)
+}
@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"golang_cms/apitoken"
+	"golang_cms/controller"
+	"golang_cms/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+func ApiTokenRoutes(incomingRoutes *gin.RouterGroup, repos repository.Repositories, tokens *apitoken.Service) {
+	apiToken := controller.NewApiTokenController(repos.ApiToken, tokens)
+
+	//token API milik user yang login, hanya bisa diatur dari sesi login dan bukan dengan token API
+	incomingRoutes.GET("/tokens", apiToken.GetApiTokens)               //mengambil semuah token API user
+	incomingRoutes.POST("/tokens", apiToken.CreateApiToken)            //membuat token API baru, token hanya ditampilkan sekali
+	incomingRoutes.DELETE("/tokens/:tokenid", apiToken.DeleteApiToken) //mencabut token API
+}
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"golang_cms/controller"
+	"golang_cms/middleware"
+	"golang_cms/rbac"
+	"golang_cms/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+func AuditRoutes(incomingRoutes *gin.RouterGroup, repos repository.Repositories, authz *rbac.Authorizer) {
+	audit := controller.NewAuditController(repos.Audit)
+
+	//catatan audit, perlu permission audit:read: ?actor=, ?actor_email=, ?resource_type=, ?resource_id=, ?action=, ?created_at[gte]=&created_at[lte]=
+	incomingRoutes.GET("/audit", middleware.RequirePermission(authz, "audit:read"), audit.GetAuditLog)
+}
@@ -1,12 +1,31 @@
 package routes
 
 import (
+	"time"
+
 	"golang_cms/controller"
+	"golang_cms/middleware"
+	"golang_cms/passwordreset"
+	"golang_cms/ratelimit"
+	"golang_cms/rbac"
+	"golang_cms/repository"
+	"golang_cms/session"
 
 	"github.com/gin-gonic/gin"
 )
 
-func AuthRoutes(incomingRoutes *gin.Engine) {
-	incomingRoutes.POST("/users/register", controller.Register)
-	incomingRoutes.POST("/users/login", controller.Login)
-}
\ No newline at end of file
+// forgotPasswordPerIP reset requests can be made from one client IP every
+// 15 minutes; each email has its own limit on top.
+const forgotPasswordPerIP = 10
+
+func AuthRoutes(incomingRoutes *gin.Engine, repos repository.Repositories, authz *rbac.Authorizer, sessions *session.Manager, resets *passwordreset.Service, authenticate gin.HandlerFunc) {
+	user := controller.NewUserController(repos.User, authz, sessions)
+	auth := controller.NewAuthController(repos.User, sessions, resets)
+
+	incomingRoutes.POST("/users/register", user.Register)
+	incomingRoutes.POST("/users/login", auth.Login)
+	incomingRoutes.POST("/auth/refresh", auth.Refresh)
+	incomingRoutes.POST("/auth/logout", authenticate, auth.Logout)
+	incomingRoutes.POST("/auth/forgot-password", middleware.RateLimitByIP(ratelimit.New(forgotPasswordPerIP, 15*time.Minute)), auth.ForgotPassword)
+	incomingRoutes.POST("/auth/reset-password", auth.ResetPassword)
+}
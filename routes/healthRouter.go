@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"context"
+	"golang_cms/controller"
+
+	"github.com/gin-gonic/gin"
+)
+
+func HealthRoutes(incomingRoutes *gin.Engine, ready func(ctx context.Context) error) {
+	health := controller.NewHealthController(ready)
+
+	incomingRoutes.GET("/healthz", health.Liveness) //proses masih hidup
+	incomingRoutes.GET("/readyz", health.Readiness) //siap menerima request, database bisa di ping
+}
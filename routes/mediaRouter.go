@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"golang_cms/controller"
+	"golang_cms/media"
+	"golang_cms/middleware"
+	"golang_cms/rbac"
+	"golang_cms/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MediaRoutes serves the files themselves to anyone, since published
+// content links to them, and everything else under admin.
+func MediaRoutes(incomingRoutes *gin.Engine, admin *gin.RouterGroup, repos repository.Repositories, library *media.Library, authz *rbac.Authorizer) {
+	guard := middleware.Guard(authz)
+	media := controller.NewMediaController(repos.Media, repos.Banner, repos.Child, library)
+
+	incomingRoutes.GET("/media/:mediaid", media.Serve) //mengambil isi file
+
+	admin.POST("/media", guard("media:write"), media.Upload)                 //upload file lewat multipart form, field "file"
+	admin.GET("/medias", guard("media:read"), media.GetMedias)               //mengambil semuah media
+	admin.GET("/media/:mediaid/info", guard("media:read"), media.GetMedia)   //mengambil data media: ukuran, dimensi, checksum, uploader
+	admin.DELETE("/media/:mediaid", guard("media:write"), media.DeleteMedia) //menghapus media yang tidak dipakai banner atau sub kategori
+}
@@ -0,0 +1,33 @@
+package routes
+
+import (
+	"golang_cms/controller"
+	"golang_cms/repository"
+	"golang_cms/search"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PublicRoutes serves the read-only delivery API of the site under /public:
+// only published content, to anyone. Managing content goes through /admin.
+func PublicRoutes(incomingRoutes *gin.Engine, repos repository.Repositories, index search.Index) {
+	banner := controller.NewBannerResource(repos.Banner, repos.Media)
+	meta := controller.NewMetaResource(repos.Meta, repos.Revision)
+	desc := controller.NewDescResource(repos.Desc, repos.Revision)
+	kategori := controller.NewKategoriResource(repos.Category, repos.Child)
+	child := controller.NewChildKategoriController(repos.Category, repos.Child, repos.Media)
+	tree := controller.NewCategoryTreeController(repos.Tree, repos.Product)
+	search := controller.NewSearchController(index)
+
+	public := incomingRoutes.Group("/public")
+
+	//GET satu data dan GET semuah data, hanya yang berstatus published
+	banner.RegisterPublic(public, "/banner", "/banners")
+	meta.RegisterPublic(public, "/meta", "/metas")
+	desc.RegisterPublic(public, "/desc", "/descs")
+	kategori.RegisterPublic(public, "/kategori", "/kategori")
+	public.GET("/kategori/:kategoriid/with-children", child.GetPublicKategoriWithChildren) //kategori published beserta semua sub kategorinya
+	public.GET("/categories/tree", tree.GetPublicTree)                                     //pohon kategori published, kategori yang tidak published menyembunyikan turunannya
+	public.GET("/categories/:categoryid", tree.GetPublicCategory)                          //satu kategori yang published beserta semua leluhurnya
+	public.GET("/search", search.PublicSearch)                                             //mencari di konten yang published saja
+}
@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"golang_cms/controller"
+	"golang_cms/middleware"
+	"golang_cms/rbac"
+	"golang_cms/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RoleRoutes(incomingRoutes *gin.RouterGroup, repos repository.Repositories, authz *rbac.Authorizer) {
+	guard := middleware.Guard(authz)
+	role := controller.NewRoleController(repos.Role, repos.User, authz)
+
+	//role bawaan: admin, editor, author, viewer; role lain dibuat admin dengan permission sendiri
+	incomingRoutes.GET("/permissions", guard("role:read"), role.GetPermissions)      //mengambil semuah permission yang ada
+	incomingRoutes.GET("/roles", guard("role:read"), role.GetRoles)                  //mengambil semuah role, bawaan dan buatan
+	incomingRoutes.GET("/roles/:role", guard("role:read"), role.GetRole)             //mengambil satu role
+	incomingRoutes.POST("/roles", guard("role:write"), role.CreateRole)              //memasukan role baru
+	incomingRoutes.PUT("/roles/:role", guard("role:write"), role.UpdateRole)         //mengubah deskripsi dan permission role buatan
+	incomingRoutes.DELETE("/roles/:role", guard("role:write"), role.DeleteRole)      //menghapus role buatan yang tidak dipakai user
+	incomingRoutes.PUT("/users/:user_id/role", guard("role:write"), role.AssignRole) //memberi user role lain
+}
@@ -0,0 +1,52 @@
+package routes
+
+import (
+	"time"
+
+	"golang_cms/apitoken"
+	"golang_cms/media"
+	"golang_cms/middleware"
+	"golang_cms/passwordreset"
+	"golang_cms/rbac"
+	"golang_cms/repository"
+	"golang_cms/search"
+	"golang_cms/session"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+)
+
+// NewRouter builds the full gin engine on top of the given repositories,
+// search index, media library and sessions, so the same router can run
+// against Mongo or the in-memory implementations.
+func NewRouter(repos repository.Repositories, index search.Index, library *media.Library, sessions *session.Manager, resets *passwordreset.Service) *gin.Engine {
+	router := gin.New()
+	router.Use(cors.New(cors.Config{
+		AllowOrigins:     []string{"*"},
+		AllowMethods:     []string{"PUT", "PATCH", "GET", "POST", "OPTIONS", "DELETE"},
+		AllowHeaders:     []string{"*"},
+		ExposeHeaders:    []string{"*"},
+		AllowCredentials: false,
+		AllowOriginFunc: func(origin string) bool {
+			return origin == "*"
+		},
+		MaxAge: 12 * time.Hour,
+	}))
+
+	//tanpa login: daftar, login, refresh token, lupa password, isi file media dan konten published di /public
+	//semuah yang lain ada di /admin dan perlu token (Authorization: Bearer) serta permission dari role user
+	authz := rbac.NewAuthorizer(repos.User, repos.Role)
+	apiTokens := apitoken.NewService(repos.ApiToken, repos.User, authz)
+	authenticate := middleware.Authentication(sessions, apiTokens)
+	admin := router.Group("/admin", authenticate)
+
+	AuthRoutes(router, repos, authz, sessions, resets, authenticate)
+	PublicRoutes(router, repos, index)
+	UserRoutes(admin, repos, authz, sessions)
+	RoleRoutes(admin, repos, authz)
+	AuditRoutes(admin, repos, authz)
+	ApiTokenRoutes(admin, repos, apiTokens)
+	SearchRoutes(admin, index, authz)
+	MediaRoutes(router, admin, repos, library, authz)
+	return router
+}
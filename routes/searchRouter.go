@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"golang_cms/controller"
+	"golang_cms/middleware"
+	"golang_cms/rbac"
+	"golang_cms/search"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SearchRoutes(incomingRoutes *gin.RouterGroup, index search.Index, authz *rbac.Authorizer) {
+	search := controller.NewSearchController(index)
+
+	incomingRoutes.GET("/search", middleware.RequirePermission(authz), search.Search) //mencari di banner, meta, desc dan kategori sekaligus, hanya jenis yang boleh dibaca user
+}
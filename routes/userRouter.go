@@ -2,40 +2,68 @@ package routes
 
 import (
 	"golang_cms/controller"
-	// "golang_cms/middleware"
+	"golang_cms/middleware"
+	"golang_cms/rbac"
+	"golang_cms/repository"
+	"golang_cms/session"
 
 	"github.com/gin-gonic/gin"
 )
 
-func UserRoutes(incomingRoutes *gin.Engine) {
-	// incomingRoutes.Use(middleware.Authentication)
-	incomingRoutes.GET("/users", controller.GetUsers)
-	incomingRoutes.GET("/users/:user_id", controller.GetUser)
-	incomingRoutes.GET("/user/:Email", controller.GetUserEmail)
-	incomingRoutes.PUT("/user/:update", controller.UpdateUser)
+// UserRoutes serves the users and the content behind /admin: every route
+// needs a token, and a permission of the role of its user.
+func UserRoutes(incomingRoutes *gin.RouterGroup, repos repository.Repositories, authz *rbac.Authorizer, sessions *session.Manager) {
+	guard := middleware.Guard(authz)
+	user := controller.NewUserController(repos.User, authz, sessions)
+	banner := controller.NewBannerResource(repos.Banner, repos.Media)
+	meta := controller.NewMetaResource(repos.Meta, repos.Revision)
+	desc := controller.NewDescResource(repos.Desc, repos.Revision)
+	kategori := controller.NewKategoriResource(repos.Category, repos.Child)
+	child := controller.NewChildKategoriController(repos.Category, repos.Child, repos.Media)
+	tree := controller.NewCategoryTreeController(repos.Tree, repos.Product)
+	product := controller.NewProductController(repos.Product, repos.Tree)
 
-	//banner
-	incomingRoutes.POST("/banner", controller.CreateBanner)             //memasukan data banner baru
-	incomingRoutes.GET("/banner/:bannerId", controller.GetBanner)      //mengambil satu data menggunakan filter ID
-	incomingRoutes.PUT("/banner/:bannerId", controller.EditBanner)     //mengedit satu data menggunaakn filter ID
-	incomingRoutes.DELETE("/banner/:bannerId", controller.DeleteBanner) //menghapus satu data menggunaakn filter ID
-	incomingRoutes.GET("/banners", controller.GetAllBanner)             // mengambil semuah data Banner
-	//meta
-	incomingRoutes.POST("/meta", controller.CreateMeta)           //memasukan data meta baru
-	incomingRoutes.GET("/meta/:metaId", controller.GetMeta)      //mengambil satu data meta dengan filter ID
-	incomingRoutes.PUT("/meta/:metaId", controller.EditMeta)     //mengedit satu data meta dengan filter ID
-	incomingRoutes.DELETE("/meta/:metaId", controller.DeleteMeta) //menghapus satu data dengan filter ID
-	incomingRoutes.GET("/metas", controller.GetAllMeta)           //mengambill semuah data meta
-	//desc
-	incomingRoutes.POST("/desc", controller.CreateDesc)           //memasukan data meta baru
-	incomingRoutes.GET("/desc/:descId", controller.GetDesc)      //mengambil satu data meta dengan filter ID
-	incomingRoutes.PUT("/desc/:descId", controller.EditDesc)     //mengedit satu data meta dengan filter ID
-	incomingRoutes.DELETE("/desc/:descId", controller.DeleteDesc) //menghapus satu data dengan filter ID
-	incomingRoutes.GET("/descs", controller.GetAllDesc)           //mengambill semuah data meta
-	//Kategori Produk main
-	incomingRoutes.POST("kategori", controller.CreateKategori)               //memasukan data baru pada kategori_produk
-	incomingRoutes.GET("kategori/:kategoriid", controller.GetKategori)      //memanggil satu data kategori denga filter ID
-	incomingRoutes.PUT("kategori/:kategoriid", controller.EditKategori)      //mengedit satu data kategori dengan filter ID
-	incomingRoutes.DELETE("delkategori/:kategoriid", controller.DeleteKategori) //menghaspus satu data kategori dengan filter ID
-	incomingRoutes.GET("kategori", controller.GetAllKategori)                //mengambil semuah data kategori produk
-}
\ No newline at end of file
+	//user boleh melihat dan mengubah datanya sendiri, selain itu perlu permission user:read atau user:write
+	incomingRoutes.GET("/users", guard("user:read"), user.GetUsers)
+	incomingRoutes.GET("/users/:user_id", middleware.RequirePermissionOrSelf(authz, "user_id", "user:read"), user.GetUser)
+	incomingRoutes.GET("/user/:Email", middleware.RequirePermissionOrSelf(authz, "Email", "user:read"), user.GetUserEmail)
+	incomingRoutes.PUT("/user/:user_id", middleware.RequirePermissionOrSelf(authz, "user_id", "user:write"), user.UpdateUser)
+
+	//banner, meta, desc dan kategori: POST/GET/PUT/DELETE satu data dengan filter ID, GET semuah data
+	//GET perlu permission <jenis>:read, selain itu <jenis>:write, misalnya banner:read dan banner:write
+	//PUT .../:id/status mengubah status tayang saja: draft, in_review, published atau archived, dengan publish_at dan unpublish_at untuk menjadwalkan
+	//publish, archive dan jadwal perlu permission <jenis>:publish, tanpa itu hanya draft dan in_review
+	//DELETE memindahkan data ke tong sampah: GET /trash/banners, /trash/metas, /trash/descs, /trash/kategori, POST .../:id/restore untuk mengembalikan, DELETE .../:id/purge untuk menghapus permanen
+	//meta dan desc juga menyimpan riwayat revisi: GET .../revisions, .../revisions/:number, .../revisions/diff?from=&to=, POST .../revisions/:number/restore
+	banner.Register(incomingRoutes, "/banner", "/banners", guard)
+	meta.Register(incomingRoutes, "/meta", "/metas", guard)
+	desc.Register(incomingRoutes, "/desc", "/descs", guard)
+	kategori.Register(incomingRoutes, "/kategori", "/kategori", guard)
+	incomingRoutes.DELETE("/delkategori/:kategoriid", guard("kategori:write"), kategori.Delete) //route lama, tetap ada untuk client yang sudah memakai
+	//sub kategori di bawah kategori utama
+	incomingRoutes.GET("/kategori/:kategoriid/with-children", guard("kategori:read"), child.GetKategoriWithChildren)    //kategori beserta semua sub kategorinya
+	incomingRoutes.POST("/kategori/:kategoriid/children", guard("kategori:write"), child.CreateChild)                   //memasukan sub kategori baru
+	incomingRoutes.GET("/kategori/:kategoriid/children", guard("kategori:read"), child.GetChildren)                     //mengambil semuah sub kategori
+	incomingRoutes.GET("/kategori/:kategoriid/children/:childid", guard("kategori:read"), child.GetChild)               //mengambil satu sub kategori
+	incomingRoutes.PUT("/kategori/:kategoriid/children/:childid", guard("kategori:write"), child.EditChild)             //mengedit satu sub kategori
+	incomingRoutes.DELETE("/kategori/:kategoriid/children/:childid", guard("kategori:write"), child.DeleteChild)        //menghapus satu sub kategori
+	incomingRoutes.PUT("/kategori/:kategoriid/children/:childid/status", guard("kategori:write"), child.SetChildStatus) //mengubah status tayang sub kategori
+	//pohon kategori dengan kedalaman bebas
+	incomingRoutes.POST("/categories", guard("category:write"), tree.CreateCategory)                      //memasukan kategori baru, parent_id kosong untuk kategori root
+	incomingRoutes.GET("/categories", guard("category:read"), tree.GetCategories)                         //mengambil semuah kategori dalam urutan pohon
+	incomingRoutes.GET("/categories/tree", guard("category:read"), tree.GetTree)                          //mengambil seluruh pohon kategori
+	incomingRoutes.PUT("/categories/reorder", guard("category:write"), tree.ReorderCategories)            //mengurutkan ulang kategori yang satu induk
+	incomingRoutes.GET("/categories/:categoryid", guard("category:read"), tree.GetCategory)               //mengambil satu kategori
+	incomingRoutes.GET("/categories/:categoryid/tree", guard("category:read"), tree.GetSubtree)           //mengambil satu kategori beserta semua turunannya
+	incomingRoutes.GET("/categories/:categoryid/breadcrumb", guard("category:read"), tree.GetBreadcrumb)  //mengambil rantai kategori dari root sampai kategori ini
+	incomingRoutes.PUT("/categories/:categoryid", guard("category:write"), tree.EditCategory)             //mengedit nama, slug dan status kategori
+	incomingRoutes.PUT("/categories/:categoryid/move", guard("category:write"), tree.MoveCategory)        //memindahkan kategori ke induk lain
+	incomingRoutes.PUT("/categories/:categoryid/status", guard("category:write"), tree.SetCategoryStatus) //mengubah status tayang kategori
+	incomingRoutes.DELETE("/categories/:categoryid", guard("category:write"), tree.DeleteCategory)        //menghapus kategori yang tidak punya turunan
+	//produk katalog
+	incomingRoutes.POST("/product", guard("product:write"), product.CreateProduct)              //memasukan produk baru
+	incomingRoutes.GET("/product/:productid", guard("product:read"), product.GetProduct)        //mengambil satu produk
+	incomingRoutes.PUT("/product/:productid", guard("product:write"), product.EditProduct)      //mengedit satu produk
+	incomingRoutes.DELETE("/product/:productid", guard("product:write"), product.DeleteProduct) //menghapus satu produk
+	incomingRoutes.GET("/products", guard("product:read"), product.GetProducts)                 //mengambil semuah produk, ?category= untuk satu kategori beserta turunannya
+}
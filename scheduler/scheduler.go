@@ -0,0 +1,73 @@
+// Package scheduler runs background jobs at a fixed interval for as long as
+// the application serves.
+package scheduler
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// Job is work repeated every Interval. Each run gets a context that ends
+// after Interval, so a stuck run does not delay the next one forever.
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// Scheduler runs each of its jobs in its own goroutine. Runs of the same job
+// never overlap.
+type Scheduler struct {
+	jobs []Job
+	wg   sync.WaitGroup
+}
+
+func New(jobs ...Job) *Scheduler {
+	return &Scheduler{jobs: jobs}
+}
+
+// Start runs every job right away and then once per Interval until ctx is
+// cancelled. A failed run is logged and the job runs again on its next tick.
+func (s *Scheduler) Start(ctx context.Context) {
+	for _, job := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(ctx, job)
+	}
+}
+
+// Wait blocks until every job has stopped, which they do once the context
+// given to Start is cancelled and their current run returns.
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+	for {
+		run(ctx, job)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func run(ctx context.Context, job Job) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("scheduler: job %s panicked: %v", job.Name, r)
+		}
+	}()
+
+	runCtx, cancel := context.WithTimeout(ctx, job.Interval)
+	defer cancel()
+	if err := job.Run(runCtx); err != nil && ctx.Err() == nil {
+		log.Printf("scheduler: job %s failed: %v", job.Name, err)
+	}
+}
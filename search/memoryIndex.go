@@ -0,0 +1,148 @@
+package search
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+)
+
+// BM25 parameters: k1 limits how much repeating a word raises the score and
+// b how much longer documents are penalized.
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+
+	// titleWeight counts each word of the title field as this many words.
+	titleWeight = 2.0
+)
+
+type entry struct {
+	doc    Document
+	freqs  map[string]float64
+	length float64
+}
+
+// MemoryIndex is an inverted index held in process memory, ranking matches
+// with BM25. It is what deployments without MongoDB search with, and is
+// rebuilt from the repositories every time the application starts.
+type MemoryIndex struct {
+	mu       sync.RWMutex
+	entries  map[string]*entry
+	postings map[string]map[string]bool
+	length   float64
+}
+
+func NewMemoryIndex() *MemoryIndex {
+	return &MemoryIndex{
+		entries:  map[string]*entry{},
+		postings: map[string]map[string]bool{},
+	}
+}
+
+func entryKey(typ string, id string) string { return typ + "/" + id }
+
+func (m *MemoryIndex) Put(ctx context.Context, doc Document) error {
+	e := &entry{doc: doc, freqs: map[string]float64{}}
+	for i, field := range doc.Fields {
+		weight := 1.0
+		if i == 0 {
+			weight = titleWeight
+		}
+		for _, t := range tokenize([]rune(field.Text)) {
+			e.freqs[t.term] += weight
+			e.length += weight
+		}
+	}
+
+	key := entryKey(doc.Type, doc.Id)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.remove(key)
+	m.entries[key] = e
+	m.length += e.length
+	for term := range e.freqs {
+		if m.postings[term] == nil {
+			m.postings[term] = map[string]bool{}
+		}
+		m.postings[term][key] = true
+	}
+	return nil
+}
+
+func (m *MemoryIndex) Remove(ctx context.Context, typ string, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.remove(entryKey(typ, id))
+	return nil
+}
+
+func (m *MemoryIndex) remove(key string) {
+	e, ok := m.entries[key]
+	if !ok {
+		return
+	}
+	for term := range e.freqs {
+		delete(m.postings[term], key)
+		if len(m.postings[term]) == 0 {
+			delete(m.postings, term)
+		}
+	}
+	m.length -= e.length
+	delete(m.entries, key)
+}
+
+func (m *MemoryIndex) Search(ctx context.Context, q Query) ([]Hit, error) {
+	queryTerms := terms(q.Text)
+	if len(queryTerms) == 0 {
+		return []Hit{}, nil
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	n := float64(len(m.entries))
+	avgLength := m.length / n
+	scores := map[string]float64{}
+	for term := range queryTerms {
+		keys := m.postings[term]
+		idf := math.Log(1 + (n-float64(len(keys))+0.5)/(float64(len(keys))+0.5))
+		for key := range keys {
+			e := m.entries[key]
+			if !wants(q.Types, e.doc.Type) || (q.Published && !e.doc.Published) {
+				continue
+			}
+			freq := e.freqs[term]
+			scores[key] += idf * freq * (bm25K1 + 1) / (freq + bm25K1*(1-bm25B+bm25B*e.length/avgLength))
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for key, score := range scores {
+		doc := m.entries[key].doc
+		hits = append(hits, Hit{Type: doc.Type, Id: doc.Id, Score: score})
+	}
+	sortHits(hits)
+	if limit := limitOf(q); len(hits) > limit {
+		hits = hits[:limit]
+	}
+	for i, hit := range hits {
+		hits[i] = hitOf(m.entries[entryKey(hit.Type, hit.Id)].doc, queryTerms, hit.Score)
+	}
+	return hits, nil
+}
+
+// sortHits puts the best scores first, breaking ties by type and id so
+// results are stable.
+func sortHits(hits []Hit) {
+	sort.Slice(hits, func(i, j int) bool {
+		a, b := hits[i], hits[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.Id < b.Id
+	})
+}
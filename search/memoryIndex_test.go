@@ -0,0 +1,88 @@
+package search
+
+import (
+	"context"
+	"testing"
+)
+
+func putDocs(t *testing.T, index Index, docs ...Document) {
+	t.Helper()
+	for _, doc := range docs {
+		if err := index.Put(context.Background(), doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func doc(typ, id, title, body string) Document {
+	return Document{Type: typ, Id: id, Fields: []Field{{Name: "title", Text: title}, {Name: "body", Text: body}}}
+}
+
+func hitIds(hits []Hit) []string {
+	ids := []string{}
+	for _, hit := range hits {
+		ids = append(ids, hit.Type+"/"+hit.Id)
+	}
+	return ids
+}
+
+func TestMemoryIndexRanking(t *testing.T) {
+	index := NewMemoryIndex()
+	putDocs(t, index,
+		doc("desc", "1", "Shoes", "red leather shoes"),
+		doc("desc", "2", "Hats", "a red hat to go with red shoes"),
+		doc("banner", "3", "Sale", "everything must go"),
+	)
+
+	hits, err := index.Search(context.Background(), Query{Text: "shoes"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a match in the title weighs more than one in the body
+	if got := hitIds(hits); len(got) != 2 || got[0] != "desc/1" {
+		t.Errorf("shoes = %v, want desc/1 first", got)
+	}
+	if hits[0].Title != "Shoes" || hits[0].Snippet == "" || hits[0].Score <= hits[1].Score {
+		t.Errorf("first hit = %+v", hits[0])
+	}
+
+	hits, _ = index.Search(context.Background(), Query{Text: "RED sale"})
+	if got := hitIds(hits); len(got) != 3 {
+		t.Errorf("any word matches: %v", got)
+	}
+	hits, _ = index.Search(context.Background(), Query{Text: "red sale", Types: []string{"banner"}})
+	if got := hitIds(hits); len(got) != 1 || got[0] != "banner/3" {
+		t.Errorf("banners only = %v", got)
+	}
+	hits, _ = index.Search(context.Background(), Query{Text: "red", Limit: 1})
+	if len(hits) != 1 {
+		t.Errorf("limit 1 = %v", hitIds(hits))
+	}
+	hits, _ = index.Search(context.Background(), Query{Text: " ,. "})
+	if hits == nil || len(hits) != 0 {
+		t.Errorf("query without words = %v, want an empty list", hits)
+	}
+}
+
+func TestMemoryIndexPutReplaces(t *testing.T) {
+	ctx := context.Background()
+	index := NewMemoryIndex()
+	putDocs(t, index, doc("desc", "1", "Shoes", "red"), doc("desc", "1", "Hats", "blue"))
+
+	if hits, _ := index.Search(ctx, Query{Text: "shoes red"}); len(hits) != 0 {
+		t.Errorf("old words still match: %v", hitIds(hits))
+	}
+	if hits, _ := index.Search(ctx, Query{Text: "hats"}); len(hits) != 1 {
+		t.Errorf("new words = %v", hitIds(hits))
+	}
+
+	if err := index.Remove(ctx, "desc", "1"); err != nil {
+		t.Fatal(err)
+	}
+	if hits, _ := index.Search(ctx, Query{Text: "hats"}); len(hits) != 0 {
+		t.Errorf("removed document still matches: %v", hitIds(hits))
+	}
+	if len(index.entries) != 0 || len(index.postings) != 0 || index.length != 0 {
+		t.Errorf("index not empty after removing everything: %d entries, %d postings, length %v", len(index.entries), len(index.postings), index.length)
+	}
+}
@@ -0,0 +1,81 @@
+package search
+
+import (
+	"context"
+
+	"golang_cms/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoIndex searches the text index of every source collection, created
+// by the create_search_text_indexes migration. MongoDB keeps those indexes
+// current on every write, so Put and Remove do nothing. Documents in the
+// trash are left out of the results, and so are unpublished ones when the
+// query asks for published documents only.
+//
+// Text scores are computed per collection; hits of different types are
+// merged by score as if they were comparable, which is close enough for
+// ranking but not exact.
+type MongoIndex struct {
+	db *mongo.Database
+}
+
+func NewMongoIndex(db *mongo.Database) *MongoIndex {
+	return &MongoIndex{db: db}
+}
+
+func (m *MongoIndex) Put(ctx context.Context, doc Document) error { return nil }
+
+func (m *MongoIndex) Remove(ctx context.Context, typ string, id string) error { return nil }
+
+func (m *MongoIndex) Search(ctx context.Context, q Query) ([]Hit, error) {
+	queryTerms := terms(q.Text)
+	if len(queryTerms) == 0 {
+		return []Hit{}, nil
+	}
+
+	limit := limitOf(q)
+	score := bson.M{"$meta": "textScore"}
+	opts := options.Find().
+		SetProjection(bson.M{"score": score}).
+		SetSort(bson.M{"score": score}).
+		SetLimit(int64(limit))
+
+	hits := []Hit{}
+	for _, source := range Sources {
+		if !wants(q.Types, source.Type) {
+			continue
+		}
+
+		filter := bson.M{"$text": bson.M{"$search": q.Text}, "deleted_at": nil}
+		if q.Published {
+			filter["status"] = bson.M{"$in": bson.A{model.StatusPublished, nil}}
+		}
+		cursor, err := m.db.Collection(source.Collection).Find(ctx, filter, opts)
+		if err != nil {
+			return nil, err
+		}
+		var found []bson.M
+		if err := cursor.All(ctx, &found); err != nil {
+			return nil, err
+		}
+
+		for _, raw := range found {
+			doc, err := documentFrom(source, raw)
+			if err != nil {
+				return nil, err
+			}
+			s, _ := raw["score"].(float64)
+			hits = append(hits, hitOf(doc, queryTerms, s))
+		}
+	}
+
+	sortHits(hits)
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
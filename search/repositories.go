@@ -0,0 +1,139 @@
+package search
+
+import (
+	"context"
+
+	"golang_cms/model"
+	"golang_cms/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Indexed wraps the searchable repositories of repos so that every create,
+// update and delete that succeeds is also applied to index.
+func Indexed(repos repository.Repositories, index Index) repository.Repositories {
+	repos.Banner = &indexedRepository[model.Banner]{repos.Banner, index, Banners}
+	repos.Meta = &indexedRepository[model.Meta]{repos.Meta, index, Metas}
+	repos.Desc = &indexedRepository[model.Desc]{repos.Desc, index, Descs}
+	repos.Category = &indexedRepository[model.MainCategory]{repos.Category, index, Kategori}
+	repos.Child = &indexedChildRepository{
+		ChildCategoryRepository: repos.Child,
+		indexed:                 &indexedRepository[model.ChildCategory]{repos.Child, index, ChildKategori},
+	}
+	repos.Tree = &indexedTreeRepository{
+		CategoryTreeRepository: repos.Tree,
+		indexed:                &indexedRepository[model.Category]{repos.Tree, index, CategoryTree},
+	}
+	return repos
+}
+
+// Rebuild puts every searchable document of repos into index.
+func Rebuild(ctx context.Context, repos repository.Repositories, index Index) error {
+	if err := putAll[model.Banner](ctx, index, Banners, repos.Banner); err != nil {
+		return err
+	}
+	if err := putAll[model.Meta](ctx, index, Metas, repos.Meta); err != nil {
+		return err
+	}
+	if err := putAll[model.Desc](ctx, index, Descs, repos.Desc); err != nil {
+		return err
+	}
+	if err := putAll[model.MainCategory](ctx, index, Kategori, repos.Category); err != nil {
+		return err
+	}
+	if err := putAll[model.ChildCategory](ctx, index, ChildKategori, repos.Child); err != nil {
+		return err
+	}
+	return putAll[model.Category](ctx, index, CategoryTree, repos.Tree)
+}
+
+func putAll[T any](ctx context.Context, index Index, source Source, repo repository.CrudRepository[T]) error {
+	docs, err := repo.FindAll(ctx)
+	if err != nil {
+		return err
+	}
+	for _, doc := range docs {
+		if err := put(ctx, index, source, doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// put indexes doc, or takes it out of the index while it is in the trash.
+func put[T any](ctx context.Context, index Index, source Source, doc T) error {
+	document, err := DocumentOf(source, doc)
+	if err != nil {
+		return err
+	}
+	if trashable, ok := interface{}(&doc).(model.Trashable); ok && trashable.TrashState().DeletedAt != nil {
+		return index.Remove(ctx, source.Type, document.Id)
+	}
+	return index.Put(ctx, document)
+}
+
+type indexedRepository[T any] struct {
+	repository.CrudRepository[T]
+	index  Index
+	source Source
+}
+
+func (r *indexedRepository[T]) Create(ctx context.Context, doc T) (T, error) {
+	created, err := r.CrudRepository.Create(ctx, doc)
+	if err != nil {
+		return created, err
+	}
+	return created, put(ctx, r.index, r.source, created)
+}
+
+func (r *indexedRepository[T]) Update(ctx context.Context, doc T) (T, error) {
+	updated, err := r.CrudRepository.Update(ctx, doc)
+	if err != nil {
+		return updated, err
+	}
+	return updated, put(ctx, r.index, r.source, updated)
+}
+
+func (r *indexedRepository[T]) Delete(ctx context.Context, id primitive.ObjectID) error {
+	if err := r.CrudRepository.Delete(ctx, id); err != nil {
+		return err
+	}
+	return r.index.Remove(ctx, r.source.Type, id.Hex())
+}
+
+// indexedChildRepository and indexedTreeRepository route the writes of the
+// shared CRUD methods through indexedRepository and leave the rest as is.
+// Moving or reordering a category does not change its indexed fields.
+type indexedChildRepository struct {
+	repository.ChildCategoryRepository
+	indexed *indexedRepository[model.ChildCategory]
+}
+
+func (r *indexedChildRepository) Create(ctx context.Context, doc model.ChildCategory) (model.ChildCategory, error) {
+	return r.indexed.Create(ctx, doc)
+}
+
+func (r *indexedChildRepository) Update(ctx context.Context, doc model.ChildCategory) (model.ChildCategory, error) {
+	return r.indexed.Update(ctx, doc)
+}
+
+func (r *indexedChildRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return r.indexed.Delete(ctx, id)
+}
+
+type indexedTreeRepository struct {
+	repository.CategoryTreeRepository
+	indexed *indexedRepository[model.Category]
+}
+
+func (r *indexedTreeRepository) Create(ctx context.Context, doc model.Category) (model.Category, error) {
+	return r.indexed.Create(ctx, doc)
+}
+
+func (r *indexedTreeRepository) Update(ctx context.Context, doc model.Category) (model.Category, error) {
+	return r.indexed.Update(ctx, doc)
+}
+
+func (r *indexedTreeRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return r.indexed.Delete(ctx, id)
+}
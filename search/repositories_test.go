@@ -0,0 +1,60 @@
+package search
+
+import (
+	"context"
+	"testing"
+
+	"golang_cms/model"
+	"golang_cms/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestIndexedFollowsWrites(t *testing.T) {
+	ctx := context.Background()
+	index := NewMemoryIndex()
+	repos := Indexed(repository.NewMemoryRepositories(), index)
+
+	banner, err := repos.Banner.Create(ctx, model.Banner{Id: primitive.NewObjectID(), Banner: "sale.png", Alt: "Summer sale", Link: "/sale"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hits, _ := index.Search(ctx, Query{Text: "summer"}); len(hits) != 1 || hits[0].Id != banner.Id.Hex() || hits[0].Title != "Summer sale" {
+		t.Errorf("after create = %+v", hits)
+	}
+
+	banner.Alt = "Winter sale"
+	if _, err := repos.Banner.Update(ctx, banner); err != nil {
+		t.Fatal(err)
+	}
+	if hits, _ := index.Search(ctx, Query{Text: "summer"}); len(hits) != 0 {
+		t.Errorf("old text still found after update: %+v", hits)
+	}
+
+	if err := repos.Banner.Delete(ctx, banner.Id); err != nil {
+		t.Fatal(err)
+	}
+	if hits, _ := index.Search(ctx, Query{Text: "winter"}); len(hits) != 0 {
+		t.Errorf("found after delete: %+v", hits)
+	}
+}
+
+func TestRebuild(t *testing.T) {
+	ctx := context.Background()
+	repos := repository.NewMemoryRepositories()
+	if _, err := repos.Desc.Create(ctx, model.Desc{Id: primitive.NewObjectID(), Title: "About us", Desc: "who we are"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repos.Tree.Create(ctx, model.Category{Id: primitive.NewObjectID(), Name: "Shoes", Slug: "shoes"}); err != nil {
+		t.Fatal(err)
+	}
+
+	index := NewMemoryIndex()
+	if err := Rebuild(ctx, repos, index); err != nil {
+		t.Fatal(err)
+	}
+	hits, _ := index.Search(ctx, Query{Text: "about shoes"})
+	if got := hitIds(hits); len(got) != 2 {
+		t.Errorf("rebuilt index finds %v", got)
+	}
+}
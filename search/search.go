@@ -0,0 +1,162 @@
+// Package search finds content of every type by the words it contains.
+//
+// An Index is either backed by MongoDB text indexes, which the database keeps
+// current on its own, or by an embedded in-process index that is built from
+// the repositories at startup and updated on every write (see Indexed).
+package search
+
+import (
+	"context"
+	"fmt"
+
+	"golang_cms/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// Source describes a searchable content type: the collection it is stored
+// in and the bson fields whose text is indexed. The first field is the
+// title of a hit and weighs more than the others when ranking.
+type Source struct {
+	Type       string
+	Collection string
+	Fields     []string
+}
+
+var (
+	Banners       = Source{Type: "banner", Collection: "Banner", Fields: []string{"alt", "banner", "link"}}
+	Metas         = Source{Type: "meta", Collection: "Meta", Fields: []string{"meta_title", "meta_desc", "meta_url"}}
+	Descs         = Source{Type: "desc", Collection: "Description", Fields: []string{"title", "desc"}}
+	Kategori      = Source{Type: "kategori", Collection: "Main Category", Fields: []string{"kategori_produk", "nama_produk"}}
+	ChildKategori = Source{Type: "child_kategori", Collection: "Child Category", Fields: []string{"nama_produk"}}
+	CategoryTree  = Source{Type: "category", Collection: "Category", Fields: []string{"name", "slug"}}
+	Sources       = []Source{Banners, Metas, Descs, Kategori, ChildKategori, CategoryTree}
+)
+
+// SourceOf returns the source of a content type.
+func SourceOf(typ string) (Source, bool) {
+	for _, source := range Sources {
+		if source.Type == typ {
+			return source, true
+		}
+	}
+	return Source{}, false
+}
+
+// Field is the text of one indexed field of a document.
+type Field struct {
+	Name string
+	Text string
+}
+
+// Document is the searchable part of a piece of content. Published is
+// false while it has a publishing status other than published; content
+// without a status is always published.
+type Document struct {
+	Type      string
+	Id        string
+	Fields    []Field
+	Published bool
+}
+
+// Title is the text of the first field, which names the document.
+func (d Document) Title() string {
+	if len(d.Fields) == 0 {
+		return ""
+	}
+	return d.Fields[0].Text
+}
+
+// Query asks for the documents matching any word of Text, limited to Types
+// when it is not empty and to published documents when Published is set.
+type Query struct {
+	Text      string
+	Types     []string
+	Limit     int
+	Published bool
+}
+
+// Hit is a matching document. Snippet is HTML: the text around the first
+// match, escaped, with every matching word wrapped in <mark>.
+type Hit struct {
+	Type    string  `json:"type"`
+	Id      string  `json:"id"`
+	Title   string  `json:"title"`
+	Score   float64 `json:"score"`
+	Snippet string  `json:"snippet"`
+}
+
+// Index stores documents and ranks them against queries, best match first.
+type Index interface {
+	Put(ctx context.Context, doc Document) error
+	Remove(ctx context.Context, typ string, id string) error
+	Search(ctx context.Context, q Query) ([]Hit, error)
+}
+
+// DocumentOf extracts the indexed fields of doc as described by source.
+func DocumentOf[T any](source Source, doc T) (Document, error) {
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return Document{}, err
+	}
+	var m bson.M
+	if err := bson.Unmarshal(raw, &m); err != nil {
+		return Document{}, err
+	}
+	return documentFrom(source, m)
+}
+
+func documentFrom(source Source, m bson.M) (Document, error) {
+	id, ok := m["_id"].(primitive.ObjectID)
+	if !ok {
+		return Document{}, fmt.Errorf("%s document has no object id", source.Type)
+	}
+
+	status, hasStatus := m["status"].(string)
+	doc := Document{Type: source.Type, Id: id.Hex(), Published: !hasStatus || status == model.StatusPublished}
+	for _, name := range source.Fields {
+		text, _ := m[name].(string)
+		doc.Fields = append(doc.Fields, Field{Name: name, Text: text})
+	}
+	return doc, nil
+}
+
+// hitOf builds the hit for doc matching terms.
+func hitOf(doc Document, terms map[string]bool, score float64) Hit {
+	return Hit{
+		Type:    doc.Type,
+		Id:      doc.Id,
+		Title:   doc.Title(),
+		Score:   score,
+		Snippet: snippet(doc, terms),
+	}
+}
+
+// wants reports whether typ is among types, an empty list wanting them all.
+func wants(types []string, typ string) bool {
+	if len(types) == 0 {
+		return true
+	}
+	for _, t := range types {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
+
+func limitOf(q Query) int {
+	if q.Limit <= 0 {
+		return DefaultLimit
+	}
+	if q.Limit > MaxLimit {
+		return MaxLimit
+	}
+	return q.Limit
+}
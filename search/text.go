@@ -0,0 +1,128 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+const (
+	// snippetWidth is roughly how many characters of text a snippet shows.
+	snippetWidth = 160
+	ellipsis     = "…"
+)
+
+// token is a word of a text, lowercased, with the rune offsets it spans.
+type token struct {
+	term       string
+	start, end int
+}
+
+// tokenize splits text into words of letters and digits. There is no
+// stemming or stop word list, since content is not all in one language.
+func tokenize(runes []rune) []token {
+	var tokens []token
+	start := -1
+	for i, r := range runes {
+		word := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if word && start < 0 {
+			start = i
+		}
+		if !word && start >= 0 {
+			tokens = append(tokens, token{term: strings.ToLower(string(runes[start:i])), start: start, end: i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, token{term: strings.ToLower(string(runes[start:])), start: start, end: len(runes)})
+	}
+	return tokens
+}
+
+// terms returns the distinct words of text.
+func terms(text string) map[string]bool {
+	set := map[string]bool{}
+	for _, t := range tokenize([]rune(text)) {
+		set[t.term] = true
+	}
+	return set
+}
+
+// snippet highlights the field of doc with the most matching words, or the
+// first field with text when none matches.
+func snippet(doc Document, terms map[string]bool) string {
+	best, bestMatches := "", -1
+	for _, field := range doc.Fields {
+		if field.Text == "" {
+			continue
+		}
+		matches := 0
+		for _, t := range tokenize([]rune(field.Text)) {
+			if terms[t.term] {
+				matches++
+			}
+		}
+		if matches > bestMatches {
+			best, bestMatches = field.Text, matches
+		}
+	}
+	return highlight(best, terms, snippetWidth)
+}
+
+// highlight cuts a window of about width runes out of text, starting a
+// little before the first match and on word boundaries, then escapes it and
+// marks the matching words.
+func highlight(text string, terms map[string]bool, width int) string {
+	runes := []rune(text)
+	tokens := tokenize(runes)
+
+	from, to := 0, len(runes)
+	if len(runes) > width {
+		for _, t := range tokens {
+			if terms[t.term] {
+				from = t.start - width/4
+				break
+			}
+		}
+		if from < 0 {
+			from = 0
+		}
+		if from+width > len(runes) {
+			from = len(runes) - width
+		}
+		to = from + width
+		snappedFrom, snappedTo := from, to
+		for _, t := range tokens {
+			if t.start < from && t.end > from {
+				snappedFrom = t.end
+			}
+			if t.start < to && t.end > to {
+				snappedTo = t.start
+			}
+		}
+		if snappedFrom < snappedTo {
+			from, to = snappedFrom, snappedTo
+		}
+	}
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString(ellipsis)
+	}
+	at := from
+	for _, t := range tokens {
+		if t.start < from || t.end > to || !terms[t.term] {
+			continue
+		}
+		b.WriteString(html.EscapeString(string(runes[at:t.start])))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(string(runes[t.start:t.end])))
+		b.WriteString("</mark>")
+		at = t.end
+	}
+	b.WriteString(html.EscapeString(string(runes[at:to])))
+	if to < len(runes) {
+		b.WriteString(ellipsis)
+	}
+	return strings.TrimSpace(b.String())
+}
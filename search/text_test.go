@@ -0,0 +1,53 @@
+package search
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	var got []string
+	for _, tok := range tokenize([]rune("Ésta es la Casa-123, ¿no?")) {
+		got = append(got, tok.term)
+	}
+	want := []string{"ésta", "es", "la", "casa", "123", "no"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("tokenize = %v, want %v", got, want)
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		terms string
+		width int
+		want  string
+	}{
+		{"marks every match", "Red shoes and red hats", "red", 100, "<mark>Red</mark> shoes and <mark>red</mark> hats"},
+		{"escapes html", "<b>red</b> & blue", "red", 100, "&lt;b&gt;<mark>red</mark>&lt;/b&gt; &amp; blue"},
+		{"no match", "blue shoes", "red", 100, "blue shoes"},
+		{"cuts on word boundaries", "one two three four five six seven eight", "five", 12, "… <mark>five</mark> six …"},
+	}
+	for _, tt := range tests {
+		if got := highlight(tt.text, terms(tt.terms), tt.width); got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSnippetPicksTheBestField(t *testing.T) {
+	doc := Document{Fields: []Field{
+		{Name: "title", Text: "Summer sale"},
+		{Name: "body", Text: ""},
+		{Name: "desc", Text: "Shoes for the summer and the beach"},
+	}}
+	got := snippet(doc, terms("summer beach"))
+	if !strings.Contains(got, "<mark>beach</mark>") {
+		t.Errorf("snippet = %q, want the desc field", got)
+	}
+	if got := snippet(doc, terms("winter")); got != "Summer sale" {
+		t.Errorf("snippet without a match = %q, want the title", got)
+	}
+}
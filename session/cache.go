@@ -0,0 +1,77 @@
+package session
+
+import (
+	"sync"
+	"time"
+)
+
+// maxCacheEntries is the size at which the cache drops its stale entries.
+const maxCacheEntries = 10000
+
+// revocationCache remembers recent revocation checks so that most requests
+// do not reach the database. A revoked token stays revoked, so that answer
+// is kept until the token expires. That a token is not revoked is only
+// trusted for ttl, since another instance may revoke it at any time.
+type revocationCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	revoked bool
+	until   time.Time
+}
+
+func newRevocationCache(ttl time.Duration) *revocationCache {
+	return &revocationCache{ttl: ttl, entries: map[string]cacheEntry{}}
+}
+
+func (c *revocationCache) get(key string, now time.Time) (revoked bool, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || !now.Before(entry.until) {
+		return false, false
+	}
+	return entry.revoked, true
+}
+
+// put caches the answer for key; expiresAt is when the token it is about
+// expires, after which it no longer matters.
+func (c *revocationCache) put(key string, revoked bool, now time.Time, expiresAt time.Time) {
+	until := expiresAt
+	if !revoked && now.Add(c.ttl).Before(until) {
+		until = now.Add(c.ttl)
+	}
+	if !now.Before(until) {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= maxCacheEntries {
+		for key, entry := range c.entries {
+			if !now.Before(entry.until) {
+				delete(c.entries, key)
+			}
+		}
+		// every answer can be looked up again
+		if len(c.entries) >= maxCacheEntries {
+			c.entries = map[string]cacheEntry{}
+		}
+	}
+	c.entries[key] = cacheEntry{revoked: revoked, until: until}
+}
+
+// forgetUnrevoked drops every cached answer that a token is not revoked,
+// for when many tokens are revoked at once.
+func (c *revocationCache) forgetUnrevoked() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, entry := range c.entries {
+		if !entry.revoked {
+			delete(c.entries, key)
+		}
+	}
+}
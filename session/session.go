@@ -0,0 +1,266 @@
+// Package session issues the tokens of a login. A session starts with a
+// short-lived access token and an opaque refresh token; each refresh hands
+// out a new pair and retires the old refresh token, and a retired token that
+// is used again revokes the whole session.
+//
+// Access tokens stop working early when their session is revoked, by logout
+// or reuse of a refresh token, or when the token itself is revoked by its
+// ID.
+package session
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"golang_cms/helper"
+	"golang_cms/model"
+	"golang_cms/repository"
+	"golang_cms/scheduler"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrInvalidRefreshToken is returned for a refresh token that is unknown,
+// expired or belongs to a revoked session.
+var ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+
+// ErrRefreshTokenReused is returned when a refresh token that was already
+// exchanged is presented again. Either the client or someone who stole the
+// token used it first, so the session is revoked.
+var ErrRefreshTokenReused = errors.New("refresh token was already used, the session has been revoked")
+
+// Tokens are handed to the client at login and on every refresh.
+// ExpiresIn is the lifetime of the access token in seconds.
+type Tokens struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// ErrTokenRevoked is returned for an access token that was revoked or
+// whose session was.
+var ErrTokenRevoked = errors.New("token has been revoked")
+
+// Manager starts, refreshes and ends sessions. Revocation checks are cached
+// in process; a token revoked by another instance is rejected here once
+// cacheTTL has passed.
+type Manager struct {
+	tokens     repository.RefreshTokenRepository
+	revoked    repository.RevokedTokenRepository
+	users      repository.UserRepository
+	refreshTTL time.Duration
+	cache      *revocationCache
+}
+
+func NewManager(tokens repository.RefreshTokenRepository, revoked repository.RevokedTokenRepository, users repository.UserRepository, refreshTTL time.Duration, cacheTTL time.Duration) *Manager {
+	return &Manager{tokens: tokens, revoked: revoked, users: users, refreshTTL: refreshTTL, cache: newRevocationCache(cacheTTL)}
+}
+
+// Start opens a new session for user.
+func (m *Manager) Start(ctx context.Context, user model.User) (Tokens, error) {
+	return m.issue(ctx, user, primitive.NewObjectID().Hex())
+}
+
+// Refresh exchanges refreshToken for a new pair of tokens in the same
+// session. The claims of the new access token are read from the user again,
+// so a changed name or role shows up on the next refresh.
+func (m *Manager) Refresh(ctx context.Context, refreshToken string) (Tokens, error) {
+	token, err := m.tokens.FindByHash(ctx, helper.HashToken(refreshToken))
+	if errors.Is(err, repository.ErrNotFound) {
+		return Tokens{}, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	now := time.Now().UTC()
+	if token.RevokedAt != nil || !now.Before(token.ExpiresAt) {
+		return Tokens{}, ErrInvalidRefreshToken
+	}
+	if token.UsedAt != nil {
+		return Tokens{}, m.reused(ctx, token, now)
+	}
+	// another request may have exchanged the token since it was read
+	err = m.tokens.MarkUsed(ctx, token, now)
+	if errors.Is(err, repository.ErrNotFound) {
+		return Tokens{}, m.reused(ctx, token, now)
+	}
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	user, err := m.users.FindByUserID(ctx, token.UserId)
+	if errors.Is(err, repository.ErrNotFound) {
+		if _, err := m.tokens.RevokeSession(ctx, token.SessionId, now); err != nil {
+			return Tokens{}, err
+		}
+		return Tokens{}, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return Tokens{}, err
+	}
+	return m.issue(ctx, user, token.SessionId)
+}
+
+// Check returns ErrTokenRevoked if the access token with claims was revoked,
+// directly or through its session.
+func (m *Manager) Check(ctx context.Context, claims *helper.SignedDetails) error {
+	now := time.Now()
+	expiresAt := time.Unix(claims.ExpiresAt, 0)
+
+	revoked, err := m.cached("jti:"+claims.Id, now, expiresAt, func() (bool, error) {
+		return m.revoked.IsRevoked(ctx, claims.Id)
+	})
+	if err != nil || revoked {
+		return revokedErr(revoked, err)
+	}
+	if claims.Session == "" {
+		return nil
+	}
+	revoked, err = m.cached("session:"+claims.Session, now, expiresAt, func() (bool, error) {
+		return m.tokens.SessionRevoked(ctx, claims.Session)
+	})
+	return revokedErr(revoked, err)
+}
+
+func (m *Manager) cached(key string, now time.Time, expiresAt time.Time, lookup func() (bool, error)) (bool, error) {
+	if revoked, ok := m.cache.get(key, now); ok {
+		return revoked, nil
+	}
+	revoked, err := lookup()
+	if err != nil {
+		return false, err
+	}
+	m.cache.put(key, revoked, now, expiresAt)
+	return revoked, nil
+}
+
+func revokedErr(revoked bool, err error) error {
+	if err != nil {
+		return err
+	}
+	if revoked {
+		return ErrTokenRevoked
+	}
+	return nil
+}
+
+// Logout revokes the access token with claims and ends its session, or
+// every session of its user when everywhere is set.
+func (m *Manager) Logout(ctx context.Context, claims *helper.SignedDetails, everywhere bool) error {
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	expiresAt := time.Unix(claims.ExpiresAt, 0).UTC()
+	if claims.Id != "" {
+		if err := m.revoked.Create(ctx, model.RevokedToken{
+			Id:        primitive.NewObjectID(),
+			Jti:       claims.Id,
+			UserId:    claims.Uid,
+			ExpiresAt: expiresAt,
+			CreatedAt: now,
+		}); err != nil {
+			return err
+		}
+		m.cache.put("jti:"+claims.Id, true, now, expiresAt)
+	}
+
+	if everywhere {
+		return m.EndAll(ctx, claims.Uid)
+	}
+	if claims.Session != "" {
+		if _, err := m.tokens.RevokeSession(ctx, claims.Session, now); err != nil {
+			return err
+		}
+		m.cache.put("session:"+claims.Session, true, now, now.Add(helper.AccessTokenTTL()))
+	}
+	return nil
+}
+
+// EndAll ends every session of user uid; their access and refresh tokens
+// stop working.
+func (m *Manager) EndAll(ctx context.Context, uid string) error {
+	return m.EndOthers(ctx, uid, "")
+}
+
+// EndOthers ends every session of user uid except keep, such as the session
+// a user changed their password in.
+func (m *Manager) EndOthers(ctx context.Context, uid string, keep string) error {
+	if _, err := m.tokens.RevokeUser(ctx, uid, keep, time.Now().UTC().Truncate(time.Millisecond)); err != nil {
+		return err
+	}
+	m.cache.forgetUnrevoked()
+	return nil
+}
+
+// reused revokes the session of a refresh token that was presented after it
+// had been exchanged.
+func (m *Manager) reused(ctx context.Context, token model.RefreshToken, now time.Time) error {
+	revoked, err := m.tokens.RevokeSession(ctx, token.SessionId, now)
+	if err != nil {
+		return err
+	}
+	log.Printf("sessions: refresh token of session %s of user %s was reused, revoked %d tokens", token.SessionId, token.UserId, revoked)
+	return ErrRefreshTokenReused
+}
+
+func (m *Manager) issue(ctx context.Context, user model.User, sessionId string) (Tokens, error) {
+	accessToken, err := helper.GenerateAccessToken(deref(user.Email), deref(user.First_name), deref(user.Last_name), deref(user.User_type), user.User_id, sessionId)
+	if err != nil {
+		return Tokens{}, err
+	}
+	refreshToken, hash, err := helper.GenerateRefreshToken()
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	if _, err := m.tokens.Create(ctx, model.RefreshToken{
+		Id:        primitive.NewObjectID(),
+		UserId:    user.User_id,
+		SessionId: sessionId,
+		TokenHash: hash,
+		ExpiresAt: now.Add(m.refreshTTL),
+		CreatedAt: now,
+	}); err != nil {
+		return Tokens{}, err
+	}
+
+	return Tokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(helper.AccessTokenTTL() / time.Second),
+	}, nil
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// Job is the scheduler job that deletes expired refresh tokens and
+// revocations of expired access tokens. Used refresh tokens are kept until
+// then so that replaying them is still detected.
+func Job(repos repository.Repositories, interval time.Duration) scheduler.Job {
+	return scheduler.Job{
+		Name:     "sessions",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			now := time.Now().UTC()
+			deleted, err := repos.Refresh.DeleteExpired(ctx, now)
+			if deleted > 0 {
+				log.Printf("sessions: deleted %d expired refresh tokens", deleted)
+			}
+			if err != nil {
+				return err
+			}
+			deleted, err = repos.Revoked.DeleteExpired(ctx, now)
+			if deleted > 0 {
+				log.Printf("sessions: deleted %d expired revocations", deleted)
+			}
+			return err
+		},
+	}
+}
@@ -0,0 +1,247 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"golang_cms/helper"
+	"golang_cms/model"
+	"golang_cms/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// deletableUsers is a user repository whose users can be made to vanish.
+type deletableUsers struct {
+	repository.UserRepository
+	deleted map[string]bool
+}
+
+func (u *deletableUsers) FindByUserID(ctx context.Context, userId string) (model.User, error) {
+	if u.deleted[userId] {
+		return model.User{}, repository.ErrNotFound
+	}
+	return u.UserRepository.FindByUserID(ctx, userId)
+}
+
+func newManager(t *testing.T, refreshTTL time.Duration) (*Manager, repository.Repositories, *deletableUsers, model.User) {
+	t.Helper()
+	helper.SetSecretKey("session-test-key")
+	repos := repository.NewMemoryRepositories()
+	users := &deletableUsers{UserRepository: repos.User, deleted: map[string]bool{}}
+	email := "ada@example.com"
+	user, err := repos.User.Create(context.Background(), model.User{ID: primitive.NewObjectID(), User_id: "ada", Email: &email, Phone: &email})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewManager(repos.Refresh, repos.Revoked, users, refreshTTL, 0), repos, users, user
+}
+
+func claimsOf(t *testing.T, accessToken string) *helper.SignedDetails {
+	t.Helper()
+	claims, msg := helper.ValidateToken(accessToken)
+	if msg != "" {
+		t.Fatalf("access token: %s", msg)
+	}
+	return claims
+}
+
+func TestRefreshRotatesTokens(t *testing.T) {
+	ctx := context.Background()
+	m, _, _, user := newManager(t, time.Hour)
+	first, err := m.Start(ctx, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := m.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.RefreshToken == first.RefreshToken || second.AccessToken == first.AccessToken {
+		t.Error("refresh handed out the same tokens")
+	}
+	if claimsOf(t, second.AccessToken).Session != claimsOf(t, first.AccessToken).Session {
+		t.Error("refresh started another session")
+	}
+	if _, err := m.Refresh(ctx, second.RefreshToken); err != nil {
+		t.Errorf("refreshing with the new token = %v", err)
+	}
+}
+
+func TestRefreshTokenReuseRevokesSession(t *testing.T) {
+	ctx := context.Background()
+	m, _, _, user := newManager(t, time.Hour)
+	first, _ := m.Start(ctx, user)
+	other, _ := m.Start(ctx, user)
+	second, err := m.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.Refresh(ctx, first.RefreshToken); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("reusing a refresh token = %v", err)
+	}
+	if _, err := m.Refresh(ctx, second.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("refreshing the revoked session = %v", err)
+	}
+	if err := m.Check(ctx, claimsOf(t, second.AccessToken)); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("access token of the revoked session = %v", err)
+	}
+
+	// other sessions of the user go on
+	if err := m.Check(ctx, claimsOf(t, other.AccessToken)); err != nil {
+		t.Errorf("access token of another session = %v", err)
+	}
+	if _, err := m.Refresh(ctx, other.RefreshToken); err != nil {
+		t.Errorf("refreshing another session = %v", err)
+	}
+}
+
+func TestConcurrentRefreshesOfOneToken(t *testing.T) {
+	ctx := context.Background()
+	m, _, _, user := newManager(t, time.Hour)
+	tokens, _ := m.Start(ctx, user)
+
+	const clients = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, clients)
+	for i := 0; i < clients; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := m.Refresh(ctx, tokens.RefreshToken)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	succeeded := 0
+	for err := range errs {
+		if err == nil {
+			succeeded++
+		} else if !errors.Is(err, ErrRefreshTokenReused) && !errors.Is(err, ErrInvalidRefreshToken) {
+			t.Errorf("refresh = %v", err)
+		}
+	}
+	if succeeded != 1 {
+		t.Errorf("%d refreshes of one token succeeded", succeeded)
+	}
+}
+
+func TestRefreshRejects(t *testing.T) {
+	ctx := context.Background()
+	m, _, users, user := newManager(t, time.Hour)
+	if _, err := m.Refresh(ctx, "made-up"); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("unknown token = %v", err)
+	}
+
+	tokens, _ := m.Start(ctx, user)
+	users.deleted[user.User_id] = true
+	if _, err := m.Refresh(ctx, tokens.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("token of a deleted user = %v", err)
+	}
+
+	expired, _, _, user := newManager(t, -time.Minute)
+	tokens, _ = expired.Start(ctx, user)
+	if _, err := expired.Refresh(ctx, tokens.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("expired token = %v", err)
+	}
+}
+
+func TestLogout(t *testing.T) {
+	ctx := context.Background()
+	m, _, _, user := newManager(t, time.Hour)
+	current, _ := m.Start(ctx, user)
+	other, _ := m.Start(ctx, user)
+
+	if err := m.Logout(ctx, claimsOf(t, current.AccessToken), false); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Check(ctx, claimsOf(t, current.AccessToken)); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("logged out access token = %v", err)
+	}
+	if _, err := m.Refresh(ctx, current.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("logged out refresh token = %v", err)
+	}
+	if err := m.Check(ctx, claimsOf(t, other.AccessToken)); err != nil {
+		t.Errorf("other session after logout = %v", err)
+	}
+
+	if err := m.Logout(ctx, claimsOf(t, other.AccessToken), true); err != nil {
+		t.Fatal(err)
+	}
+	third, _ := m.Start(ctx, user)
+	if _, err := m.Refresh(ctx, other.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("refresh after logging out everywhere = %v", err)
+	}
+	if _, err := m.Refresh(ctx, third.RefreshToken); err != nil {
+		t.Errorf("session started after logging out everywhere = %v", err)
+	}
+}
+
+func TestJobDeletesExpiredTokens(t *testing.T) {
+	ctx := context.Background()
+	m, repos, _, user := newManager(t, -time.Minute)
+	tokens, _ := m.Start(ctx, user)
+	if err := Job(repos, time.Minute).Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repos.Refresh.FindByHash(ctx, helper.HashToken(tokens.RefreshToken)); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expired refresh token kept: %v", err)
+	}
+}
+
+func TestRevocationCache(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := newRevocationCache(time.Minute)
+
+	c.put("fresh", false, now, now.Add(time.Hour))
+	c.put("revoked", true, now, now.Add(time.Hour))
+	c.put("expired", true, now, now)
+
+	if revoked, ok := c.get("fresh", now.Add(30*time.Second)); !ok || revoked {
+		t.Errorf("fresh within ttl = %v, %v", revoked, ok)
+	}
+	if _, ok := c.get("fresh", now.Add(2*time.Minute)); ok {
+		t.Error("unrevoked answer kept past the ttl")
+	}
+	if revoked, ok := c.get("revoked", now.Add(30*time.Minute)); !ok || !revoked {
+		t.Errorf("revoked until expiry = %v, %v", revoked, ok)
+	}
+	if _, ok := c.get("expired", now); ok {
+		t.Error("answer for an expired token cached")
+	}
+
+	c.forgetUnrevoked()
+	if _, ok := c.get("fresh", now); ok {
+		t.Error("unrevoked answer kept after forgetUnrevoked")
+	}
+	if _, ok := c.get("revoked", now); !ok {
+		t.Error("revoked answer dropped by forgetUnrevoked")
+	}
+}
+
+func TestEndOthers(t *testing.T) {
+	ctx := context.Background()
+	m, _, _, user := newManager(t, time.Hour)
+	kept, _ := m.Start(ctx, user)
+	ended, _ := m.Start(ctx, user)
+
+	if err := m.EndOthers(ctx, user.User_id, claimsOf(t, kept.AccessToken).Session); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Check(ctx, claimsOf(t, ended.AccessToken)); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("access token of an ended session = %v", err)
+	}
+	if err := m.Check(ctx, claimsOf(t, kept.AccessToken)); err != nil {
+		t.Errorf("access token of the kept session = %v", err)
+	}
+	if _, err := m.Refresh(ctx, kept.RefreshToken); err != nil {
+		t.Errorf("refreshing the kept session = %v", err)
+	}
+}
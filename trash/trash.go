@@ -0,0 +1,86 @@
+// Package trash purges content that has been in the trash for longer than
+// its retention period.
+package trash
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"golang_cms/audit"
+	"golang_cms/model"
+	"golang_cms/repository"
+	"golang_cms/scheduler"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Purge permanently deletes the documents of repo that were moved to the
+// trash at or before cutoff and returns how many it deleted. T must embed
+// model.Trash.
+func Purge[T any](ctx context.Context, repo repository.CrudRepository[T], cutoff time.Time) (int, error) {
+	docs, _, err := repo.List(ctx, repository.Query{Filter: repository.Filter{
+		"deleted_at": []repository.Cond{repository.Ne(nil), repository.Lte(cutoff)},
+	}})
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, doc := range docs {
+		id, err := idOf(doc)
+		if err != nil {
+			return purged, err
+		}
+		if err := repo.Delete(ctx, id); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+func idOf(doc interface{}) (primitive.ObjectID, error) {
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	var key struct {
+		Id primitive.ObjectID `bson:"_id"`
+	}
+	err = bson.Unmarshal(raw, &key)
+	return key.Id, err
+}
+
+// Job is the scheduler job that purges trash older than retention from
+// every content type deleted to the trash first.
+func Job(repos repository.Repositories, retention time.Duration, interval time.Duration) scheduler.Job {
+	return scheduler.Job{
+		Name:     "trash",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			ctx = audit.WithActor(ctx, audit.System("trash"))
+			cutoff := time.Now().Add(-retention)
+			purgers := []func() (int, error){
+				func() (int, error) { return Purge[model.Banner](ctx, repos.Banner, cutoff) },
+				func() (int, error) { return Purge[model.Meta](ctx, repos.Meta, cutoff) },
+				func() (int, error) { return Purge[model.Desc](ctx, repos.Desc, cutoff) },
+				func() (int, error) { return Purge[model.MainCategory](ctx, repos.Category, cutoff) },
+			}
+
+			total := 0
+			for _, purge := range purgers {
+				purged, err := purge()
+				total += purged
+				if err != nil {
+					return err
+				}
+			}
+			if total > 0 {
+				log.Printf("trash: purged %d documents deleted before %s", total, cutoff.Format(time.RFC3339))
+			}
+			return nil
+		},
+	}
+}
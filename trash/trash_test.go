@@ -0,0 +1,41 @@
+package trash
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"golang_cms/model"
+	"golang_cms/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPurge(t *testing.T) {
+	ctx := context.Background()
+	repos := repository.NewMemoryRepositories()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	old, recent := now.Add(-48*time.Hour), now.Add(-time.Hour)
+
+	descs := map[string]*time.Time{"kept": nil, "old": &old, "recent": &recent}
+	ids := map[string]primitive.ObjectID{}
+	for title, deletedAt := range descs {
+		desc := model.Desc{Id: primitive.NewObjectID(), Title: title, Desc: title}
+		desc.DeletedAt = deletedAt
+		if _, err := repos.Desc.Create(ctx, desc); err != nil {
+			t.Fatal(err)
+		}
+		ids[title] = desc.Id
+	}
+
+	purged, err := Purge[model.Desc](ctx, repos.Desc, now.Add(-24*time.Hour))
+	if err != nil || purged != 1 {
+		t.Fatalf("purge = %d, %v; want 1", purged, err)
+	}
+	for title, id := range ids {
+		_, err := repos.Desc.FindByID(ctx, id)
+		if gone := err != nil; gone != (title == "old") {
+			t.Errorf("%s gone = %v", title, gone)
+		}
+	}
+}
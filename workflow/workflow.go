@@ -0,0 +1,102 @@
+// Package workflow carries out the publishing schedule of content: drafts
+// and documents in review are published once their publish_at passes, and
+// published documents are archived once their unpublish_at passes.
+package workflow
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"golang_cms/audit"
+	"golang_cms/model"
+	"golang_cms/repository"
+	"golang_cms/scheduler"
+)
+
+// step moves state one transition along its schedule and clears the
+// schedule entry it carried out, so that a document moved back by hand is
+// not moved again. It reports whether anything changed.
+func step(state *model.Workflow, now time.Time) bool {
+	switch {
+	case (state.Status == model.StatusDraft || state.Status == model.StatusInReview) && state.PublishAt != nil && !state.PublishAt.After(now):
+		state.Status = model.StatusPublished
+		state.PublishAt = nil
+		return true
+	case state.Status == model.StatusPublished && state.UnpublishAt != nil && !state.UnpublishAt.After(now):
+		state.Status = model.StatusArchived
+		state.UnpublishAt = nil
+		return true
+	}
+	return false
+}
+
+// Apply carries out every schedule of repo that is due at now and returns
+// how many documents changed. T must embed model.Workflow.
+func Apply[T any](ctx context.Context, repo repository.CrudRepository[T], now time.Time) (int, error) {
+	due := []repository.Filter{
+		{"status": repository.In(model.StatusDraft, model.StatusInReview), "publish_at": repository.Lte(now)},
+		{"status": model.StatusPublished, "unpublish_at": repository.Lte(now)},
+	}
+
+	changed := 0
+	for _, filter := range due {
+		docs, _, err := repo.List(ctx, repository.Query{Filter: filter})
+		if err != nil {
+			return changed, err
+		}
+		for i := range docs {
+			publishable, ok := interface{}(&docs[i]).(model.Publishable)
+			if !ok {
+				return changed, nil
+			}
+			state := publishable.WorkflowState()
+			moved := false
+			for step(state, now) {
+				moved = true
+			}
+			if !moved {
+				continue
+			}
+			if _, err := repo.Update(ctx, docs[i]); err != nil {
+				return changed, err
+			}
+			changed++
+		}
+	}
+	return changed, nil
+}
+
+// Job is the scheduler job that applies the schedules of every content type
+// with a publishing status.
+func Job(repos repository.Repositories, interval time.Duration) scheduler.Job {
+	return scheduler.Job{
+		Name:     "workflow",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			ctx = audit.WithActor(ctx, audit.System("workflow"))
+			now := time.Now()
+			appliers := []func() (int, error){
+				func() (int, error) { return Apply[model.Banner](ctx, repos.Banner, now) },
+				func() (int, error) { return Apply[model.Meta](ctx, repos.Meta, now) },
+				func() (int, error) { return Apply[model.Desc](ctx, repos.Desc, now) },
+				func() (int, error) { return Apply[model.MainCategory](ctx, repos.Category, now) },
+				func() (int, error) { return Apply[model.ChildCategory](ctx, repos.Child, now) },
+				func() (int, error) { return Apply[model.Category](ctx, repos.Tree, now) },
+			}
+
+			total := 0
+			for _, apply := range appliers {
+				changed, err := apply()
+				total += changed
+				if err != nil {
+					return err
+				}
+			}
+			if total > 0 {
+				log.Printf("workflow: published or archived %d documents", total)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,119 @@
+package workflow
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"golang_cms/model"
+	"golang_cms/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func at(t time.Time) *time.Time {
+	return &t
+}
+
+func TestStep(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	past, future := at(now.Add(-time.Hour)), at(now.Add(time.Hour))
+
+	tests := []struct {
+		name  string
+		state model.Workflow
+		want  model.Workflow
+		moved bool
+	}{
+		{"draft due", model.Workflow{Status: model.StatusDraft, PublishAt: past}, model.Workflow{Status: model.StatusPublished}, true},
+		{"in review due", model.Workflow{Status: model.StatusInReview, PublishAt: at(now)}, model.Workflow{Status: model.StatusPublished}, true},
+		{"draft not due", model.Workflow{Status: model.StatusDraft, PublishAt: future}, model.Workflow{Status: model.StatusDraft, PublishAt: future}, false},
+		{"draft unscheduled", model.Workflow{Status: model.StatusDraft}, model.Workflow{Status: model.StatusDraft}, false},
+		{"published expired", model.Workflow{Status: model.StatusPublished, UnpublishAt: past}, model.Workflow{Status: model.StatusArchived}, true},
+		{"published running", model.Workflow{Status: model.StatusPublished, UnpublishAt: future}, model.Workflow{Status: model.StatusPublished, UnpublishAt: future}, false},
+		{"archived with old publish_at", model.Workflow{Status: model.StatusArchived, PublishAt: past}, model.Workflow{Status: model.StatusArchived, PublishAt: past}, false},
+		{"published with old publish_at", model.Workflow{Status: model.StatusPublished, PublishAt: past}, model.Workflow{Status: model.StatusPublished, PublishAt: past}, false},
+	}
+	for _, tt := range tests {
+		state := tt.state
+		if moved := step(&state, now); moved != tt.moved {
+			t.Errorf("%s: moved = %v", tt.name, moved)
+		}
+		if state.Status != tt.want.Status || !sameTime(state.PublishAt, tt.want.PublishAt) || !sameTime(state.UnpublishAt, tt.want.UnpublishAt) {
+			t.Errorf("%s: state = %+v, want %+v", tt.name, state, tt.want)
+		}
+	}
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func TestApply(t *testing.T) {
+	ctx := context.Background()
+	repos := repository.NewMemoryRepositories()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	past, future := at(now.Add(-time.Hour)), at(now.Add(time.Hour))
+
+	docs := map[string]model.Workflow{
+		"due":          {Status: model.StatusDraft, PublishAt: past},
+		"due and over": {Status: model.StatusInReview, PublishAt: at(now.Add(-2 * time.Hour)), UnpublishAt: past},
+		"expired":      {Status: model.StatusPublished, UnpublishAt: past},
+		"scheduled":    {Status: model.StatusDraft, PublishAt: future},
+		"running":      {Status: model.StatusPublished, UnpublishAt: future},
+		"draft":        {Status: model.StatusDraft},
+	}
+	ids := map[string]primitive.ObjectID{}
+	for title, state := range docs {
+		desc := model.Desc{Id: primitive.NewObjectID(), Title: title, Desc: "d", Workflow: state}
+		if _, err := repos.Desc.Create(ctx, desc); err != nil {
+			t.Fatal(err)
+		}
+		ids[title] = desc.Id
+	}
+
+	changed, err := Apply[model.Desc](ctx, repos.Desc, now)
+	if err != nil || changed != 3 {
+		t.Fatalf("Apply = %d, %v; want 3 changed", changed, err)
+	}
+
+	want := map[string]string{
+		"due":          model.StatusPublished,
+		"due and over": model.StatusArchived,
+		"expired":      model.StatusArchived,
+		"scheduled":    model.StatusDraft,
+		"running":      model.StatusPublished,
+		"draft":        model.StatusDraft,
+	}
+	for title, status := range want {
+		desc, err := repos.Desc.FindByID(ctx, ids[title])
+		if err != nil || desc.Status != status {
+			t.Errorf("%s: status %q, %v; want %q", title, desc.Status, err, status)
+		}
+	}
+
+	if changed, err := Apply[model.Desc](ctx, repos.Desc, now); err != nil || changed != 0 {
+		t.Errorf("second Apply = %d, %v", changed, err)
+	}
+}
+
+func TestJob(t *testing.T) {
+	ctx := context.Background()
+	repos := repository.NewMemoryRepositories()
+	banner := model.Banner{Id: primitive.NewObjectID(), Banner: "a.png", Alt: "a", Link: "/a",
+		Workflow: model.Workflow{Status: model.StatusDraft, PublishAt: at(time.Now().Add(-time.Minute))}}
+	if _, err := repos.Banner.Create(ctx, banner); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Job(repos, time.Minute).Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := repos.Banner.FindByID(ctx, banner.Id)
+	if stored.Status != model.StatusPublished {
+		t.Errorf("banner status = %q", stored.Status)
+	}
+}